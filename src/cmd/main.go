@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	_ "github.com/betterreads/docs"
 	"github.com/betterreads/internal/application"
 )
@@ -12,6 +14,19 @@ import (
 // @BasePath /
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		application.RunMigrateCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "role" {
+		application.RunRoleCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "blobs" {
+		application.RunBlobsCommand(os.Args[2:])
+		return
+	}
+
 	r := application.NewRouter(":8080")
 	r.Run()
 }
@@ -0,0 +1,179 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/storage"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const blobsMigrateBatchSize = 50
+
+// Tables that keep pictures, with the column that identifies them and the key they get in the store
+var pictureTables = []struct {
+	table    string
+	idColumn string
+	key      func(id string) string
+}{
+	{"pictures", "book_id", storage.BookPictureKey},
+	{"pictures_users", "user_id", storage.UserPictureKey},
+	{"communities_pictures", "community_id", storage.CommunityPictureKey},
+}
+
+// RunBlobsCommand handles `blobs migrate`, it moves the pictures still saved in the database to the blob store
+// and resizes the ones saved before there were variants.
+func RunBlobsCommand(args []string) {
+	if len(args) != 1 || args[0] != "migrate" {
+		fmt.Fprintln(os.Stderr, "usage: betterreads blobs migrate")
+		os.Exit(2)
+	}
+
+	// Exits once runBlobsMigrate returned, so the connection is closed
+	if err := runBlobsMigrate(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runBlobsMigrate() error {
+	cfg := LoadConfig()
+	conn, err := connectDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("can't connect to db: %w", err)
+	}
+	defer conn.Close()
+
+	blobs, err := newBlobStore(cfg)
+	if err != nil {
+		return fmt.Errorf("can't create blob store: %w", err)
+	}
+
+	// The picture being moved is left as it was when the command is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	for _, t := range pictureTables {
+		migrated, err := migratePictures(ctx, conn, blobs, t.table, t.idColumn, t.key)
+		if err != nil {
+			return fmt.Errorf("can't migrate %s: %w", t.table, err)
+		}
+		fmt.Printf("Migrated %d pictures from %s\n", migrated, t.table)
+	}
+	return nil
+}
+
+// migratePictures goes through the pictures of the table without variants in batches. A picture is only
+// cleared from the row once it is in the store, so the command can be run again if it fails halfway.
+// Pictures that can't be resized are moved as they are. They are saved under a new key and the row is
+// only updated when it wasn't changed since it was read, so a picture uploaded meanwhile is kept.
+func migratePictures(ctx context.Context, conn *sqlx.DB, blobs storage.BlobStore, table string, idColumn string, key func(id string) string) (int, error) {
+	selectQuery := fmt.Sprintf(`SELECT %[1]s AS id, picture, blob_key, content_type, variants, etag, updated_at FROM %[2]s
+			WHERE NOT variants AND (picture IS NOT NULL OR blob_key IS NOT NULL) AND %[1]s > $1
+			ORDER BY %[1]s LIMIT $2;`, idColumn, table)
+	resizedQuery := fmt.Sprintf(`UPDATE %s SET blob_key = $2, content_type = $3, variants = true, etag = $4,
+			picture = NULL, updated_at = now() WHERE %s = $1 AND NOT variants AND updated_at = $5;`, table, idColumn)
+	movedQuery := fmt.Sprintf(`UPDATE %s SET blob_key = $2, content_type = $3, etag = $4, picture = NULL
+			WHERE %s = $1 AND blob_key IS NULL AND NOT variants AND updated_at = $5;`, table, idColumn)
+
+	migrated := 0
+	last := uuid.Nil
+	for {
+		rows := []struct {
+			Id uuid.UUID `db:"id"`
+			storage.PictureRecord
+		}{}
+		if err := conn.SelectContext(ctx, &rows, selectQuery, last, blobsMigrateBatchSize); err != nil {
+			return migrated, fmt.Errorf("failed to get pictures: %w", err)
+		}
+		if len(rows) == 0 {
+			return migrated, nil
+		}
+
+		for _, row := range rows {
+			last = row.Id
+			blobKey := key(row.Id.String()) + "_" + uuid.NewString()
+			blob, err := storage.LoadPicture(ctx, blobs, &row.PictureRecord, "")
+			if err != nil {
+				return migrated, fmt.Errorf("failed to load picture %s: %w", row.Id, err)
+			}
+			if blob == nil {
+				continue
+			}
+
+			data, err := blob.Data(ctx)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to load picture %s: %w", row.Id, err)
+			}
+
+			picture, err := images.Process(data)
+			if err != nil {
+				fmt.Printf("Can't resize picture %s of %s, it is kept as it is: %v\n", row.Id, table, err)
+				if row.BlobKey != nil {
+					continue
+				}
+				if err := blobs.Put(ctx, blobKey, data, blob.ContentType); err != nil {
+					return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+				}
+				res, err := conn.ExecContext(ctx, movedQuery, row.Id, blobKey, blob.ContentType, blob.ETag, row.UpdatedAt)
+				if err != nil {
+					return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+				}
+				changed, err := pictureChanged(res)
+				if err != nil {
+					return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+				}
+				if changed {
+					if err := blobs.Delete(ctx, blobKey); err != nil {
+						return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+					}
+					fmt.Printf("Picture %s of %s changed while it was moved, it is skipped\n", row.Id, table)
+					continue
+				}
+				migrated++
+				continue
+			}
+
+			if err := picture.Save(ctx, blobs, blobKey); err != nil {
+				return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+			}
+			res, err := conn.ExecContext(ctx, resizedQuery, row.Id, blobKey, picture.ContentType, picture.ETag, row.UpdatedAt)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+			}
+			changed, err := pictureChanged(res)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+			}
+			if changed {
+				if err := images.Delete(ctx, blobs, blobKey); err != nil {
+					return migrated, fmt.Errorf("failed to move picture %s: %w", row.Id, err)
+				}
+				fmt.Printf("Picture %s of %s changed while it was resized, it is skipped\n", row.Id, table)
+				continue
+			}
+			// The picture saved before there were variants isn't used anymore
+			if row.BlobKey != nil {
+				if err := blobs.Delete(ctx, *row.BlobKey); err != nil {
+					return migrated, fmt.Errorf("failed to delete the old picture %s: %w", row.Id, err)
+				}
+			}
+			migrated++
+		}
+	}
+}
+
+// pictureChanged tells if the row was updated or uploaded again after it was read, then the update did nothing
+func pictureChanged(res sql.Result) (bool, error) {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 0, nil
+}
@@ -1,6 +1,11 @@
 package application
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config struct that holds the configuration of the server
 type Config struct {
@@ -12,6 +17,43 @@ type Config struct {
 	DatabaseName     string
 	DatabaseUser     string
 	DatabasePassword string
+	AutoMigrate      bool
+	LogLevel         string
+	LogFormat        string
+	// How long the requests in flight have to finish when the server is stopped
+	ShutdownTimeout time.Duration
+	// How long /readyz fails before the server stops taking new connections
+	DrainDelay time.Duration
+	// memory or postgres, postgres shares the limits between replicas
+	RateLimitStore string
+	// Proxies whose X-Forwarded-For gives the client IP
+	TrustedProxies []string
+	// Public URL of the app, used in the links sent by email
+	AppURL       string
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	// Where the pictures are saved, local or s3
+	BlobDriver  string
+	BlobDir     string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// Log in with an OpenID Connect provider, disabled when there is no issuer
+	OIDCProviderName string
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	// Bearer token Prometheus scrapes /metrics with, the endpoint is disabled without it
+	MetricsToken string
 }
 
 // LoadConfig loads the configuration from the Environment variables
@@ -25,6 +67,35 @@ func LoadConfig() *Config {
 		DatabaseName:     os.Getenv("DATABASE_NAME"),
 		DatabaseUser:     os.Getenv("DATABASE_USER"),
 		DatabasePassword: os.Getenv("DATABASE_PASSWORD"),
+		AutoMigrate:      getEnvOrDefault("DATABASE_AUTO_MIGRATE", "true") == "true",
+		LogLevel:         getEnvOrDefault("LOG_LEVEL", "info"),
+		LogFormat:        getEnvOrDefault("LOG_FORMAT", "json"),
+		ShutdownTimeout:  time.Duration(getIntEnvOrDefault("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+		DrainDelay:       time.Duration(getIntEnvOrDefault("SHUTDOWN_DRAIN_SECONDS", 5)) * time.Second,
+		RateLimitStore:   getEnvOrDefault("RATE_LIMIT_STORE", "memory"),
+		TrustedProxies:   getListEnv("TRUSTED_PROXIES"),
+		AppURL:           getEnvOrDefault("APP_URL", "http://localhost:8080"),
+		MailDriver:       getEnvOrDefault("MAIL_DRIVER", "file"),
+		MailFrom:         getEnvOrDefault("MAIL_FROM", "Betterreads <no-reply@betterreads.local>"),
+		MailDir:          os.Getenv("MAIL_DIR"),
+		SMTPHost:         os.Getenv("SMTP_HOST"),
+		SMTPPort:         getEnvOrDefault("SMTP_PORT", "587"),
+		SMTPUsername:     os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		BlobDriver:       getEnvOrDefault("BLOB_DRIVER", "local"),
+		BlobDir:          getEnvOrDefault("BLOB_DIR", "data/blobs"),
+		S3Endpoint:       os.Getenv("S3_ENDPOINT"),
+		S3Region:         getEnvOrDefault("S3_REGION", "us-east-1"),
+		S3Bucket:         os.Getenv("S3_BUCKET"),
+		S3AccessKey:      os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:      os.Getenv("S3_SECRET_KEY"),
+		OIDCProviderName: getEnvOrDefault("OIDC_PROVIDER_NAME", "oidc"),
+		OIDCIssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		OIDCClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:       getListEnv("OIDC_SCOPES"),
+		MetricsToken:     os.Getenv("METRICS_TOKEN"),
 	}
 }
 
@@ -34,3 +105,22 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getListEnv splits a comma separated variable, it is nil when the variable is empty
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getIntEnvOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,52 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long /readyz waits for the database before answering it isn't ready
+const readinessTimeout = 2 * time.Second
+
+// Healthz godoc
+// @Summary Liveness probe
+// @Description Answers 200 while the process is running
+// @Tags health
+// @Produce  json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (r *Router) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Answers 200 when the database answers, and 503 when it doesn't or the server is shutting down
+// @Tags health
+// @Produce  json
+// @Success 200 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /readyz [get]
+func (r *Router) readyz(c *gin.Context) {
+	if r.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+	if err := r.db.PingContext(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func addHealthHandlers(r *Router) {
+	r.engine.GET("/healthz", r.healthz)
+	r.engine.GET("/readyz", r.readyz)
+}
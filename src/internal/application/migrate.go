@@ -0,0 +1,109 @@
+package application
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/betterreads/internal/pkg/migrations"
+	"github.com/jmoiron/sqlx"
+)
+
+func connectDatabase(cfg *Config) (*sqlx.DB, error) {
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DatabaseUser,
+		cfg.DatabasePassword,
+		cfg.DatabaseHost,
+		cfg.DatabasePort,
+		cfg.DatabaseName)
+
+	return sqlx.Connect("postgres", dsn)
+}
+
+func migrateUp(conn *sqlx.DB) error {
+	migrator, err := migrations.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+	}
+	return err
+}
+
+// RunMigrateCommand handles `migrate up`, `migrate down [steps]` and `migrate status`.
+func RunMigrateCommand(args []string) {
+	if len(args) == 0 {
+		printMigrateUsage()
+	}
+
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+	case "down":
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				printMigrateUsage()
+			}
+		}
+	default:
+		printMigrateUsage()
+	}
+
+	// Exits once runMigrate returned, so the connection is closed and the advisory lock released
+	if err := runMigrate(args[0], steps); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runMigrate(command string, steps int) error {
+	cfg := LoadConfig()
+	conn, err := connectDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("can't connect to db: %w", err)
+	}
+	defer conn.Close()
+
+	migrator, err := migrations.NewMigrator(conn)
+	if err != nil {
+		return fmt.Errorf("can't load migrations: %w", err)
+	}
+
+	switch command {
+	case "up":
+		if err := migrateUp(conn); err != nil {
+			return fmt.Errorf("can't migrate db: %w", err)
+		}
+	case "down":
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted migration %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("can't revert migrations: %w", err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return fmt.Errorf("can't get migrations status: %w", err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+	}
+	return nil
+}
+
+func printMigrateUsage() {
+	fmt.Fprintln(os.Stderr, "usage: betterreads migrate up | down [steps] | status")
+	os.Exit(2)
+}
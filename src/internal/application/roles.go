@@ -0,0 +1,43 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	usersRepository "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/pkg/auth"
+)
+
+// RunRoleCommand handles `role <username> <role>`, it is used to create the first admin.
+func RunRoleCommand(args []string) {
+	if len(args) != 2 || !auth.Role(args[1]).IsValid() {
+		fmt.Fprintln(os.Stderr, "usage: betterreads role <username> user | author | moderator | admin")
+		os.Exit(2)
+	}
+
+	cfg := LoadConfig()
+	conn, err := connectDatabase(cfg)
+	if err != nil {
+		log.Fatalf("can't connect to db: %v", err)
+	}
+	defer conn.Close()
+
+	blobs, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("can't create blob store: %v", err)
+	}
+
+	ctx := context.Background()
+	userRepo := usersRepository.NewPostgresUserRepository(conn, blobs)
+	user, err := userRepo.GetUserByUsername(ctx, args[0])
+	if err != nil {
+		log.Fatalf("can't get user %s: %v", args[0], err)
+	}
+
+	if _, err := userRepo.UpdateUserRole(ctx, user.Id, auth.Role(args[1])); err != nil {
+		log.Fatalf("can't update role: %v", err)
+	}
+	fmt.Printf("%s is now %s\n", user.Username, args[1])
+}
@@ -31,7 +31,16 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
+	"context"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	usersController "github.com/betterreads/internal/domains/users/controller"
 	usersRepository "github.com/betterreads/internal/domains/users/repository"
@@ -42,11 +51,35 @@ import (
 	_ "github.com/lib/pq"
 
 	middlewares "github.com/betterreads/internal/middlewares"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/mail"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/betterreads/internal/pkg/oidc"
+	"github.com/betterreads/internal/pkg/ratelimit"
+	"github.com/betterreads/internal/pkg/storage"
+)
+
+// Rate limits of the route groups. Logged out routes are limited by IP and the writes of
+// logged users by user.
+var (
+	loginLimit    = ratelimit.Limit{Requests: 10, Period: time.Minute}
+	registerLimit = ratelimit.Limit{Requests: 5, Period: time.Hour}
+	// Refresh, email verification and password reset
+	accountLimit = ratelimit.Limit{Requests: 20, Period: time.Minute}
+	writeLimit   = ratelimit.Limit{Requests: 60, Period: time.Minute}
 )
 
 type Router struct {
 	engine  *gin.Engine
 	address string
+	db      *sqlx.DB
+	limits  ratelimit.Store
+	// How long the requests in flight have to finish when the server is stopped
+	shutdownTimeout time.Duration
+	// How long /readyz fails before the server stops taking connections, so load balancers stop sending requests
+	drainDelay   time.Duration
+	shuttingDown atomic.Bool
 }
 
 func createRouterFromConfig(cfg *Config) *Router {
@@ -54,19 +87,23 @@ func createRouterFromConfig(cfg *Config) *Router {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// gin.DefaultWriter = io.Discard
-	// gin.DefaultErrorWriter = io.Discard
-	// logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	// slog.SetDefault(logger)
-
 	engine := gin.New()
-	engine.Use(gin.Recovery())
-	engine.Use(middlewares.ErrorMiddleware)
+	// ClientIP only reads X-Forwarded-For from these proxies, otherwise clients could pick the IP they are rate limited by
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+	engine.Use(middlewares.RequestID)
+	// Before the recovery, so the requests that panic are logged and counted with their 500
 	engine.Use(middlewares.RequestLogger)
+	engine.Use(middlewares.Metrics)
+	engine.Use(middlewares.Recovery)
+	engine.Use(middlewares.ErrorMiddleware)
 
 	router := &Router{
-		engine:  engine,
-		address: cfg.Host + ":" + cfg.Port,
+		engine:          engine,
+		address:         cfg.Host + ":" + cfg.Port,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		drainDelay:      cfg.DrainDelay,
 	}
 
 	return router
@@ -75,28 +112,54 @@ func createRouterFromConfig(cfg *Config) *Router {
 func NewRouter(port string) *Router {
 	cfg := LoadConfig()
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.DatabaseUser,
-		cfg.DatabasePassword,
-		cfg.DatabaseHost,
-		cfg.DatabasePort,
-		cfg.DatabaseName)
+	// The std log package also writes through it
+	l, err := logger.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("can't create logger: %v", err)
+	}
+	slog.SetDefault(l)
 
-	conn, err := sqlx.Connect("postgres", dsn)
+	conn, err := connectDatabase(cfg)
 	if err != nil {
 		log.Fatalf("can't connect to db: %v", err)
 	}
+	metrics.RegisterDB(conn.DB, cfg.DatabaseName)
+
+	if cfg.AutoMigrate {
+		if err := migrateUp(conn); err != nil {
+			log.Fatalf("can't migrate db: %v", err)
+		}
+	}
+
+	blobs, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("can't create blob store: %v", err)
+	}
+
+	limits, err := newRateLimitStore(cfg, conn)
+	if err != nil {
+		log.Fatalf("can't create rate limit store: %v", err)
+	}
 
 	r := createRouterFromConfig(cfg)
+	r.db = conn
+	r.limits = limits
 	addCorsConfiguration(r)
-	users := addUsersHandlers(r, conn)
-	books, booksRepo := addBooksHandlers(r, conn)
+	addHealthHandlers(r)
+	users := addUsersHandlers(r, conn, newMailer(cfg), cfg.AppURL, blobs, newOIDCProvider(cfg))
+	books, booksRepo := addBooksHandlers(r, conn, blobs)
 	AddBookshelfHandlers(r, conn, books)
 	AddRecommendationsHandlers(r, conn, books, booksRepo)
 	addFriendsHandlers(r, users, conn)
-	AddCommunitiesHandlers(r, conn)
+	AddCommunitiesHandlers(r, conn, blobs)
 	addFeedHandlers(r, users, conn)
 
+	if cfg.MetricsToken != "" {
+		r.engine.GET("/metrics", middlewares.RequireToken(cfg.MetricsToken), gin.WrapH(metrics.Handler()))
+	} else {
+		slog.Warn("METRICS_TOKEN is not set, /metrics is disabled")
+	}
+
 	//Adds swagger documentation
 	r.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -106,48 +169,125 @@ func NewRouter(port string) *Router {
 func addCorsConfiguration(r *Router) {
 	config := cors.DefaultConfig()
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-None-Match", "If-Modified-Since"}
+	config.ExposeHeaders = []string{"ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"}
 	config.AllowAllOrigins = true
 	config.AllowCredentials = true
 	r.engine.Use(cors.New(config))
 }
 
-func addUsersHandlers(r *Router, conn *sqlx.DB) usersService.UsersService {
+// newMailer sends the emails with SMTP when MAIL_DRIVER is smtp, otherwise they are written to MAIL_DIR.
+func newMailer(cfg *Config) mail.Mailer {
+	if cfg.MailDriver == "smtp" {
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return mail.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+}
+
+// newBlobStore saves the pictures in an S3 compatible bucket when BLOB_DRIVER is s3, otherwise in BLOB_DIR.
+func newBlobStore(cfg *Config) (storage.BlobStore, error) {
+	switch cfg.BlobDriver {
+	case "s3":
+		return storage.NewS3BlobStore(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	case "local":
+		return storage.NewLocalBlobStore(cfg.BlobDir), nil
+	default:
+		return nil, fmt.Errorf("unknown blob driver %s", cfg.BlobDriver)
+	}
+}
 
-	userRepo, err := usersRepository.NewPostgresUserRepository(conn)
-	if err != nil {
-		log.Fatalf("can't create db: %v", err)
+// newRateLimitStore shares the rate limits between the replicas through the database when
+// RATE_LIMIT_STORE is postgres, otherwise each one keeps its own in memory.
+func newRateLimitStore(cfg *Config, conn *sqlx.DB) (ratelimit.Store, error) {
+	switch cfg.RateLimitStore {
+	case "postgres":
+		return ratelimit.NewPostgresStore(conn), nil
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %s", cfg.RateLimitStore)
+	}
+}
+
+// newOIDCProvider is nil when OIDC_ISSUER_URL isn't set, then the log in with the provider is disabled.
+func newOIDCProvider(cfg *Config) *oidc.Provider {
+	if cfg.OIDCIssuerURL == "" {
+		return nil
+	}
+
+	redirectURL := cfg.OIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimSuffix(cfg.AppURL, "/") + "/oidc/callback"
 	}
-	us := usersService.NewUsersServiceImpl(userRepo)
+	scopes := cfg.OIDCScopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	return oidc.NewProvider(oidc.Config{
+		Name:         cfg.OIDCProviderName,
+		IssuerURL:    cfg.OIDCIssuerURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+	})
+}
+
+func addUsersHandlers(r *Router, conn *sqlx.DB, mailer mail.Mailer, appURL string, blobs storage.BlobStore, provider *oidc.Provider) usersService.UsersService {
+
+	userRepo := usersRepository.NewPostgresUserRepository(conn, blobs)
+	// Access tokens of revoked sessions are rejected by the auth middlewares
+	auth.SetRevocationChecker(userRepo)
+	us := usersService.NewUsersServiceImpl(userRepo, mailer, appURL, provider)
 	uc := usersController.NewUsersController(us)
 
+	limitRegister := middlewares.RateLimit(r.limits, "register", registerLimit, middlewares.ByIP)
+	limitLogin := middlewares.RateLimit(r.limits, "login", loginLimit, middlewares.ByIP)
+	limitAccount := middlewares.RateLimit(r.limits, "account", accountLimit, middlewares.ByIP)
+
 	public := r.engine.Group("/users")
 	{
-		public.POST("/register/basic", uc.RegisterFirstStep)
-		public.POST("/register/:id/additional-info", uc.RegisterSecondStep)
-		public.POST("/login", uc.LogIn)
+		public.POST("/register/basic", limitRegister, uc.RegisterFirstStep)
+		public.POST("/register/:id/additional-info", limitRegister, uc.RegisterSecondStep)
+		public.POST("/login", limitLogin, uc.LogIn)
+		public.POST("/login/2fa", limitLogin, uc.LogInSecondFactor)
+		public.GET("/oidc/login", limitAccount, uc.OIDCLogIn)
+		public.POST("/oidc/callback", limitLogin, uc.OIDCCallback)
+		public.POST("/refresh", limitAccount, uc.Refresh)
+		public.POST("/verify-email", limitAccount, uc.VerifyEmail)
+		public.POST("/password/forgot", limitAccount, uc.ForgotPassword)
+		public.POST("/password/reset", limitAccount, uc.ResetPassword)
 		public.GET("/:id", uc.GetUser)
 		public.GET("/:id/picture", uc.GetPicture)
-		public.GET("/search", uc.SearchUsers)
+		public.GET("/search", middlewares.AuthPublicMiddleware, uc.SearchUsers)
 	}
 
 	private := r.engine.Group("/users")
-	private.Use(middlewares.AuthMiddleware)
+	private.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
 	{
 		private.GET("/", uc.GetUsers)
 		private.POST("/picture", uc.PostPicture)
+		private.POST("/logout", uc.LogOut)
+		private.POST("/logout/all", uc.LogOutAll)
+		private.POST("/verify-email/resend", uc.ResendVerificationEmail)
+		private.POST("/2fa/enroll", uc.EnrollTOTP)
+		private.POST("/2fa/confirm", uc.ConfirmTOTP)
+		private.POST("/2fa/disable", uc.DisableTOTP)
 	}
+
+	admin := r.engine.Group("/admin/users")
+	admin.Use(middlewares.AuthMiddleware, middlewares.RequireRole(auth.RoleAdmin))
+	{
+		admin.GET("", uc.GetUsersByRole)
+		admin.PUT("/:id/role", uc.ChangeUserRole)
+	}
+
 	return us
 }
 
-func addBooksHandlers(r *Router, conn *sqlx.DB) (booksService.BooksService, booksRepository.BooksDatabase) {
-	booksRepo, err := booksRepository.NewPostgresBookRepository(conn)
-	if err != nil {
-		fmt.Println("error: %w", err)
-	}
-	if booksRepo == nil {
-		fmt.Println("booksRepo is nil")
-	}
+func addBooksHandlers(r *Router, conn *sqlx.DB, blobs storage.BlobStore) (booksService.BooksService, booksRepository.BooksDatabase) {
+	booksRepo := booksRepository.NewPostgresBookRepository(conn, blobs)
 	bs := booksService.NewBooksServiceImpl(booksRepo)
 	bc := booksController.NewBooksController(bs)
 
@@ -162,10 +302,16 @@ func addBooksHandlers(r *Router, conn *sqlx.DB) (booksService.BooksService, book
 		public.GET("/genres", bc.GetGenres)
 	}
 
+	// Moderators can change the books of any author
+	canEditBooks := middlewares.RequireRole(append([]auth.Role{auth.RoleAuthor}, auth.ModeratorRoles...)...)
+
 	private := r.engine.Group("/books")
-	private.Use(middlewares.AuthMiddleware)
+	private.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
 	{
-		private.POST("/", bc.PublishBook)
+		private.POST("/", middlewares.RequireRole(auth.RoleAuthor), bc.PublishBook)
+		private.PUT("/:id", canEditBooks, bc.ReplaceBook)
+		private.PATCH("/:id", canEditBooks, bc.UpdateBook)
+		private.DELETE("/:id", canEditBooks, bc.DeleteBook)
 		private.POST("/:id/reviews", bc.ReviewBook)
 		private.POST("/:id/rating", bc.RateBook)
 		private.PUT("/:id/rating", bc.UpdateRatingOfBook)
@@ -173,16 +319,14 @@ func addBooksHandlers(r *Router, conn *sqlx.DB) (booksService.BooksService, book
 		private.GET("/user/:id/reviews", bc.GetAllReviewsOfUser)
 		private.DELETE("/:id/reviews", bc.DeleteReview)
 		private.PUT("/:id/reviews", bc.EditReview)
+		private.DELETE("/:id/reviews/:userId", bc.DeleteReview)
 	}
 
 	return bs, booksRepo
 }
 
 func AddBookshelfHandlers(r *Router, conn *sqlx.DB, books booksService.BooksService) {
-	bookshelfRepo, err := bookshelfRepository.NewPostgresBookShelfRepository(conn)
-	if err != nil {
-		fmt.Println("error: %w", err)
-	}
+	bookshelfRepo := bookshelfRepository.NewPostgresBookShelfRepository(conn)
 	bs := bookshelfService.NewBookShelfServiceImpl(bookshelfRepo, books)
 	bc := bookshelfController.NewBookshelfController(bs)
 
@@ -190,14 +334,40 @@ func AddBookshelfHandlers(r *Router, conn *sqlx.DB, books booksService.BooksServ
 	{
 		public.GET("/:id/shelf", bc.GetBookShelf)
 		public.GET("/:id/shelf/search", bc.SearchBookShelf)
+		public.GET("/:id/shelves", bc.GetShelves)
+		public.GET("/:id/goals", bc.GetGoals)
+		public.GET("/:id/goals/:year", bc.GetGoal)
+		public.GET("/:id/goals/:year/books", bc.GetGoalBooks)
+		public.GET("/:id/stats", bc.GetStats)
+	}
+
+	goals := r.engine.Group("users/goals")
+	goals.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
+	{
+		goals.PUT("/:year", bc.SetGoal)
+		goals.DELETE("/:year", bc.DeleteGoal)
 	}
 
 	private := r.engine.Group("users/shelf")
-	private.Use(middlewares.AuthMiddleware)
+	private.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
 	{
 		private.POST("/", bc.AddBookToShelf)
 		private.PUT("/", bc.EditBookInShelf)
 		private.DELETE("/", bc.DeleteBookFromShelf)
+		private.POST("/import", bc.ImportGoodreads)
+		private.GET("/export", bc.ExportLibrary)
+		private.POST("/custom", bc.CreateShelf)
+		private.PUT("/custom/:shelfId", bc.RenameShelf)
+		private.DELETE("/custom/:shelfId", bc.DeleteShelf)
+		private.POST("/custom/:shelfId/books", bc.AddBookToCustomShelf)
+		private.DELETE("/custom/:shelfId/books/:bookId", bc.RemoveBookFromCustomShelf)
+		private.PUT("/books/:bookId/shelves", bc.SetBookCustomShelves)
+		private.POST("/books/:bookId/progress", bc.AddProgress)
+		private.GET("/books/:bookId/progress", bc.GetProgressHistory)
+		private.GET("/books/:bookId/reads", bc.GetReadThroughs)
+		private.POST("/books/:bookId/reads", bc.AddReadThrough)
+		private.PUT("/books/:bookId/reads/:readId", bc.UpdateReadThrough)
+		private.DELETE("/books/:bookId/reads/:readId", bc.DeleteReadThrough)
 	}
 }
 
@@ -215,19 +385,18 @@ func AddRecommendationsHandlers(r *Router, conn *sqlx.DB, books booksService.Boo
 }
 
 func addFriendsHandlers(r *Router, users usersService.UsersService, conn *sqlx.DB) {
-	friendsRepo, err := friendsRepository.NewPostgresFriendsRepository(conn)
-	if err != nil {
-		fmt.Println("error: %w", err)
-	}
+	friendsRepo := friendsRepository.NewPostgresFriendsRepository(conn)
 	fs := friendsService.NewFriendsServiceImpl(friendsRepo, users)
 	fc := friendsController.NewFriendsController(fs)
 	public := r.engine.Group("users")
 	{
 		public.GET("/:id/friends", fc.GetFriends)
+		public.GET("/:id/followers", fc.GetFollowers)
+		public.GET("/:id/following", fc.GetFollowing)
 	}
 
 	private := r.engine.Group("users/friends")
-	private.Use(middlewares.AuthMiddleware)
+	private.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
 	{
 		private.POST("/", fc.AddFriend)
 		private.DELETE("/", fc.DeleteFriend)
@@ -237,13 +406,33 @@ func addFriendsHandlers(r *Router, users usersService.UsersService, conn *sqlx.D
 		private.GET("/requests/received", fc.GetFriendRequestsReceived)
 	}
 
-}
+	follows := r.engine.Group("users/follows")
+	follows.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
+	{
+		follows.POST("/", fc.Follow)
+		follows.DELETE("/", fc.Unfollow)
+	}
 
-func AddCommunitiesHandlers(r *Router, conn *sqlx.DB) {
-	communitiesRepo, err := communitiesRepository.NewPostgresCommunitiesRepository(conn)
-	if err != nil {
-		fmt.Println("error: %w", err)
+	blocks := r.engine.Group("users/blocks")
+	blocks.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
+	{
+		blocks.GET("/", fc.GetBlocked)
+		blocks.POST("/", fc.Block)
+		blocks.DELETE("/", fc.Unblock)
+	}
+
+	mutes := r.engine.Group("users/mutes")
+	mutes.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
+	{
+		mutes.GET("/", fc.GetMuted)
+		mutes.POST("/", fc.Mute)
+		mutes.DELETE("/", fc.Unmute)
 	}
+
+}
+
+func AddCommunitiesHandlers(r *Router, conn *sqlx.DB, blobs storage.BlobStore) {
+	communitiesRepo := communitiesRepository.NewPostgresCommunitiesRepository(conn, blobs)
 	cs := communitiesService.NewCommunitiesServiceImpl(communitiesRepo)
 	cc := communitiesController.NewCommunitiesController(cs)
 
@@ -259,11 +448,13 @@ func AddCommunitiesHandlers(r *Router, conn *sqlx.DB) {
 	}
 
 	private := r.engine.Group("communities")
-	private.Use(middlewares.AuthMiddleware)
+	private.Use(middlewares.AuthMiddleware, middlewares.WritesOnly(middlewares.RateLimit(r.limits, "writes", writeLimit, middlewares.ByUser)))
 	{
 		private.POST("/", cc.CreateCommunity)
 		private.POST("/:id/join", cc.JoinCommunity)
 		private.POST("/:id/posts", cc.CreateCommunityPost)
+		private.PUT("/:id/posts/:postId", cc.EditCommunityPost)
+		private.DELETE("/:id/posts/:postId", cc.DeleteCommunityPost)
 		private.DELETE("/:id/leave", cc.LeaveCommunity)
 		private.DELETE("/:id", cc.DeleteCommunity)
 	}
@@ -280,9 +471,43 @@ func addFeedHandlers(r *Router, users usersService.UsersService, conn *sqlx.DB)
 	}
 }
 
+// Run serves the requests until the process gets SIGINT or SIGTERM. Then /readyz starts failing, after the
+// drain delay the requests in flight are given the shutdown timeout to finish and the database is closed.
 func (r *Router) Run() {
-	fmt.Println("Server is running on", r.address)
-	if err := r.engine.Run(r.address); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:    r.address,
+		Handler: r.engine,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("server is running", "address", r.address)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
 		log.Fatalln("can't start server: ", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("shutting down server", "drain_delay", r.drainDelay.String(), "timeout", r.shutdownTimeout.String())
+	r.shuttingDown.Store(true)
+	// Keeps serving while the load balancers see /readyz failing and take the replica out
+	time.Sleep(r.drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("requests didn't finish before the shutdown timeout", "error", err)
+	}
+
+	if err := r.db.Close(); err != nil {
+		slog.Error("can't close the database", "error", err)
 	}
+	slog.Info("server stopped")
 }
@@ -4,13 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"time"
 
 	"github.com/betterreads/internal/domains/books/models"
 	"github.com/betterreads/internal/domains/books/service"
 	aux "github.com/betterreads/internal/pkg/controller"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -31,35 +32,33 @@ func NewBooksController(bookService service.BooksService) *BooksController {
 // @Accept  mpfd
 // @Produce  json
 // @Param data formData string true "Book Data" follows model NewBookRequest
-// @Param file formData file true "Book Picture"
+// @Param file formData file true "Book Picture, a JPEG, PNG or WebP image up to 5 MB"
 // @Param book body models.NewBookRequest true "Don't need to send this in json, this param is only here to reference NewBookRequest, DONT SEND PICTURE in JSON"
 // @Success 201 {object} models.Book
 // @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 413 {object} errors.ErrorDetailsWithParams
+// @Failure 415 {object} errors.ErrorDetailsWithParams
 // @Failure 500 {object} errors.ErrorDetails
 // @Router /books [post]
 func (bc *BooksController) PublishBook(ctx *gin.Context) {
-	isAuthor := ctx.GetBool("IsAuthor")
 	userId, errDetail := aux.GetLoggedUserId(ctx)
 	if errDetail != nil {
 		ctx.AbortWithError(errDetail.Status, errDetail)
 		return
 	}
 
-	if !isAuthor {
-		errDetails := er.NewErrorDetails("Errorr when publishing Book", fmt.Errorf("User is not an author"), http.StatusUnauthorized)
-		ctx.AbortWithError(errDetails.Status, errDetails)
-		return
-	}
-
 	newBookRequest, errReq := getBookRequest(ctx)
 	if errReq != nil {
 		ctx.AbortWithError(errReq.Status, errReq)
 		return
 	}
 
-	book, err := bc.bookService.PublishBook(newBookRequest, userId)
+	book, err := bc.bookService.PublishBook(ctx.Request.Context(), newBookRequest, userId)
 	if err != nil {
-		if errors.Is(err, service.ErrGenreNotFound) {
+		if errDetail := aux.GetPictureError("Error when publishing Book", err); errDetail != nil {
+			ctx.AbortWithError(errDetail.Status, errDetail)
+		} else if errors.Is(err, service.ErrGenreNotFound) {
 			errDetail := er.NewErrorDetailsWithParams("Error when publishing Book", http.StatusBadRequest, err)
 			ctx.AbortWithError(errDetail.Status, errDetail)
 		} else if errors.Is(err, service.ErrUserNotAuthor) {
@@ -71,6 +70,12 @@ func (bc *BooksController) PublishBook(ctx *gin.Context) {
 		} else if errors.Is(err, service.ErrGenreRequired) {
 			errDetail := er.NewErrorDetailsWithParams("Error when publishing Book", http.StatusBadRequest, err)
 			ctx.AbortWithError(errDetail.Status, errDetail)
+		} else if errors.Is(err, service.ErrInvalidISBN) {
+			errDetail := er.NewErrorDetailsWithParams("Error when publishing Book", http.StatusBadRequest, err)
+			ctx.AbortWithError(errDetail.Status, errDetail)
+		} else if errors.Is(err, service.ErrISBNAlreadyExists) {
+			errDetail := er.NewErrorDetailsWithParams("Error when publishing Book", http.StatusConflict, err)
+			ctx.AbortWithError(errDetail.Status, errDetail)
 		} else {
 			errDetail := er.NewErrorDetails("Error when publishing Book", err, http.StatusInternalServerError)
 			ctx.AbortWithError(errDetail.Status, errDetail)
@@ -83,13 +88,17 @@ func (bc *BooksController) PublishBook(ctx *gin.Context) {
 
 // GetBookInfo godoc
 // @Summary Get book by id
-// @Description Get book id, note that its a UUID
+// @Description Get book id, note that its a UUID. Supports conditional requests with the ETag, and with Last-Modified when not logged in
 // @Tags books
 // @Param id path string true "Book Id"
+// @Param If-None-Match header string false "ETag of the cached book"
+// @Param If-Modified-Since header string false "Last-Modified of the cached book"
 // @Produce  json
 // @Success 200 {object} models.BookResponseWithReview
+// @Success 304
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetails
+// @Failure 410 {object} errors.ErrorDetails
 // @Router /books/{id}/info [get]
 func (bc *BooksController) GetBookInfo(ctx *gin.Context) {
 	userId := aux.GetUserIdIfLogged(ctx)
@@ -101,11 +110,14 @@ func (bc *BooksController) GetBookInfo(ctx *gin.Context) {
 		return
 	}
 
-	book, err := bc.bookService.GetBookInfo(bookUuid, userId)
+	book, err := bc.bookService.GetBookInfo(ctx.Request.Context(), bookUuid, userId)
 	if err != nil {
 		if errors.Is(err, service.ErrBookNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting Book", err, http.StatusNotFound)
 			ctx.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrBookDeleted) {
+			errDetails := er.NewErrorDetails("Error when getting Book", err, http.StatusGone)
+			ctx.AbortWithError(errDetails.Status, errDetails)
 		} else {
 			errDetails := er.NewErrorDetails("Error when getting Book", err, http.StatusInternalServerError)
 			ctx.AbortWithError(errDetails.Status, errDetails)
@@ -113,16 +125,25 @@ func (bc *BooksController) GetBookInfo(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, book)
+	// The review and status of the logged user aren't in updated_at, their book is only validated with the ETag
+	ctx.Header("Vary", "Authorization")
+	modTime, cacheControl := book.Book.UpdatedAt, "public, no-cache"
+	if userId != uuid.Nil {
+		modTime, cacheControl = time.Time{}, "private, no-cache"
+	}
+	if err := aux.SendCachedJSON(ctx, book, modTime, cacheControl); err != nil {
+		errDetails := er.NewErrorDetails("Error when getting Book", err, http.StatusInternalServerError)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	}
 }
 
 // GetBooksByName
-// @Summary Get books by name
-// @Description Get books by name, filters by genre and also sorts, if no books found returns an empty array
+// @Summary Search books
+// @Description Full text search over the title, author, description and genres of the books, tolerating typos. Filters by genre and also sorts, by default the best matches go first. Each book has a match with its relevance and the highlighted title and description, matches are wrapped in <mark></mark>. If no books found returns an empty array
 // @Tags books
-// @Param name query string true "Book Name"
+// @Param name query string true "Search text"
 // @Param genre query string false "Book Genre"
-// @Param sort query string false "Sort by publication_date, total_ratings, avg_rating"
+// @Param sort query string false "Sort by relevance, publication_date, total_ratings, avg_ratings"
 // @Param direction query string false "Sort direction asc or desc"
 // @Produce  json
 // @Success 200 {object} []models.BookResponseWithReview
@@ -134,9 +155,9 @@ func (bc *BooksController) SearchBooksInfo(ctx *gin.Context) {
 	genre := ctx.Query("genre")
 	sort := ctx.Query("sort")
 	direction := ctx.Query("direction")
-	books, err := bc.bookService.SearchBooks(name, genre, userId, sort, direction)
+	books, err := bc.bookService.SearchBooks(ctx.Request.Context(), name, genre, userId, sort, direction)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidSort) {
+		if errors.Is(err, service.ErrInvalidSearchSort) {
 			errDetails := er.NewErrorDetailsWithParams(
 				"Error when searching books", http.StatusBadRequest, err)
 			ctx.AbortWithError(errDetails.Status, errDetails)
@@ -178,7 +199,7 @@ func (bc *BooksController) GetBooksOfAuthor(ctx *gin.Context) {
 		return
 	}
 
-	books, err := bc.bookService.GetBooksOfAuthor(authorId, userId)
+	books, err := bc.bookService.GetBooksOfAuthor(ctx.Request.Context(), authorId, userId)
 	if err != nil {
 		if errors.Is(err, service.ErrAuthorNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting books author", err, http.StatusNotFound)
@@ -200,10 +221,15 @@ func (bc *BooksController) GetBooksOfAuthor(ctx *gin.Context) {
 // @Description Get book id, note that its a UUID
 // @Tags books
 // @Param id path string true "Book Id"
-// @Produce jpeg
+// @Param size query string false "thumb, medium or large (default)"
+// @Param If-None-Match header string false "ETag of the cached picture"
+// @Produce jpeg,png
 // @Success 200 {file} []byte
+// @Success 204
+// @Success 304
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetails
+// @Failure 410 {object} errors.ErrorDetails
 // @Router /books/{id}/picture [get]
 func (bc *BooksController) GetBookPicture(ctx *gin.Context) {
 	id := ctx.Param("id")
@@ -214,11 +240,20 @@ func (bc *BooksController) GetBookPicture(ctx *gin.Context) {
 		return
 	}
 
-	base64Bytes, err := bc.bookService.GetBookPicture(uuid)
+	size, errSize := aux.GetPictureSize(ctx)
+	if errSize != nil {
+		ctx.AbortWithError(errSize.Status, errSize)
+		return
+	}
+
+	picture, err := bc.bookService.GetBookPicture(ctx.Request.Context(), uuid, size)
 	if err != nil {
 		if errors.Is(err, service.ErrBookNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting Book picture", err, http.StatusNotFound)
 			ctx.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrBookDeleted) {
+			errDetails := er.NewErrorDetails("Error when getting Book picture", err, http.StatusGone)
+			ctx.AbortWithError(errDetails.Status, errDetails)
 		} else {
 			errDetails := er.NewErrorDetails("Error when getting Book picture", err, http.StatusInternalServerError)
 			ctx.AbortWithError(errDetails.Status, errDetails)
@@ -226,11 +261,10 @@ func (bc *BooksController) GetBookPicture(ctx *gin.Context) {
 		return
 	}
 
-	if base64Bytes == nil {
-		ctx.JSON(http.StatusNoContent, gin.H{})
+	if err := aux.SendPicture(ctx, picture); err != nil {
+		errDetails := er.NewErrorDetails("Error when getting Book picture", err, http.StatusInternalServerError)
+		ctx.AbortWithError(errDetails.Status, errDetails)
 	}
-
-	ctx.Data(http.StatusOK, "image/jpeg", base64Bytes)
 }
 
 // GetBooksInfo godoc
@@ -239,12 +273,21 @@ func (bc *BooksController) GetBookPicture(ctx *gin.Context) {
 // @Tags books
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} []models.BookResponseWithReview
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[models.BookResponseWithReview]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
 // @Failure 500 {object} errors.ErrorDetails
 // @Router /books/info [get]
 func (bc *BooksController) GetBooksInfo(ctx *gin.Context) {
 	userId := aux.GetUserIdIfLogged(ctx)
-	books, err := bc.bookService.GetBooksInfo(userId)
+	page, errDetails := aux.GetPageRequest(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	books, err := bc.bookService.GetBooksInfo(ctx.Request.Context(), userId, page)
 	if err != nil {
 		err := er.NewErrorDetails("Error when getting books", err, http.StatusInternalServerError)
 		ctx.AbortWithError(err.Status, err)
@@ -288,7 +331,7 @@ func (bc *BooksController) RateBook(ctx *gin.Context) {
 
 	rateAmount := newBookRating.Rating
 
-	rating, err := bc.bookService.RateBook(bookId, userId, rateAmount)
+	rating, err := bc.bookService.RateBook(ctx.Request.Context(), bookId, userId, rateAmount)
 	if err != nil {
 		if errors.Is(err, service.ErrBookNotFound) {
 			errDetails := er.NewErrorDetails("Error when rating Book", err, http.StatusNotFound)
@@ -347,7 +390,7 @@ func (bc *BooksController) UpdateRatingOfBook(ctx *gin.Context) {
 		return
 	}
 
-	err = bc.bookService.UpdateRating(bookId, userId, newBookRating.Rating)
+	err = bc.bookService.UpdateRating(ctx.Request.Context(), bookId, userId, newBookRating.Rating)
 	if err != nil {
 		if errors.Is(err, service.ErrRatingNotFound) {
 			errDetails := er.NewErrorDetails("Error when updating rating", err, http.StatusNotFound)
@@ -394,7 +437,7 @@ func (bc *BooksController) ReviewBook(ctx *gin.Context) {
 		return
 	}
 
-	if err := bc.bookService.AddReview(bookId, userId, newReview); err != nil {
+	if err := bc.bookService.AddReview(ctx.Request.Context(), bookId, userId, newReview); err != nil {
 		if errors.Is(err, service.ErrReviewAlreadyExists) {
 			errDetails := er.NewErrorDetails("Error when adding review", err, http.StatusConflict)
 			ctx.AbortWithError(errDetails.Status, errDetails)
@@ -418,13 +461,16 @@ func (bc *BooksController) ReviewBook(ctx *gin.Context) {
 
 // GetBooksReviews godoc
 // @Summary Gets reviews of a book
-// @Description Get reviews of a book
+// @Description Get reviews of a book. The reviews of the users blocked by the user logged in, or that blocked them, are hidden
 // @Tags books
 // @Param id path string true "Book Id"
 // @Produce  json
-// @Success 200 {object} []models.ReviewOfBook
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[models.ReviewOfBook]
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetailsWithParams
+// @Failure 410 {object} errors.ErrorDetails
 // @router /books/{id}/review [get]
 func (bc *BooksController) GetBookReviews(ctx *gin.Context) {
 	bookId, err := uuid.Parse(ctx.Param("id"))
@@ -434,18 +480,28 @@ func (bc *BooksController) GetBookReviews(ctx *gin.Context) {
 		return
 	}
 
-	reviews, err := bc.bookService.GetBookReviews(bookId)
+	page, errDetails := aux.GetPageRequest(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	viewerId := aux.GetUserIdIfLogged(ctx)
+	reviews, err := bc.bookService.GetBookReviews(ctx.Request.Context(), bookId, viewerId, page)
 	if err != nil {
 		if err == service.ErrBookNotFound {
 			errDetails := er.NewErrorDetails("Error when getting Book reviews", err, http.StatusNotFound)
 			ctx.AbortWithError(errDetails.Status, errDetails)
+		} else if err == service.ErrBookDeleted {
+			errDetails := er.NewErrorDetails("Error when getting Book reviews", err, http.StatusGone)
+			ctx.AbortWithError(errDetails.Status, errDetails)
 		} else {
 			errDetails := er.NewErrorDetails("Error when getting Book reviews", err, http.StatusInternalServerError)
 			ctx.AbortWithError(errDetails.Status, errDetails)
 		}
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"reviews": reviews})
+	ctx.JSON(http.StatusOK, reviews)
 }
 
 // GetAllReviewsOfUser godoc
@@ -454,7 +510,9 @@ func (bc *BooksController) GetBookReviews(ctx *gin.Context) {
 // @Tags books
 // @Param id path string true "User Id"
 // @Produce  json
-// @Success 200 {object} []models.ReviewOfUser
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[models.ReviewOfUser]
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetailsWithParams
 // @router /books/user/{id}/reviews [get]
@@ -466,7 +524,19 @@ func (bc *BooksController) GetAllReviewsOfUser(ctx *gin.Context) {
 		return
 	}
 
-	reviews, err := bc.bookService.GetAllReviewsOfUser(userId)
+	page, errDetails := aux.GetPageRequest(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	viewerId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	reviews, err := bc.bookService.GetAllReviewsOfUser(ctx.Request.Context(), userId, viewerId, page)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting User reviews", err, http.StatusNotFound)
@@ -477,7 +547,7 @@ func (bc *BooksController) GetAllReviewsOfUser(ctx *gin.Context) {
 		}
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"reviews": reviews})
+	ctx.JSON(http.StatusOK, reviews)
 }
 
 // GetGenres godoc
@@ -489,7 +559,7 @@ func (bc *BooksController) GetAllReviewsOfUser(ctx *gin.Context) {
 // @Failure 500 {object} errors.ErrorDetails
 // @Router /books/genres [get]
 func (bc *BooksController) GetGenres(ctx *gin.Context) {
-	genres, err := bc.bookService.GetGenres()
+	genres, err := bc.bookService.GetGenres(ctx.Request.Context())
 	if err != nil {
 		errDetails := er.NewErrorDetails("Error when getting genres", err, http.StatusInternalServerError)
 		ctx.AbortWithError(errDetails.Status, errDetails)
@@ -500,31 +570,30 @@ func (bc *BooksController) GetGenres(ctx *gin.Context) {
 
 // DeleteReview godoc
 // @Summary Delete review of a book
-// @Description Delete review of a book that belongs to the user
+// @Description Delete review of a book that belongs to the user. Moderators and admins can delete the review of any user passing its id
 // @Tags books
 // @Param id path string true "Book Id"
+// @Param userId path string false "Id of the reviewer, the logged user if empty"
 // @Produce  json
 // @Success 204
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetails
 // @Failure 500 {object} errors.ErrorDetails
-// @Router /books/{id}/review [delete]
+// @Router /books/{id}/reviews/{userId} [delete]
 func (bc *BooksController) DeleteReview(ctx *gin.Context) {
-	userId, errDetails := aux.GetLoggedUserId(ctx)
+	userId, bookId, reviewerId, errDetails := getReviewIds(ctx)
 	if errDetails != nil {
 		ctx.AbortWithError(errDetails.Status, errDetails)
 		return
 	}
 
-	bookId, err := uuid.Parse(ctx.Param("id"))
-	if err != nil {
-		errDetails := er.NewErrorDetails("Error when getting Book id", fmt.Errorf("Invalid uuid %s", ctx.Param("id")), http.StatusBadRequest)
-		ctx.AbortWithError(errDetails.Status, errDetails)
-		return
-	}
-
-	err = bc.bookService.DeleteReview(bookId, userId)
+	err := bc.bookService.DeleteReview(ctx.Request.Context(), bookId, reviewerId, userId, aux.GetLoggedUserRole(ctx))
 	if err != nil {
-		if errors.Is(err, service.ErrReviewNotFound) {
+		if errors.Is(err, service.ErrUserNotReviewer) {
+			errDetails := er.NewErrorDetails("Error when deleting review", err, http.StatusForbidden)
+			ctx.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrReviewNotFound) {
 			errDetails := er.NewErrorDetails("Error when deleting review", err, http.StatusNotFound)
 			ctx.AbortWithError(errDetails.Status, errDetails)
 		} else {
@@ -547,28 +616,21 @@ func (bc *BooksController) DeleteReview(ctx *gin.Context) {
 // @Failure 400 {object} errors.ErrorDetailsWithParams
 // @Failure 404 {object} errors.ErrorDetails
 // @Failure 500 {object} errors.ErrorDetails
-// @Router /books/{id}/review [put]
+// @Router /books/{id}/reviews [put]
 func (bc *BooksController) EditReview(ctx *gin.Context) {
-	userId, errDetails := aux.GetLoggedUserId(ctx)
+	userId, bookId, _, errDetails := getReviewIds(ctx)
 	if errDetails != nil {
 		ctx.AbortWithError(errDetails.Status, errDetails)
 		return
 	}
 
-	bookId, err := uuid.Parse(ctx.Param("id"))
-	if err != nil {
-		errDetails := er.NewErrorDetails("Error when getting Book id", fmt.Errorf("Invalid uuid %s", ctx.Param("id")), http.StatusBadRequest)
-		ctx.AbortWithError(errDetails.Status, errDetails)
-		return
-	}
-
 	var newReview models.NewReviewRequest
 	if err := ctx.ShouldBindJSON(&newReview); err != nil {
 		er.AbortWithJsonErorr(ctx, err)
 		return
 	}
 
-	err = bc.bookService.EditReview(bookId, userId, newReview)
+	err := bc.bookService.EditReview(ctx.Request.Context(), bookId, userId, newReview)
 	if err != nil {
 		if errors.Is(err, service.ErrReviewNotFound) {
 			errDetails := er.NewErrorDetails("Error when editing review", err, http.StatusNotFound)
@@ -587,6 +649,28 @@ func (bc *BooksController) EditReview(ctx *gin.Context) {
 }
 
 // AUX FUNCTIONS
+// getReviewIds returns the logged user, the book and the reviewer, which is the logged user when
+// the userId param is empty.
+func getReviewIds(ctx *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, *er.ErrorDetails) {
+	userId, errDetails := aux.GetLoggedUserId(ctx)
+	if errDetails != nil {
+		return uuid.Nil, uuid.Nil, uuid.Nil, errDetails
+	}
+
+	bookId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, uuid.Nil, er.NewErrorDetails("Error when getting Book id", fmt.Errorf("Invalid uuid %s", ctx.Param("id")), http.StatusBadRequest)
+	}
+
+	reviewerId := userId
+	if param := ctx.Param("userId"); param != "" {
+		if reviewerId, err = uuid.Parse(param); err != nil {
+			return uuid.Nil, uuid.Nil, uuid.Nil, er.NewErrorDetails("Error when getting User id", fmt.Errorf("Invalid uuid %s", param), http.StatusBadRequest)
+		}
+	}
+	return userId, bookId, reviewerId, nil
+}
+
 /*
 * getBookRequest is a helper function that parses the request body and returns a New
 * Book Request struct. It also gets the picture from the request and adds it to the
@@ -616,6 +700,13 @@ func getBookRequest(ctx *gin.Context) (*models.NewBookRequest, *er.ErrorDetailsW
 
 // Aux
 func getPicture(ctx *gin.Context) ([]byte, *er.ErrorDetailsWithParams) {
+	if err := aux.ParsePictureForm(ctx); err != nil {
+		if errDetails := aux.GetPictureError("Error Publishing Book", err); errDetails != nil {
+			return nil, errDetails
+		}
+		return nil, er.NewErrorDetailsWithParams("Error Publishing Book", http.StatusBadRequest, err)
+	}
+
 	file, _, err := ctx.Request.FormFile("file")
 	if err != nil {
 		errParam := er.ErrorParam{
@@ -625,8 +716,11 @@ func getPicture(ctx *gin.Context) ([]byte, *er.ErrorDetailsWithParams) {
 		return nil, er.NewErrorDetailsWithParams("Error Publishing Book", http.StatusBadRequest, errParam)
 	}
 	defer file.Close()
-	picture, err := io.ReadAll(file)
+	picture, err := images.ReadUpload(file)
 	if err != nil {
+		if errDetails := aux.GetPictureError("Error Publishing Book", err); errDetails != nil {
+			return nil, errDetails
+		}
 		errParam := er.ErrorParam{
 			Name:   "picture",
 			Reason: "file is invalid",
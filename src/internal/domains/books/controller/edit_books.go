@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/books/models"
+	"github.com/betterreads/internal/domains/books/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ReplaceBook godoc
+// @Summary Replace a book
+// @Description Replaces the data of a book, only its author, a moderator or an admin can do it. The data follows models.NewBookRequest in JSON like when publishing, the picture is optional and is kept when it isn't sent
+// @Tags books
+// @Accept  mpfd
+// @Produce  json
+// @Param id path string true "Book Id"
+// @Param data formData string true "Book Data" follows model NewBookRequest
+// @Param file formData file false "Book Picture, a JPEG, PNG or WebP image up to 5 MB"
+// @Success 200 {object} models.BookResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetailsWithParams
+// @Failure 410 {object} errors.ErrorDetails
+// @Failure 413 {object} errors.ErrorDetailsWithParams
+// @Failure 415 {object} errors.ErrorDetailsWithParams
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /books/{id} [put]
+func (bc *BooksController) ReplaceBook(ctx *gin.Context) {
+	userId, bookId, errDetails := getBookEditIds(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	req, errReq := getReplaceBookRequest(ctx)
+	if errReq != nil {
+		ctx.AbortWithError(errReq.Status, errReq)
+		return
+	}
+
+	book, err := bc.bookService.UpdateBook(ctx.Request.Context(), bookId, userId, aux.GetLoggedUserRole(ctx), req)
+	if err != nil {
+		abortWithEditBookError(ctx, "Error when updating Book", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, book)
+}
+
+// UpdateBook godoc
+// @Summary Update a book
+// @Description Changes only the sent fields of a book, only its author, a moderator or an admin can do it. Genres replace the previous ones and an empty isbn removes it
+// @Tags books
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Book Id"
+// @Param book body models.UpdateBookRequest true "Fields to change"
+// @Success 200 {object} models.BookResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetailsWithParams
+// @Failure 410 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /books/{id} [patch]
+func (bc *BooksController) UpdateBook(ctx *gin.Context) {
+	userId, bookId, errDetails := getBookEditIds(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	var req models.UpdateBookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(ctx, err)
+		return
+	}
+
+	book, err := bc.bookService.UpdateBook(ctx.Request.Context(), bookId, userId, aux.GetLoggedUserRole(ctx), &req)
+	if err != nil {
+		abortWithEditBookError(ctx, "Error when updating Book", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, book)
+}
+
+// DeleteBook godoc
+// @Summary Delete a book
+// @Description Deletes a book, only its author, a moderator or an admin can do it. The book is removed from the catalogue but stays in the shelves of its readers marked as deleted, and its reviews are kept
+// @Tags books
+// @Param id path string true "Book Id"
+// @Success 204
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 410 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /books/{id} [delete]
+func (bc *BooksController) DeleteBook(ctx *gin.Context) {
+	userId, bookId, errDetails := getBookEditIds(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	if err := bc.bookService.DeleteBook(ctx.Request.Context(), bookId, userId, aux.GetLoggedUserRole(ctx)); err != nil {
+		abortWithEditBookError(ctx, "Error when deleting Book", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func getBookEditIds(ctx *gin.Context) (uuid.UUID, uuid.UUID, *er.ErrorDetails) {
+	userId, errDetails := aux.GetLoggedUserId(ctx)
+	if errDetails != nil {
+		return uuid.Nil, uuid.Nil, errDetails
+	}
+
+	bookId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, er.NewErrorDetails("Error when getting Book id", fmt.Errorf("Invalid uuid %s", ctx.Param("id")), http.StatusBadRequest)
+	}
+	return userId, bookId, nil
+}
+
+// getReplaceBookRequest parses the same form as publishing a book, but the picture is optional.
+func getReplaceBookRequest(ctx *gin.Context) (*models.UpdateBookRequest, *er.ErrorDetailsWithParams) {
+	if err := aux.ParsePictureForm(ctx); err != nil {
+		if errDetails := aux.GetPictureError("Error getting book data", err); errDetails != nil {
+			return nil, errDetails
+		}
+		return nil, er.NewErrorDetailsWithParams("Error getting book data", http.StatusBadRequest, err)
+	}
+
+	var bookRequest models.NewBookRequest
+	if err := json.Unmarshal([]byte(ctx.PostForm("data")), &bookRequest); err != nil {
+		return nil, er.NewErrorDetailsWithParams("Error getting book data", http.StatusBadRequest, err)
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(bookRequest); err != nil {
+		return nil, er.NewErrorDetailsWithParams("Error getting book data", http.StatusBadRequest, err)
+	}
+
+	req := &models.UpdateBookRequest{
+		Title:           &bookRequest.Title,
+		Description:     &bookRequest.Description,
+		AmountOfPages:   &bookRequest.AmountOfPages,
+		PublicationDate: &bookRequest.PublicationDate,
+		Language:        &bookRequest.Language,
+		Genres:          bookRequest.Genres,
+		ISBN:            &bookRequest.ISBN,
+	}
+
+	file, _, err := ctx.Request.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return req, nil
+	} else if err != nil {
+		errParam := er.ErrorParam{Name: "picture", Reason: "file is invalid"}
+		return nil, er.NewErrorDetailsWithParams("Error getting book data", http.StatusBadRequest, errParam)
+	}
+	defer file.Close()
+
+	req.Picture, err = images.ReadUpload(file)
+	if err != nil {
+		if errDetails := aux.GetPictureError("Error getting book data", err); errDetails != nil {
+			return nil, errDetails
+		}
+		errParam := er.ErrorParam{Name: "picture", Reason: "file is invalid"}
+		return nil, er.NewErrorDetailsWithParams("Error getting book data", http.StatusBadRequest, errParam)
+	}
+	return req, nil
+}
+
+func abortWithEditBookError(ctx *gin.Context, title string, err error) {
+	if errDetails := aux.GetPictureError(title, err); errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrGenreNotFound) || errors.Is(err, service.ErrGenreRequired) ||
+		errors.Is(err, service.ErrInvalidISBN) || errors.Is(err, service.ErrEmptyBookField) ||
+		errors.Is(err, service.ErrInvalidAmountOfPages) {
+		errDetails := er.NewErrorDetailsWithParams(title, http.StatusBadRequest, err)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrISBNAlreadyExists) {
+		errDetails := er.NewErrorDetailsWithParams(title, http.StatusConflict, err)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrUserNotAuthor) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusForbidden)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrBookNotFound) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusNotFound)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrBookDeleted) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusGone)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	} else {
+		errDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		ctx.AbortWithError(errDetails.Status, errDetails)
+	}
+}
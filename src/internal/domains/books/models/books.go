@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // RECORDS
 type Book struct {
@@ -15,12 +19,13 @@ type Book struct {
 	Id              uuid.UUID `json:"id" binding:"required" db:"id"`
 	TotalRatings    int       `json:"total_ratings" db:"total_ratings"`
 	AverageRating   float64   `json:"avg_rating" db:"avg_rating"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 } // This struct is used to return the genres and book from the database
 
 type BookRecord struct {
 	Title           string    `json:"title" db:"title"`
 	Author          uuid.UUID `json:"author" db:"author"`
-	AuthorName      string    `json:"author" db:"author_name"`
+	AuthorName      string    `json:"author_name" db:"author_name"`
 	Description     string    `json:"description" db:"description"`
 	AmountOfPages   int       `json:"amount_of_pages" db:"amount_of_pages"`
 	PublicationDate string    `json:"publication_date" db:"publication_date"`
@@ -28,8 +33,21 @@ type BookRecord struct {
 	Id              uuid.UUID `json:"id" db:"id"`
 	TotalRatings    int       `json:"total_ratings" db:"total_ratings"`
 	AverageRating   float64   `json:"avg_rating" db:"avg_ratings"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type BookSearchRecord struct {
+	BookRecord
+	Relevance            float64 `json:"relevance" db:"relevance"`
+	TitleHighlight       *string `json:"title_highlight" db:"title_highlight"`
+	DescriptionHighlight *string `json:"description_highlight" db:"description_highlight"`
 }
 
+type BookSearchResult struct {
+	Book  *Book
+	Match *BookSearchMatch
+} // A book with how well it matched the search
+
 type BookDb struct {
 	Title           string    `json:"title" db:"title"`
 	Author          uuid.UUID `json:"author" db:"author"`
@@ -38,6 +56,7 @@ type BookDb struct {
 	PublicationDate string    `json:"publication_date" db:"publication_date"`
 	Language        string    `json:"language" db:"language"`
 	Id              uuid.UUID `json:"id" db:"id"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type GenreBook struct {
@@ -77,9 +96,22 @@ type NewBookRequest struct {
 	PublicationDate string   `json:"publication_date" validate:"required"`
 	Language        string   `json:"language" validate:"required"`
 	Genres          []string `json:"genres" validate:"required"`
+	ISBN            string   `json:"isbn"`
 	Picture         []byte   `json:"picture"`
 }
 
+// Only the fields that are sent are changed, genres replace the previous ones and an empty isbn removes it.
+type UpdateBookRequest struct {
+	Title           *string  `json:"title"`
+	Description     *string  `json:"description"`
+	AmountOfPages   *int     `json:"amount_of_pages"`
+	PublicationDate *string  `json:"publication_date"`
+	Language        *string  `json:"language"`
+	Genres          []string `json:"genres"`
+	ISBN            *string  `json:"isbn"`
+	Picture         []byte   `json:"-"`
+}
+
 type NewRatingRequest struct {
 	Rating int `json:"rating" binding:"required"`
 }
@@ -102,6 +134,7 @@ type BookResponse struct {
 	TotalRatings    int       `json:"total_ratings"`
 	AverageRating   float64   `json:"avg_rating"`
 	Id              uuid.UUID `json:"id"`
+	UpdatedAt       time.Time `json:"updated_at"` // Last time the book was edited or rated
 }
 
 type ReviewOfUser struct {
@@ -120,8 +153,15 @@ type ReviewOfBook struct {
 	PublicationDate string    `json:"publication_date" db:"publication_date"`
 }
 
+type BookSearchMatch struct {
+	Relevance            float64 `json:"relevance"`
+	TitleHighlight       *string `json:"title_highlight,omitempty"`
+	DescriptionHighlight *string `json:"description_highlight,omitempty"`
+}
+
 type BookResponseWithReview struct {
-	Book            *BookResponse `json:"book"`
-	Review          *Review       `json:"review,omitempty"`
-	BookShelfStatus *string       `json:"status,omitempty"`
+	Book            *BookResponse    `json:"book"`
+	Review          *Review          `json:"review,omitempty"`
+	BookShelfStatus *string          `json:"status,omitempty"`
+	Match           *BookSearchMatch `json:"match,omitempty"`
 }
@@ -1,9 +1,14 @@
 package repository
 
 import (
+	"context"
+
 	"errors"
 
 	"github.com/betterreads/internal/domains/books/models"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -45,32 +50,39 @@ var (
 	ErrReviewNotFound      = errors.New("review not found")
 	ErrReviewEmpty         = errors.New("review is empty")
 	ErrUserNotFound        = errors.New("user not found")
+	ErrBookDeleted         = errors.New("book deleted")
 )
 
 type BooksDatabase interface {
-	SaveBook(*models.NewBookRequest, uuid.UUID) (*models.Book, error)
-	GetBookById(id uuid.UUID) (*models.Book, error)
-	GetBookPictureById(id uuid.UUID) ([]byte, error)
-	GetBooks() ([]*models.Book, error)
-	GetBooksOfAuthor(authorId uuid.UUID) ([]*models.Book, error)
-	GetBooksByNameAndGenre(name string, genre string, sort string, directAsc bool) ([]*models.Book, error)
-	GetGenresForBook(book_id uuid.UUID) ([]string, error)
-	GetGenres() ([]string, error)
+	SaveBook(ctx context.Context, book *models.NewBookRequest, author uuid.UUID, picture *images.Picture) (*models.Book, error)
+	GetBookById(ctx context.Context, id uuid.UUID) (*models.Book, error)
+	GetBookPictureById(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error)
+	GetBooks(ctx context.Context, page pagination.Request) (pagination.Page[*models.Book], error)
+	GetBooksOfAuthor(ctx context.Context, authorId uuid.UUID) ([]*models.Book, error)
+	SearchBooks(ctx context.Context, query string, genre string, sort string, directAsc bool) ([]*models.BookSearchResult, error)
+	GetGenresForBook(ctx context.Context, book_id uuid.UUID) ([]string, error)
+	GetGenres(ctx context.Context) ([]string, error)
+	UpdateBook(ctx context.Context, bookId uuid.UUID, req *models.UpdateBookRequest, picture *images.Picture) error
+	DeleteBook(ctx context.Context, bookId uuid.UUID) error
+	GetBookAuthor(ctx context.Context, bookId uuid.UUID) (uuid.UUID, error)
 
-	CheckIfBookExists(bookId uuid.UUID) bool
-	CheckIfUserExists(userId uuid.UUID) bool
-	CheckIfUserIsAuthor(authorId uuid.UUID) bool
+	CheckIfBookExists(ctx context.Context, bookId uuid.UUID) bool
+	CheckIfUserExists(ctx context.Context, userId uuid.UUID) bool
+	CheckIfUserIsAuthor(ctx context.Context, authorId uuid.UUID) bool
+	CheckIfISBNExists(ctx context.Context, isbn string) bool
+	CheckIfISBNUsedByOtherBook(ctx context.Context, isbn string, bookId uuid.UUID) bool
+	CheckIfBookDeleted(ctx context.Context, bookId uuid.UUID) bool
 
-	RateBook(bookId uuid.UUID, userId uuid.UUID, rating int) (*models.Rating, error)
-	UpdateRating(bookId uuid.UUID, userId uuid.UUID, rating int) error
-	CheckIfRatingExists(bookId uuid.UUID, userId uuid.UUID) (bool, error)
+	RateBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rating int) (*models.Rating, error)
+	UpdateRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rating int) error
+	CheckIfRatingExists(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (bool, error)
 
-	AddReview(bookId uuid.UUID, userId uuid.UUID, review string, rating int) error
-	CheckifReviewExists(bookId uuid.UUID, userId uuid.UUID) (bool, error)
-	GetBookReviews(bookID uuid.UUID) ([]*models.ReviewOfBook, error)
-	GetBookReviewOfUser(bookId uuid.UUID, userId uuid.UUID) (*models.Review, error)
-	GetBookshelfStatusOfUser(bookId uuid.UUID, userId uuid.UUID) (*string, error)
-	GetAllReviewsOfUser(userId uuid.UUID) ([]*models.ReviewOfUser, error)
-	EditReview(bookId uuid.UUID, userId uuid.UUID, rating int, review string) error
-	DeleteReview(bookId uuid.UUID, userId uuid.UUID) error
+	AddReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, review string, rating int) error
+	CheckifReviewExists(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (bool, error)
+	GetBookReviews(ctx context.Context, bookID uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfBook], error)
+	GetBookReviewOfUser(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (*models.Review, error)
+	GetBookshelfStatusOfUser(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (*string, error)
+	GetAllReviewsOfUser(ctx context.Context, userId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfUser], error)
+	EditReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rating int, review string) error
+	DeleteReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) error
 }
@@ -1,18 +1,24 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 
 	"fmt"
 
 	"github.com/betterreads/internal/domains/books/models"
 	"github.com/betterreads/internal/domains/books/utils"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type PostgresBookRepository struct {
-	c *sqlx.DB
+	c     *sqlx.DB
+	blobs storage.BlobStore
 }
 
 func GetGenreById(genre string) (int, error) {
@@ -28,130 +34,20 @@ func GetGenre(genre int) string {
 	return GenresDict[genre]
 }
 
-func NewPostgresBookRepository(c *sqlx.DB) (BooksDatabase, error) {
-	enableUUIDExtension := `CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`
-	if _, err := c.Exec(enableUUIDExtension); err != nil {
-		return nil, fmt.Errorf("failed to enable uuid extension: %w", err)
-	}
-
-	schemaBooks := `
-		CREATE TABLE IF NOT EXISTS books (
-			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-			title VARCHAR(255) NOT NULL,
-			author UUID  NOT NULL,
-			description VARCHAR(255) NOT NULL,
-			amount_of_pages INTEGER NOT NULL,
-			publication_date VARCHAR(255) NOT NULL,
-            language VARCHAR(255) NOT NULL,
-			FOREIGN KEY (author) REFERENCES users(id)
-			);
-			`
-	schemaGendersBooks := `
-        CREATE TABLE IF NOT EXISTS genres_books (
-            book_id UUID NOT NULL,
-            genre_id INT,
-            PRIMARY KEY (book_id, genre_id),
-            FOREIGN KEY (book_id) REFERENCES books(id)
-        );
-    `
-
-	schemaReviews := `
-        CREATE TABLE IF NOT EXISTS reviews (
-            user_id UUID,
-            book_id UUID,
-            rating INTEGER NOT NULL,
-            review VARCHAR (255) NOT NULL,
-			publication_date VARCHAR(255) NOT NULL DEFAULT CURRENT_DATE,
-            PRIMARY KEY (user_id, book_id),
-            FOREIGN KEY (book_id) REFERENCES books(id),
-            FOREIGN KEY (user_id) REFERENCES users(id)
-        );
-    `
-
-	schemaPictures := `
-		CREATE TABLE IF NOT EXISTS pictures (
-			book_id UUID,
-			picture BYTEA,
-			FOREIGN KEY (book_id) REFERENCES books(id),
-			PRIMARY KEY (book_id)
-		);
-		`
-
-	if _, err := c.Exec(schemaBooks); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	if _, err := c.Exec(schemaReviews); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	if _, err := c.Exec(schemaGendersBooks); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-	if _, err := c.Exec(schemaPictures); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	if err := defineView(c); err != nil {
-		return nil, fmt.Errorf("failed to create view: %w", err)
-	}
-
-	return &PostgresBookRepository{c}, nil
-}
-
-func defineView(c *sqlx.DB) error {
-	query := `
-    DO $$
-    BEGIN
-        -- Check if the view exists
-        IF NOT EXISTS (SELECT 1 FROM pg_catalog.pg_views WHERE viewname = 'book_view') THEN
-            -- Create the view if it doesn't exist
-            CREATE VIEW book_view AS
-            WITH ratings AS (
-                SELECT 
-                    book_id, 
-                    COUNT(*) AS total_ratings, 
-                    AVG(COALESCE(rating, 0)) AS avg_ratings
-                FROM 
-                    reviews
-                GROUP BY 
-                    book_id
-            )
-            SELECT 
-                bk.title, 
-                bk.author, 
-                (SELECT username FROM users WHERE id = bk.author) AS author_name, 
-                bk.description, 
-                bk.amount_of_pages, 
-                bk.publication_date, 
-                bk.language, 
-                bk.id,
-                COALESCE(r.total_ratings, 0) AS total_ratings,
-                COALESCE(r.avg_ratings, 0) AS avg_ratings
-            FROM 
-                books bk
-            LEFT JOIN 
-                ratings r ON bk.id = r.book_id;
-        END IF;
-    END $$;
-    `
-
-	if _, err := c.Exec(query); err != nil {
-		return fmt.Errorf("failed to create book view: %w", err)
-	}
-	return nil
+func NewPostgresBookRepository(c *sqlx.DB, blobs storage.BlobStore) BooksDatabase {
+	return &PostgresBookRepository{c, blobs}
 }
 
-func (r *PostgresBookRepository) SaveBook(book *models.NewBookRequest, author uuid.UUID) (*models.Book, error) {
+func (r *PostgresBookRepository) SaveBook(ctx context.Context, book *models.NewBookRequest, author uuid.UUID, picture *images.Picture) (*models.Book, error) {
 	bookRecord := &models.BookDb{}
 	query := `INSERT INTO books (title, author, description,  amount_of_pages,
-                    publication_date, language)
-                    VALUES ($1, $2, $3, $4, $5, $6)
-                    RETURNING id, title, author, description, amount_of_pages, publication_date, language;`
+                    publication_date, language, isbn)
+                    VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+                    RETURNING id, title, author, description, amount_of_pages, publication_date, language, updated_at;`
 
-	args := []interface{}{book.Title, author, book.Description, book.AmountOfPages, book.PublicationDate, book.Language}
+	args := []interface{}{book.Title, author, book.Description, book.AmountOfPages, book.PublicationDate, book.Language, book.ISBN}
 
-	if err := r.c.Get(bookRecord, query, args...); err != nil {
+	if err := r.c.GetContext(ctx, bookRecord, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to create book: %w", err)
 	}
 
@@ -164,21 +60,16 @@ func (r *PostgresBookRepository) SaveBook(book *models.NewBookRequest, author uu
 			return nil, ErrGenreNotFound
 		}
 		args = []interface{}{bookRecord.Id, genreid}
-		if _, err := r.c.Exec(query, args...); err != nil {
+		if _, err := r.c.ExecContext(ctx, query, args...); err != nil {
 			return nil, fmt.Errorf("failed to create book: %w", err)
 		}
 	}
 
-	query = `INSERT INTO pictures (book_id, picture)
-			VAlUES ($1, $2);`
-
-	args = []interface{}{bookRecord.Id, book.Picture}
-
-	if _, err := r.c.Exec(query, args...); err != nil {
+	if err := r.saveBookPicture(ctx, r.c, bookRecord.Id, picture); err != nil {
 		return nil, fmt.Errorf("failed to create book: %w", err)
 	}
 
-	authorName, err := r.getAuthorName(author)
+	authorName, err := r.getAuthorName(ctx, author)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create book: %w", err)
 	}
@@ -188,10 +79,10 @@ func (r *PostgresBookRepository) SaveBook(book *models.NewBookRequest, author uu
 	return res, nil
 }
 
-func (r *PostgresBookRepository) GetGenresForBook(book_id uuid.UUID) ([]string, error) {
+func (r *PostgresBookRepository) GetGenresForBook(ctx context.Context, book_id uuid.UUID) ([]string, error) {
 	var genres_ids []int
 	query := `SELECT genre_id FROM genres_books WHERE book_id = $1;`
-	if err := r.c.Select(&genres_ids, query, book_id); err != nil {
+	if err := r.c.SelectContext(ctx, &genres_ids, query, book_id); err != nil {
 		return nil, fmt.Errorf("failed to get genres: %w", err)
 	}
 
@@ -203,17 +94,17 @@ func (r *PostgresBookRepository) GetGenresForBook(book_id uuid.UUID) ([]string,
 	return genres, nil
 }
 
-func (r *PostgresBookRepository) GetBookById(id uuid.UUID) (*models.Book, error) {
+func (r *PostgresBookRepository) GetBookById(ctx context.Context, id uuid.UUID) (*models.Book, error) {
 	bookRecord := &models.BookRecord{}
 	query := `SELECT * FROM book_view WHERE id = $1;`
-	if err := r.c.Get(bookRecord, query, id); err != nil {
+	if err := r.c.GetContext(ctx, bookRecord, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrBookNotFound
 		}
 		return nil, fmt.Errorf("failed to get book: %w", err)
 	}
 
-	genres, err := r.GetGenresForBook(id)
+	genres, err := r.GetGenresForBook(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get book: %w", err)
 	}
@@ -221,25 +112,44 @@ func (r *PostgresBookRepository) GetBookById(id uuid.UUID) (*models.Book, error)
 	return utils.MapBookRecordToBook(bookRecord, genres), nil
 }
 
-func (r *PostgresBookRepository) GetBooksByNameAndGenre(name string, genre string, sort string, ascDirection bool) ([]*models.Book, error) {
-	bookRecords := []*models.BookRecord{}
-	var query string
-	query_start := `SELECT bk.title, bk.author, bk.author_name, bk.description, bk.amount_of_pages, bk.publication_date, bk.language, bk.id, bk.total_ratings, bk.avg_ratings FROM book_view bk
-    `
-	var err error
-	var genre_id int
-
-	if genre == "" {
-		query = query_start + "WHERE LOWER(bk.title) LIKE LOWER('%'||$1||'%')"
-	} else {
-		genre_id, err = GetGenreById(genre)
+// Matches the query against the weighted search_vector of the book (title, author, description
+// and genres) and fuzzily against the title and author name, so typos like "hobit" still match.
+const searchQuery = `
+    SELECT
+        bk.title, bk.author, bk.author_name, bk.description, bk.amount_of_pages, bk.publication_date,
+        bk.language, bk.id, bk.total_ratings, bk.avg_ratings, bk.updated_at,
+        CASE WHEN $1 = '' THEN 0 ELSE
+            ts_rank_cd(b.search_vector, websearch_to_tsquery('english', $1)) +
+            GREATEST(word_similarity($1, b.title), word_similarity($1, bk.author_name))
+        END AS relevance,
+        CASE WHEN $1 = '' THEN NULL ELSE
+            ts_headline('english', b.title, websearch_to_tsquery('english', $1),
+                'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+        END AS title_highlight,
+        CASE WHEN $1 = '' THEN NULL ELSE
+            ts_headline('english', b.description, websearch_to_tsquery('english', $1),
+                'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')
+        END AS description_highlight
+    FROM book_view bk
+    JOIN books b ON b.id = bk.id
+    WHERE ($1 = ''
+        OR b.search_vector @@ websearch_to_tsquery('english', $1)
+        OR $1 <% b.title
+        OR $1 <% bk.author_name)
+`
+
+func (r *PostgresBookRepository) SearchBooks(ctx context.Context, search string, genre string, sort string, ascDirection bool) ([]*models.BookSearchResult, error) {
+	records := []*models.BookSearchRecord{}
+	query := searchQuery
+	args := []interface{}{search}
+
+	if genre != "" {
+		genreId, err := GetGenreById(genre)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get books: %w", err)
 		}
-		query = query_start + `
-        JOIN genres_books gb ON bk.id = gb.book_id
-        WHERE (LOWER(bk.title) like lower('%'||$1||'%')) and gb.genre_id = $2
-        `
+		query += " AND EXISTS(SELECT 1 FROM genres_books gb WHERE gb.book_id = bk.id AND gb.genre_id = $2)"
+		args = append(args, genreId)
 	}
 
 	if sort != "" {
@@ -249,113 +159,139 @@ func (r *PostgresBookRepository) GetBooksByNameAndGenre(name string, genre strin
 		} else {
 			direciton = "DESC"
 		}
-		query += " ORDER BY " + sort + " " + direciton
-	}
-
-	if genre == "" {
-		err = r.c.Select(&bookRecords, query, name)
-	} else {
-		err = r.c.Select(&bookRecords, query, name, genre_id)
+		query += " ORDER BY " + sort + " " + direciton + ", bk.id"
 	}
 
-	if err != nil {
+	if err := r.c.SelectContext(ctx, &records, query, args...); err != nil {
 		if err != sql.ErrNoRows {
 			return nil, fmt.Errorf("failed to get books: %w", err)
 		}
 	}
 
-	res, err := r.CompleteBooks(bookRecords)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get book: %w", err)
+	res := []*models.BookSearchResult{}
+	for _, record := range records {
+		genres, err := r.GetGenresForBook(ctx, record.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get book: %w", err)
+		}
+		res = append(res, utils.MapBookSearchRecordToBookSearchResult(record, genres))
 	}
 
 	return res, nil
 }
 
-func (r *PostgresBookRepository) GetBookPictureById(id uuid.UUID) ([]byte, error) {
-	var picture []byte
-	query := `SELECT picture FROM pictures WHERE book_id = $1;`
-	if err := r.c.Get(&picture, query, id); err != nil {
+func (r *PostgresBookRepository) GetBookPictureById(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error) {
+	record := &storage.PictureRecord{}
+	query := `SELECT p.picture, p.blob_key, p.content_type, p.variants, p.etag, p.updated_at FROM pictures p
+			  JOIN books bk ON bk.id = p.book_id
+			  WHERE p.book_id = $1 AND bk.deleted_at IS NULL;`
+	if err := r.c.GetContext(ctx, record, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get book: %w", err)
 	}
-	return picture, nil
+	return storage.LoadPicture(ctx, r.blobs, record, string(size))
+}
+
+// saveBookPicture puts the picture in the blob store and points the book to it.
+func (r *PostgresBookRepository) saveBookPicture(ctx context.Context, exec sqlx.ExecerContext, bookId uuid.UUID, picture *images.Picture) error {
+	key := storage.BookPictureKey(bookId.String())
+	if err := picture.Save(ctx, r.blobs, key); err != nil {
+		return fmt.Errorf("failed to save picture: %w", err)
+	}
+
+	query := `INSERT INTO pictures (book_id, blob_key, content_type, variants, etag) VALUES ($1, $2, $3, true, $4)
+			  ON CONFLICT (book_id) DO UPDATE
+			  SET picture = NULL, blob_key = EXCLUDED.blob_key, content_type = EXCLUDED.content_type, variants = true,
+			  etag = EXCLUDED.etag, updated_at = now();`
+	if _, err := exec.ExecContext(ctx, query, bookId, key, picture.ContentType, picture.ETag); err != nil {
+		return fmt.Errorf("failed to save picture: %w", err)
+	}
+	return nil
 }
 
-func (r *PostgresBookRepository) GetBooks() ([]*models.Book, error) {
+func (r *PostgresBookRepository) GetBooks(ctx context.Context, page pagination.Request) (pagination.Page[*models.Book], error) {
 	books := []*models.BookRecord{}
-	query := `SELECT * FROM book_view;`
-	if err := r.c.Select(&books, query); err != nil {
-		if err == sql.ErrNoRows {
-			return []*models.Book{}, nil
+	query := `SELECT * FROM book_view bk`
+	args := []interface{}{}
+	if page.Cursor != nil {
+		query += ` WHERE (bk.title, bk.id::TEXT) > ($1, $2)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(` ORDER BY bk.title, bk.id::TEXT LIMIT $%d;`, len(args)+1)
+	args = append(args, page.Fetch())
+
+	if err := r.c.SelectContext(ctx, &books, query, args...); err != nil {
+		if err != sql.ErrNoRows {
+			return pagination.Page[*models.Book]{}, fmt.Errorf("failed to get books: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get books: %w", err)
 	}
-	res, error := r.CompleteBooks(books)
+	res, error := r.CompleteBooks(ctx, books)
 	if error != nil {
-		return nil, fmt.Errorf("failed to get book: %w", error)
+		return pagination.Page[*models.Book]{}, fmt.Errorf("failed to get book: %w", error)
 	}
-	return res, nil
+	return pagination.NewPage(res, page, func(book *models.Book) pagination.Cursor {
+		return pagination.Cursor{Key: book.Title, Id: book.Id.String()}
+	}), nil
 }
 
-func (r *PostgresBookRepository) GetBooksOfAuthor(authorId uuid.UUID) ([]*models.Book, error) {
+func (r *PostgresBookRepository) GetBooksOfAuthor(ctx context.Context, authorId uuid.UUID) ([]*models.Book, error) {
 	books := []*models.BookRecord{}
 	query := `SELECT * FROM book_view WHERE author = $1;`
-	if err := r.c.Select(&books, query, authorId); err != nil {
+	if err := r.c.SelectContext(ctx, &books, query, authorId); err != nil {
 		if err == sql.ErrNoRows {
 			return []*models.Book{}, nil
 		}
 		return nil, fmt.Errorf("failed to get books: %w", err)
 	}
 
-	res, err := r.CompleteBooks(books)
+	res, err := r.CompleteBooks(ctx, books)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get book: %w", err)
 	}
 	return res, nil
 }
 
-func (r *PostgresBookRepository) RateBook(bookId uuid.UUID, userId uuid.UUID, rating int) (*models.Rating, error) {
+func (r *PostgresBookRepository) RateBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rating int) (*models.Rating, error) {
 	var ratingRecord models.Rating
 	query := `INSERT INTO reviews (user_id, book_id, rating, review)
 			VALUES ($1, $2, $3, $4)
 			RETURNING user_id, book_id, rating;`
 	args := []interface{}{userId, bookId, rating, ""}
 
-	if err := r.c.Get(&ratingRecord, query, args...); err != nil {
+	if err := r.c.GetContext(ctx, &ratingRecord, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to rate book: %w", err)
 	}
 	return &ratingRecord, nil
 }
 
-func (r *PostgresBookRepository) CheckIfRatingExists(bookId uuid.UUID, userId uuid.UUID) (bool, error) {
+func (r *PostgresBookRepository) CheckIfRatingExists(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM reviews WHERE user_id = $1 AND book_id = $2);`
 	var exists bool
-	if err := r.c.Get(&exists, query, userId, bookId); err != nil {
+	if err := r.c.GetContext(ctx, &exists, query, userId, bookId); err != nil {
 		return false, err
 	} else {
 		return exists, nil
 	}
 }
 
-func (r *PostgresBookRepository) UpdateRating(bookId uuid.UUID, userId uuid.UUID, rating int) error {
+func (r *PostgresBookRepository) UpdateRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rating int) error {
 	query := `UPDATE reviews SET rating = $1 WHERE user_id = $2 AND book_id = $3;`
 	args := []interface{}{rating, userId, bookId}
-	if _, err := r.c.Exec(query, args...); err != nil {
+	if _, err := r.c.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to update rating: %w", err)
 	}
 
 	return nil
 }
 
-func (r *PostgresBookRepository) GetBookReviewOfUser(bookId uuid.UUID, userId uuid.UUID) (*models.Review, error) {
+func (r *PostgresBookRepository) GetBookReviewOfUser(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (*models.Review, error) {
 	var ratings models.ReviewDb
 	query := `SELECT * FROM reviews WHERE book_id = $1 AND user_id = $2;`
 	args := []interface{}{bookId, userId}
 
-	if err := r.c.Get(&ratings, query, args...); err != nil {
+	if err := r.c.GetContext(ctx, &ratings, query, args...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrReviewNotFound
 		}
@@ -368,30 +304,39 @@ func (r *PostgresBookRepository) GetBookReviewOfUser(bookId uuid.UUID, userId uu
 	return ReviewRes, nil
 }
 
-func (r *PostgresBookRepository) GetAllReviewsOfUser(userId uuid.UUID) ([]*models.ReviewOfUser, error) {
-
+func (r *PostgresBookRepository) GetAllReviewsOfUser(ctx context.Context, userId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfUser], error) {
 	res := []*models.ReviewOfUser{}
 	query := `
         SELECT b.title AS book_title, r.review,b.id as book_id, r.rating, r.publication_date
         FROM reviews r
         INNER JOIN books b ON r.book_id = b.id
         WHERE r.user_id = $1
-		ORDER BY r.publication_date DESC;
+        AND NOT EXISTS (SELECT 1 FROM blocks bl
+            WHERE (bl.blocker_id = $2 AND bl.blocked_id = r.user_id) OR (bl.blocker_id = r.user_id AND bl.blocked_id = $2))
     `
-	if err := r.c.Select(&res, query, userId); err != nil {
-		if err == sql.ErrNoRows {
-			return []*models.ReviewOfUser{}, nil
+	args := []interface{}{userId, viewerId}
+	if page.Cursor != nil {
+		query += ` AND (r.publication_date, r.book_id::TEXT) < ($3, $4)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(` ORDER BY r.publication_date DESC, r.book_id::TEXT DESC LIMIT $%d;`, len(args)+1)
+	args = append(args, page.Fetch())
+
+	if err := r.c.SelectContext(ctx, &res, query, args...); err != nil {
+		if err != sql.ErrNoRows {
+			return pagination.Page[*models.ReviewOfUser]{}, fmt.Errorf("failed to get reviews: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get reviews: %w", err)
 	}
 
-	return res, nil
+	return pagination.NewPage(res, page, func(review *models.ReviewOfUser) pagination.Cursor {
+		return pagination.Cursor{Key: review.PublicationDate, Id: review.BookId.String()}
+	}), nil
 }
 
-func (r *PostgresBookRepository) getAuthorName(authorId uuid.UUID) (string, error) {
+func (r *PostgresBookRepository) getAuthorName(ctx context.Context, authorId uuid.UUID) (string, error) {
 	var authorName string
 	query := `SELECT username FROM users WHERE id = $1;`
-	if err := r.c.Get(&authorName, query, authorId); err != nil {
+	if err := r.c.GetContext(ctx, &authorName, query, authorId); err != nil {
 		if err == sql.ErrNoRows {
 			return "", ErrAuthorNotFound
 		}
@@ -400,45 +345,45 @@ func (r *PostgresBookRepository) getAuthorName(authorId uuid.UUID) (string, erro
 	return authorName, nil
 }
 
-func (r *PostgresBookRepository) AddReview(bookId uuid.UUID, userId uuid.UUID, review string, rating int) error {
+func (r *PostgresBookRepository) AddReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, review string, rating int) error {
 	args := []interface{}{userId, bookId}
 	query := `INSERT INTO reviews (user_id, book_id, review, rating)
     VALUES ($1, $2, $3, $4);`
 	args = []interface{}{userId, bookId, review, rating}
 
-	if _, err := r.c.Exec(query, args...); err != nil {
+	if _, err := r.c.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to add review: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgresBookRepository) EditReview(bookId uuid.UUID, userId uuid.UUID, rating int, review string) error {
+func (r *PostgresBookRepository) EditReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rating int, review string) error {
 	query := `UPDATE reviews SET review = $1, rating = $2 WHERE book_id = $3 AND user_id = $4;`
 	args := []interface{}{review, rating, bookId, userId}
-	if _, err := r.c.Exec(query, args...); err != nil {
+	if _, err := r.c.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to update review: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgresBookRepository) DeleteReview(bookId uuid.UUID, userId uuid.UUID) error {
+func (r *PostgresBookRepository) DeleteReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) error {
 	query := `DELETE FROM reviews WHERE book_id = $1 AND user_id = $2;`
 	args := []interface{}{bookId, userId}
-	if _, err := r.c.Exec(query, args...); err != nil {
+	if _, err := r.c.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to delete review: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgresBookRepository) DeleteRating(bookId uuid.UUID, userId uuid.UUID) error {
-	return r.DeleteReview(bookId, userId)
+func (r *PostgresBookRepository) DeleteRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) error {
+	return r.DeleteReview(ctx, bookId, userId)
 }
 
-func (r *PostgresBookRepository) CheckifReviewExists(bookId uuid.UUID, userId uuid.UUID) (bool, error) {
+func (r *PostgresBookRepository) CheckifReviewExists(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (bool, error) {
 	reviewCheck := &models.ReviewDb{}
 	query := `SELECT * FROM reviews WHERE book_id = $1 AND user_id = $2;`
 	args := []interface{}{bookId, userId}
-	if err := r.c.Get(reviewCheck, query, args...); err != nil {
+	if err := r.c.GetContext(ctx, reviewCheck, query, args...); err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
 		}
@@ -452,30 +397,39 @@ func (r *PostgresBookRepository) CheckifReviewExists(bookId uuid.UUID, userId uu
 	return true, nil
 }
 
-func (r *PostgresBookRepository) GetBookReviews(bookID uuid.UUID) ([]*models.ReviewOfBook, error) {
+func (r *PostgresBookRepository) GetBookReviews(ctx context.Context, bookID uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfBook], error) {
 	res := []*models.ReviewOfBook{}
 	query := `
         SELECT u.username, r.review, u.id AS user_id, r.rating, r.publication_date
         FROM reviews r
         INNER JOIN users u ON r.user_id = u.id
         WHERE r.book_id = $1
-		ORDER BY r.publication_date DESC;
+        AND NOT EXISTS (SELECT 1 FROM blocks bl
+            WHERE (bl.blocker_id = $2 AND bl.blocked_id = r.user_id) OR (bl.blocker_id = r.user_id AND bl.blocked_id = $2))
     `
+	args := []interface{}{bookID, viewerId}
+	if page.Cursor != nil {
+		query += ` AND (r.publication_date, r.user_id::TEXT) < ($3, $4)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(` ORDER BY r.publication_date DESC, r.user_id::TEXT DESC LIMIT $%d;`, len(args)+1)
+	args = append(args, page.Fetch())
 
-	if err := r.c.Select(&res, query, bookID); err != nil {
-		if err == sql.ErrNoRows {
-			return []*models.ReviewOfBook{}, nil
+	if err := r.c.SelectContext(ctx, &res, query, args...); err != nil {
+		if err != sql.ErrNoRows {
+			return pagination.Page[*models.ReviewOfBook]{}, fmt.Errorf("failed to get reviews: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get reviews: %w", err)
 	}
 
-	return res, nil
+	return pagination.NewPage(res, page, func(review *models.ReviewOfBook) pagination.Cursor {
+		return pagination.Cursor{Key: review.PublicationDate, Id: review.UserId.String()}
+	}), nil
 }
 
-func (r *PostgresBookRepository) GetBookshelfStatusOfUser(bookId uuid.UUID, userId uuid.UUID) (*string, error) {
+func (r *PostgresBookRepository) GetBookshelfStatusOfUser(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (*string, error) {
 	var status string
 	query := `SELECT status FROM bookshelf WHERE book_id = $1 AND user_id = $2;`
-	if err := r.c.Get(&status, query, bookId, userId); err != nil {
+	if err := r.c.GetContext(ctx, &status, query, bookId, userId); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrBookNotInShelf
 		}
@@ -484,37 +438,50 @@ func (r *PostgresBookRepository) GetBookshelfStatusOfUser(bookId uuid.UUID, user
 	return &status, nil
 }
 
-func (r *PostgresBookRepository) CheckIfBookExists(bookId uuid.UUID) bool {
+func (r *PostgresBookRepository) CheckIfBookExists(ctx context.Context, bookId uuid.UUID) bool {
+	exists := false
+	query := `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1 AND deleted_at IS NULL);`
+	if err := r.c.GetContext(ctx, &exists, query, bookId); err != nil {
+		logger.FromContext(ctx).Error("failed to check if book exists", "error", err)
+		return false
+	}
+	return exists
+}
+
+func (r *PostgresBookRepository) CheckIfUserIsAuthor(ctx context.Context, authorId uuid.UUID) bool {
 	exists := false
-	query := `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1);`
-	if err := r.c.Get(&exists, query, bookId); err != nil {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'author');`
+	if err := r.c.GetContext(ctx, &exists, query, authorId); err != nil {
+		logger.FromContext(ctx).Error("failed to check if user is author", "error", err)
 		return false
 	}
 	return exists
 }
 
-func (r *PostgresBookRepository) CheckIfUserIsAuthor(authorId uuid.UUID) bool {
+func (r *PostgresBookRepository) CheckIfISBNExists(ctx context.Context, isbn string) bool {
 	exists := false
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND is_author = true);`
-	if err := r.c.Get(&exists, query, authorId); err != nil {
+	query := `SELECT EXISTS(SELECT 1 FROM books WHERE isbn = $1 AND deleted_at IS NULL);`
+	if err := r.c.GetContext(ctx, &exists, query, isbn); err != nil {
+		logger.FromContext(ctx).Error("failed to check if isbn exists", "error", err)
 		return false
 	}
 	return exists
 }
 
-func (r *PostgresBookRepository) CheckIfUserExists(userId uuid.UUID) bool {
+func (r *PostgresBookRepository) CheckIfUserExists(ctx context.Context, userId uuid.UUID) bool {
 	exists := false
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1);`
-	if err := r.c.Get(&exists, query, userId); err != nil {
+	if err := r.c.GetContext(ctx, &exists, query, userId); err != nil {
+		logger.FromContext(ctx).Error("failed to check if user exists", "error", err)
 		return false
 	}
 	return exists
 }
 
-func (r *PostgresBookRepository) CompleteBooks(books []*models.BookRecord) ([]*models.Book, error) {
+func (r *PostgresBookRepository) CompleteBooks(ctx context.Context, books []*models.BookRecord) ([]*models.Book, error) {
 	res := []*models.Book{}
 	for _, book := range books {
-		genres, err := r.GetGenresForBook(book.Id)
+		genres, err := r.GetGenresForBook(ctx, book.Id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get book: %w", err)
 		}
@@ -523,7 +490,7 @@ func (r *PostgresBookRepository) CompleteBooks(books []*models.BookRecord) ([]*m
 	return res, nil
 }
 
-func (r *PostgresBookRepository) GetGenres() ([]string, error) {
+func (r *PostgresBookRepository) GetGenres(ctx context.Context) ([]string, error) {
 	genres := []string{}
 	for _, genre := range GenresDict {
 		genres = append(genres, genre)
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/books/models"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// UpdateBook changes the sent fields of the book, the genres and the picture are replaced when sent.
+func (r *PostgresBookRepository) UpdateBook(ctx context.Context, bookId uuid.UUID, req *models.UpdateBookRequest, picture *images.Picture) error {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE books SET
+				title = COALESCE($2, title),
+				description = COALESCE($3, description),
+				amount_of_pages = COALESCE($4, amount_of_pages),
+				publication_date = COALESCE($5, publication_date),
+				language = COALESCE($6, language),
+				isbn = CASE WHEN $7::TEXT IS NULL THEN isbn ELSE NULLIF($7, '') END
+			  WHERE id = $1 AND deleted_at IS NULL;`
+	args := []interface{}{bookId, req.Title, req.Description, req.AmountOfPages, req.PublicationDate, req.Language, req.ISBN}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
+	} else if rows == 0 {
+		return ErrBookNotFound
+	}
+
+	if req.Genres != nil {
+		query = `DELETE FROM genres_books WHERE book_id = $1;`
+		if _, err := tx.ExecContext(ctx, query, bookId); err != nil {
+			return fmt.Errorf("failed to update genres: %w", err)
+		}
+
+		query = `INSERT INTO genres_books (book_id, genre_id) VALUES ($1, $2);`
+		for _, genre := range req.Genres {
+			genreId, err := GetGenreById(genre)
+			if err != nil {
+				return ErrGenreNotFound
+			}
+			if _, err := tx.ExecContext(ctx, query, bookId, genreId); err != nil {
+				return fmt.Errorf("failed to update genres: %w", err)
+			}
+		}
+	}
+
+	if picture != nil {
+		if err := r.saveBookPicture(ctx, tx, bookId, picture); err != nil {
+			return fmt.Errorf("failed to update picture: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	return nil
+}
+
+// DeleteBook leaves a tombstone, the book is hidden from the catalogue but the shelves,
+// reads and reviews that have it are kept.
+func (r *PostgresBookRepository) DeleteBook(ctx context.Context, bookId uuid.UUID) error {
+	query := `UPDATE books SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL;`
+	res, err := r.c.ExecContext(ctx, query, bookId)
+	if err != nil {
+		return fmt.Errorf("failed to delete book: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete book: %w", err)
+	} else if rows == 0 {
+		return ErrBookNotFound
+	}
+	return nil
+}
+
+// GetBookAuthor returns the author of the book, or ErrBookDeleted when the book was deleted.
+func (r *PostgresBookRepository) GetBookAuthor(ctx context.Context, bookId uuid.UUID) (uuid.UUID, error) {
+	var book struct {
+		Author  uuid.UUID `db:"author"`
+		Deleted bool      `db:"deleted"`
+	}
+	query := `SELECT author, deleted_at IS NOT NULL AS deleted FROM books WHERE id = $1;`
+	if err := r.c.GetContext(ctx, &book, query, bookId); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, ErrBookNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	if book.Deleted {
+		return uuid.Nil, ErrBookDeleted
+	}
+	return book.Author, nil
+}
+
+func (r *PostgresBookRepository) CheckIfISBNUsedByOtherBook(ctx context.Context, isbn string, bookId uuid.UUID) bool {
+	exists := false
+	query := `SELECT EXISTS(SELECT 1 FROM books WHERE isbn = $1 AND id <> $2 AND deleted_at IS NULL);`
+	if err := r.c.GetContext(ctx, &exists, query, isbn, bookId); err != nil {
+		logger.FromContext(ctx).Error("failed to check if isbn is used by other book", "error", err)
+		return false
+	}
+	return exists
+}
+
+func (r *PostgresBookRepository) CheckIfBookDeleted(ctx context.Context, bookId uuid.UUID) bool {
+	deleted := false
+	query := `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1 AND deleted_at IS NOT NULL);`
+	if err := r.c.GetContext(ctx, &deleted, query, bookId); err != nil {
+		logger.FromContext(ctx).Error("failed to check if book is deleted", "error", err)
+		return false
+	}
+	return deleted
+}
@@ -1,12 +1,18 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/betterreads/internal/domains/books/models"
 	"github.com/betterreads/internal/domains/books/repository"
 	"github.com/betterreads/internal/domains/books/utils"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -18,20 +24,36 @@ func NewBooksServiceImpl(booksRepository repository.BooksDatabase) BooksService
 	return &BooksServiceImpl{booksRepository: booksRepository}
 }
 
-func (bs *BooksServiceImpl) PublishBook(req *models.NewBookRequest, author uuid.UUID) (*models.BookResponse, error) {
+func (bs *BooksServiceImpl) PublishBook(ctx context.Context, req *models.NewBookRequest, author uuid.UUID) (*models.BookResponse, error) {
 	if len(req.Genres) == 0 {
 		return nil, ErrGenreRequired
 	}
 
-	if !bs.booksRepository.CheckIfUserExists(author) {
+	if !bs.booksRepository.CheckIfUserExists(ctx, author) {
 		return nil, ErrAuthorNotFound
 	}
 
-	if !bs.booksRepository.CheckIfUserIsAuthor(author) {
+	if !bs.booksRepository.CheckIfUserIsAuthor(ctx, author) {
 		return nil, ErrUserNotAuthor
 	}
 
-	book, err := bs.booksRepository.SaveBook(req, author)
+	if req.ISBN != "" {
+		isbn, ok := utils.NormalizeISBN(req.ISBN)
+		if !ok {
+			return nil, ErrInvalidISBN
+		}
+		if bs.booksRepository.CheckIfISBNExists(ctx, isbn) {
+			return nil, ErrISBNAlreadyExists
+		}
+		req.ISBN = isbn
+	}
+
+	picture, err := images.Process(req.Picture)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := bs.booksRepository.SaveBook(ctx, req, author, picture)
 	if err != nil {
 		if errors.Is(err, repository.ErrGenreNotFound) {
 			return nil, ErrGenreNotFound
@@ -39,21 +61,22 @@ func (bs *BooksServiceImpl) PublishBook(req *models.NewBookRequest, author uuid.
 		return nil, err
 	}
 
+	metrics.BooksPublished.Inc()
 	res := utils.MapBookToBookResponse(book)
 
 	return res, nil
 }
 
-func (bs *BooksServiceImpl) GetBookInfo(bookId uuid.UUID, userId uuid.UUID) (*models.BookResponseWithReview, error) {
-	book, err := bs.booksRepository.GetBookById(bookId)
+func (bs *BooksServiceImpl) GetBookInfo(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (*models.BookResponseWithReview, error) {
+	book, err := bs.booksRepository.GetBookById(ctx, bookId)
 	if err != nil {
 		if errors.Is(err, repository.ErrBookNotFound) {
-			return nil, ErrBookNotFound
+			return nil, bs.bookNotFoundError(ctx, bookId)
 		}
 		return nil, err
 	}
 
-	bookRes, err := bs.mapBookToBookResponseWithReview(book, userId)
+	bookRes, err := bs.mapBookToBookResponseWithReview(ctx, book, userId)
 
 	if err != nil {
 		return nil, err
@@ -62,18 +85,18 @@ func (bs *BooksServiceImpl) GetBookInfo(bookId uuid.UUID, userId uuid.UUID) (*mo
 	return bookRes, nil
 }
 
-func (bs *BooksServiceImpl) GetBooksOfAuthor(authorId uuid.UUID, userId uuid.UUID) ([]*models.BookResponseWithReview, error) {
-	exists := bs.booksRepository.CheckIfUserExists(authorId)
+func (bs *BooksServiceImpl) GetBooksOfAuthor(ctx context.Context, authorId uuid.UUID, userId uuid.UUID) ([]*models.BookResponseWithReview, error) {
+	exists := bs.booksRepository.CheckIfUserExists(ctx, authorId)
 	if !exists {
 		return nil, ErrAuthorNotFound
 	}
 
-	isAuthor := bs.booksRepository.CheckIfUserIsAuthor(authorId)
+	isAuthor := bs.booksRepository.CheckIfUserIsAuthor(ctx, authorId)
 	if !isAuthor {
 		return nil, ErrUserNotAuthor
 	}
 
-	books, err := bs.booksRepository.GetBooksOfAuthor(authorId)
+	books, err := bs.booksRepository.GetBooksOfAuthor(ctx, authorId)
 	if err != nil {
 		if errors.Is(err, repository.ErrAuthorNotFound) {
 			return nil, ErrAuthorNotFound
@@ -81,12 +104,12 @@ func (bs *BooksServiceImpl) GetBooksOfAuthor(authorId uuid.UUID, userId uuid.UUI
 		return nil, err
 	}
 
-	return bs.mapBooksToBooksResponseWithReview(books, userId)
+	return bs.mapBooksToBooksResponseWithReview(ctx, books, userId)
 }
 
-func (bs *BooksServiceImpl) SearchBooks(name string, genre string, userId uuid.UUID, sort string, direction string) ([]*models.BookResponseWithReview, error) {
+func (bs *BooksServiceImpl) SearchBooks(ctx context.Context, name string, genre string, userId uuid.UUID, sort string, direction string) ([]*models.BookResponseWithReview, error) {
 	if sort != "" {
-		if err := ValidateSort(sort); err != nil {
+		if err := ValidateSearchSort(sort); err != nil {
 			return nil, err
 		}
 	}
@@ -99,9 +122,15 @@ func (bs *BooksServiceImpl) SearchBooks(name string, genre string, userId uuid.U
 		return nil, ErrInvalidDirection
 	}
 
+	// Without an explicit sort the best matches go first
+	if sort == "" && name != "" {
+		sort = SortRelevance
+		direction = "desc"
+	}
+
 	isDirAsc := direction == "asc"
 
-	books, err := bs.booksRepository.GetBooksByNameAndGenre(name, genre, sort, isDirAsc)
+	results, err := bs.booksRepository.SearchBooks(ctx, name, genre, sort, isDirAsc)
 	if err != nil {
 		if errors.Is(err, repository.ErrGenreNotFound) {
 			return nil, ErrGenreNotFound
@@ -109,41 +138,52 @@ func (bs *BooksServiceImpl) SearchBooks(name string, genre string, userId uuid.U
 		return nil, err
 	}
 
-	return bs.mapBooksToBooksResponseWithReview(books, userId)
+	booksResponses := []*models.BookResponseWithReview{}
+	for _, result := range results {
+		bookResponse, err := bs.mapBookToBookResponseWithReview(ctx, result.Book, userId)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			bookResponse.Match = result.Match
+		}
+		booksResponses = append(booksResponses, bookResponse)
+	}
+	return booksResponses, nil
 }
 
-func (bs *BooksServiceImpl) GetBookPicture(id uuid.UUID) ([]byte, error) {
-	exists := bs.booksRepository.CheckIfBookExists(id)
+func (bs *BooksServiceImpl) GetBookPicture(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error) {
+	exists := bs.booksRepository.CheckIfBookExists(ctx, id)
 	if !exists {
-		return nil, ErrBookNotFound
+		return nil, bs.bookNotFoundError(ctx, id)
 	}
 
-	book, err := bs.booksRepository.GetBookPictureById(id)
+	picture, err := bs.booksRepository.GetBookPictureById(ctx, id, size)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get book picture: %w", err)
 	}
 
-	return book, nil
+	return picture, nil
 }
 
-func (bs *BooksServiceImpl) GetBooksInfo(userId uuid.UUID) ([]*models.BookResponseWithReview, error) {
-	books, err := bs.booksRepository.GetBooks()
+func (bs *BooksServiceImpl) GetBooksInfo(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[*models.BookResponseWithReview], error) {
+	books, err := bs.booksRepository.GetBooks(ctx, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*models.BookResponseWithReview]{}, err
 	}
-	res, err := bs.mapBooksToBooksResponseWithReview(books, userId)
+	res, err := bs.mapBooksToBooksResponseWithReview(ctx, books.Data, userId)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*models.BookResponseWithReview]{}, err
 	}
 
-	return res, nil
+	return pagination.Page[*models.BookResponseWithReview]{Data: res, NextCursor: books.NextCursor}, nil
 }
 
-func (bs *BooksServiceImpl) mapBooksToBooksResponseWithReview(books []*models.Book, userId uuid.UUID) ([]*models.BookResponseWithReview, error) {
+func (bs *BooksServiceImpl) mapBooksToBooksResponseWithReview(ctx context.Context, books []*models.Book, userId uuid.UUID) ([]*models.BookResponseWithReview, error) {
 	booksResponses := []*models.BookResponseWithReview{}
 
 	for _, book := range books {
-		bookResponse, err := bs.mapBookToBookResponseWithReview(book, userId)
+		bookResponse, err := bs.mapBookToBookResponseWithReview(ctx, book, userId)
 		if err != nil {
 			return nil, err
 		}
@@ -152,11 +192,11 @@ func (bs *BooksServiceImpl) mapBooksToBooksResponseWithReview(books []*models.Bo
 	return booksResponses, nil
 }
 
-func (bs *BooksServiceImpl) mapBookToBookResponseWithReview(book *models.Book, userId uuid.UUID) (*models.BookResponseWithReview, error) {
+func (bs *BooksServiceImpl) mapBookToBookResponseWithReview(ctx context.Context, book *models.Book, userId uuid.UUID) (*models.BookResponseWithReview, error) {
 	var err error
 	bookRes := &models.BookResponseWithReview{}
 	if userId != uuid.Nil {
-		bookRes.Review, err = bs.booksRepository.GetBookReviewOfUser(book.Id, userId)
+		bookRes.Review, err = bs.booksRepository.GetBookReviewOfUser(ctx, book.Id, userId)
 		if err != nil {
 			if errors.Is(err, repository.ErrReviewNotFound) {
 				bookRes.Review = nil
@@ -164,7 +204,7 @@ func (bs *BooksServiceImpl) mapBookToBookResponseWithReview(book *models.Book, u
 				return nil, err
 			}
 		}
-		bookRes.BookShelfStatus, err = bs.booksRepository.GetBookshelfStatusOfUser(book.Id, userId)
+		bookRes.BookShelfStatus, err = bs.booksRepository.GetBookshelfStatusOfUser(ctx, book.Id, userId)
 		if err != nil {
 			if errors.Is(err, repository.ErrBookNotInShelf) {
 				bookRes.BookShelfStatus = nil
@@ -179,39 +219,39 @@ func (bs *BooksServiceImpl) mapBookToBookResponseWithReview(book *models.Book, u
 	return bookRes, nil
 }
 
-func (bs *BooksServiceImpl) RateBook(bookId uuid.UUID, userId uuid.UUID, rateAmount int) (*models.Rating, error) {
+func (bs *BooksServiceImpl) RateBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rateAmount int) (*models.Rating, error) {
 	if rateAmount < 1 || rateAmount > 5 {
 		return nil, ErrRatingAmount
 	}
 
-	bookExists := bs.booksRepository.CheckIfBookExists(bookId)
+	bookExists := bs.booksRepository.CheckIfBookExists(ctx, bookId)
 	if !bookExists {
 		return nil, ErrBookNotFound
 	}
 
-	if exists, err := bs.booksRepository.CheckIfRatingExists(bookId, userId); err != nil {
+	if exists, err := bs.booksRepository.CheckIfRatingExists(ctx, bookId, userId); err != nil {
 		return nil, err
 	} else if exists {
 		return nil, ErrRatingAlreadyExists
 	}
 
-	if ratingOwnBook, err := bs.CheckIfAuthorIsRatingOwnBook(bookId, userId); err != nil {
+	if ratingOwnBook, err := bs.CheckIfAuthorIsRatingOwnBook(ctx, bookId, userId); err != nil {
 		return nil, err
 	} else if ratingOwnBook {
 		return nil, ErrRatingOwnBook
 	}
 
-	bookRating, err := bs.booksRepository.RateBook(bookId, userId, rateAmount)
+	bookRating, err := bs.booksRepository.RateBook(ctx, bookId, userId, rateAmount)
 	if err != nil {
 		return nil, err
 	}
 	return bookRating, nil
 }
 
-func (bs *BooksServiceImpl) CheckIfAuthorIsRatingOwnBook(bookId uuid.UUID, userId uuid.UUID) (bool, error) {
-	isAuthor := bs.booksRepository.CheckIfUserIsAuthor(userId)
+func (bs *BooksServiceImpl) CheckIfAuthorIsRatingOwnBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (bool, error) {
+	isAuthor := bs.booksRepository.CheckIfUserIsAuthor(ctx, userId)
 	if isAuthor {
-		AuthorsBooks, err := bs.booksRepository.GetBooksOfAuthor(userId)
+		AuthorsBooks, err := bs.booksRepository.GetBooksOfAuthor(ctx, userId)
 		if err != nil {
 			return false, err
 		}
@@ -224,62 +264,68 @@ func (bs *BooksServiceImpl) CheckIfAuthorIsRatingOwnBook(bookId uuid.UUID, userI
 	return false, nil
 }
 
-func (bs *BooksServiceImpl) UpdateRating(bookId uuid.UUID, userId uuid.UUID, rateAmount int) error {
+func (bs *BooksServiceImpl) UpdateRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rateAmount int) error {
 	if rateAmount < 1 || rateAmount > 5 {
 		return ErrRatingAmount
 	}
 
-	if exists, err := bs.booksRepository.CheckIfRatingExists(bookId, userId); err != nil {
+	if exists, err := bs.booksRepository.CheckIfRatingExists(ctx, bookId, userId); err != nil {
 		return err
 	} else if !exists {
 		return ErrRatingNotFound
 	}
 
-	if ratingOwnBook, err := bs.CheckIfAuthorIsRatingOwnBook(bookId, userId); err != nil {
+	if ratingOwnBook, err := bs.CheckIfAuthorIsRatingOwnBook(ctx, bookId, userId); err != nil {
 		return err
 	} else if ratingOwnBook {
 		return ErrRatingOwnBook
 	}
 
-	err := bs.booksRepository.UpdateRating(bookId, userId, rateAmount)
+	err := bs.booksRepository.UpdateRating(ctx, bookId, userId, rateAmount)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (bs *BooksServiceImpl) GetBookReviews(bookId uuid.UUID) ([]*models.ReviewOfBook, error) {
-	reviews, err := bs.booksRepository.GetBookReviews(bookId)
+// GetBookReviews hides the reviews of the users blocked by the viewer or that blocked them,
+// the viewer is uuid.Nil when there is no user logged in.
+func (bs *BooksServiceImpl) GetBookReviews(ctx context.Context, bookId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfBook], error) {
+	if bs.booksRepository.CheckIfBookDeleted(ctx, bookId) {
+		return pagination.Page[*models.ReviewOfBook]{}, ErrBookDeleted
+	}
+
+	reviews, err := bs.booksRepository.GetBookReviews(ctx, bookId, viewerId, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*models.ReviewOfBook]{}, err
 	}
 	return reviews, nil
 }
 
-func (bs *BooksServiceImpl) GetAllReviewsOfUser(userId uuid.UUID) ([]*models.ReviewOfUser, error) {
-	exists := bs.booksRepository.CheckIfUserExists(userId)
+func (bs *BooksServiceImpl) GetAllReviewsOfUser(ctx context.Context, userId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfUser], error) {
+	exists := bs.booksRepository.CheckIfUserExists(ctx, userId)
 	if !exists {
-		return nil, ErrUserNotFound
+		return pagination.Page[*models.ReviewOfUser]{}, ErrUserNotFound
 	}
 
-	reviews, err := bs.booksRepository.GetAllReviewsOfUser(userId)
+	reviews, err := bs.booksRepository.GetAllReviewsOfUser(ctx, userId, viewerId, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*models.ReviewOfUser]{}, err
 	}
 	return reviews, nil
 }
 
-func (bs *BooksServiceImpl) AddReview(bookId uuid.UUID, userId uuid.UUID, review models.NewReviewRequest) error {
+func (bs *BooksServiceImpl) AddReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, review models.NewReviewRequest) error {
 	if review.Rating < 1 || review.Rating > 5 {
 		return ErrRatingAmount
 	}
 
-	bookExists := bs.booksRepository.CheckIfBookExists(bookId)
+	bookExists := bs.booksRepository.CheckIfBookExists(ctx, bookId)
 	if !bookExists {
 		return ErrBookNotFound
 	}
 
-	exists, err := bs.booksRepository.CheckifReviewExists(bookId, userId)
+	exists, err := bs.booksRepository.CheckifReviewExists(ctx, bookId, userId)
 	if err != repository.ErrReviewEmpty && err != nil {
 		return err
 	}
@@ -288,28 +334,29 @@ func (bs *BooksServiceImpl) AddReview(bookId uuid.UUID, userId uuid.UUID, review
 		return ErrReviewAlreadyExists
 	}
 
-	if ratingOwnBook, err := bs.CheckIfAuthorIsRatingOwnBook(bookId, userId); err != nil {
+	if ratingOwnBook, err := bs.CheckIfAuthorIsRatingOwnBook(ctx, bookId, userId); err != nil {
 		return err
 	} else if ratingOwnBook {
 		return ErrRatingOwnBook
 	}
 
 	if err == repository.ErrReviewEmpty {
-		err = bs.booksRepository.EditReview(bookId, userId, review.Rating, review.Review)
+		err = bs.booksRepository.EditReview(ctx, bookId, userId, review.Rating, review.Review)
 		if err != nil {
 			return err
 		}
 	} else {
-		err = bs.booksRepository.AddReview(bookId, userId, review.Review, review.Rating)
+		err = bs.booksRepository.AddReview(ctx, bookId, userId, review.Review, review.Rating)
 		if err != nil {
 			return err
 		}
 	}
+	metrics.ReviewsWritten.Inc()
 	return nil
 }
 
-func (bs *BooksServiceImpl) CheckIfUserExists(userId uuid.UUID) bool {
-	return bs.booksRepository.CheckIfUserExists(userId)
+func (bs *BooksServiceImpl) CheckIfUserExists(ctx context.Context, userId uuid.UUID) bool {
+	return bs.booksRepository.CheckIfUserExists(ctx, userId)
 }
 
 func ValidateSort(sort string) error {
@@ -321,16 +368,32 @@ func ValidateSort(sort string) error {
 	return ErrInvalidSort
 }
 
-func (bs *BooksServiceImpl) GetGenres() ([]string, error) {
-	genres, err := bs.booksRepository.GetGenres()
+// Same as ValidateSort but also accepts sorting by relevance, which only exists when searching books.
+func ValidateSearchSort(sort string) error {
+	if sort == SortRelevance {
+		return nil
+	}
+	if err := ValidateSort(sort); err != nil {
+		return ErrInvalidSearchSort
+	}
+	return nil
+}
+
+func (bs *BooksServiceImpl) GetGenres(ctx context.Context) ([]string, error) {
+	genres, err := bs.booksRepository.GetGenres(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return genres, nil
 }
 
-func (bs *BooksServiceImpl) DeleteReview(bookId uuid.UUID, userId uuid.UUID) error {
-	exists, err := bs.booksRepository.CheckifReviewExists(bookId, userId)
+// DeleteReview deletes the review of the reviewer, only the reviewer or a moderator can do it.
+func (bs *BooksServiceImpl) DeleteReview(ctx context.Context, bookId uuid.UUID, reviewerId uuid.UUID, userId uuid.UUID, role auth.Role) error {
+	if reviewerId != userId && !role.CanModerate() {
+		return ErrUserNotReviewer
+	}
+
+	exists, err := bs.booksRepository.CheckifReviewExists(ctx, bookId, reviewerId)
 	if err != nil {
 		return err
 	}
@@ -339,15 +402,15 @@ func (bs *BooksServiceImpl) DeleteReview(bookId uuid.UUID, userId uuid.UUID) err
 		return ErrReviewNotFound
 	}
 
-	err = bs.booksRepository.DeleteReview(bookId, userId)
+	err = bs.booksRepository.DeleteReview(ctx, bookId, reviewerId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (bs *BooksServiceImpl) DeleteRating(bookId uuid.UUID, userId uuid.UUID) error {
-	exists, err := bs.booksRepository.CheckIfRatingExists(bookId, userId)
+func (bs *BooksServiceImpl) DeleteRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) error {
+	exists, err := bs.booksRepository.CheckIfRatingExists(ctx, bookId, userId)
 	if err != nil {
 		return err
 	}
@@ -356,14 +419,16 @@ func (bs *BooksServiceImpl) DeleteRating(bookId uuid.UUID, userId uuid.UUID) err
 		return ErrRatingNotFound
 	}
 
-	err = bs.booksRepository.DeleteReview(bookId, userId)
+	err = bs.booksRepository.DeleteReview(ctx, bookId, userId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (bs *BooksServiceImpl) EditReview(bookId uuid.UUID, userId uuid.UUID, editReview models.NewReviewRequest) error {
+// EditReview changes the review of the user. Moderators can delete reviews but not edit them, the
+// words and the rating are the reviewer's.
+func (bs *BooksServiceImpl) EditReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, editReview models.NewReviewRequest) error {
 	review := editReview.Review
 	rating := editReview.Rating
 
@@ -371,12 +436,15 @@ func (bs *BooksServiceImpl) EditReview(bookId uuid.UUID, userId uuid.UUID, editR
 		return ErrRatingAmount
 	}
 
-	exists, err := bs.booksRepository.CheckifReviewExists(bookId, userId)
+	exists, err := bs.booksRepository.CheckifReviewExists(ctx, bookId, userId)
+	if err != nil {
+		return err
+	}
 	if !exists {
 		return ErrReviewNotFound
 	}
 
-	err = bs.booksRepository.EditReview(bookId, userId, rating, review)
+	err = bs.booksRepository.EditReview(ctx, bookId, userId, rating, review)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/betterreads/internal/domains/books/models"
+	"github.com/betterreads/internal/domains/books/repository"
+	"github.com/betterreads/internal/domains/books/utils"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/google/uuid"
+)
+
+// UpdateBook changes the book, only its author or a moderator can do it.
+func (bs *BooksServiceImpl) UpdateBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, role auth.Role, req *models.UpdateBookRequest) (*models.BookResponse, error) {
+	if err := bs.checkBookAuthor(ctx, bookId, userId, role); err != nil {
+		return nil, err
+	}
+
+	if err := bs.validateUpdateBookRequest(ctx, bookId, req); err != nil {
+		return nil, err
+	}
+
+	var picture *images.Picture
+	if req.Picture != nil {
+		processed, err := images.Process(req.Picture)
+		if err != nil {
+			return nil, err
+		}
+		picture = processed
+	}
+
+	if err := bs.booksRepository.UpdateBook(ctx, bookId, req, picture); err != nil {
+		if errors.Is(err, repository.ErrGenreNotFound) {
+			return nil, ErrGenreNotFound
+		} else if errors.Is(err, repository.ErrBookNotFound) {
+			return nil, ErrBookNotFound
+		}
+		return nil, err
+	}
+
+	book, err := bs.booksRepository.GetBookById(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MapBookToBookResponse(book), nil
+}
+
+// DeleteBook hides the book from the catalogue, only its author or a moderator can do it. The book
+// stays in the shelves of the readers as deleted and its reviews are kept.
+func (bs *BooksServiceImpl) DeleteBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, role auth.Role) error {
+	if err := bs.checkBookAuthor(ctx, bookId, userId, role); err != nil {
+		return err
+	}
+
+	if err := bs.booksRepository.DeleteBook(ctx, bookId); err != nil {
+		if errors.Is(err, repository.ErrBookNotFound) {
+			return ErrBookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (bs *BooksServiceImpl) CheckIfBookExists(ctx context.Context, bookId uuid.UUID) bool {
+	return bs.booksRepository.CheckIfBookExists(ctx, bookId)
+}
+
+// checkBookAuthor fails if the book can't be changed by the user, moderators can change any book.
+func (bs *BooksServiceImpl) checkBookAuthor(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, role auth.Role) error {
+	author, err := bs.booksRepository.GetBookAuthor(ctx, bookId)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookNotFound) {
+			return ErrBookNotFound
+		} else if errors.Is(err, repository.ErrBookDeleted) {
+			return ErrBookDeleted
+		}
+		return err
+	}
+
+	if author != userId && !role.CanModerate() {
+		return ErrUserNotAuthor
+	}
+	return nil
+}
+
+// Normalizes the isbn of the request
+func (bs *BooksServiceImpl) validateUpdateBookRequest(ctx context.Context, bookId uuid.UUID, req *models.UpdateBookRequest) error {
+	for _, field := range []*string{req.Title, req.Description, req.PublicationDate, req.Language} {
+		if field != nil && strings.TrimSpace(*field) == "" {
+			return ErrEmptyBookField
+		}
+	}
+
+	if req.AmountOfPages != nil && *req.AmountOfPages < 1 {
+		return ErrInvalidAmountOfPages
+	}
+
+	if req.Genres != nil && len(req.Genres) == 0 {
+		return ErrGenreRequired
+	}
+
+	if req.ISBN != nil && *req.ISBN != "" {
+		isbn, ok := utils.NormalizeISBN(*req.ISBN)
+		if !ok {
+			return ErrInvalidISBN
+		}
+		if bs.booksRepository.CheckIfISBNUsedByOtherBook(ctx, isbn, bookId) {
+			return ErrISBNAlreadyExists
+		}
+		req.ISBN = &isbn
+	}
+	return nil
+}
+
+// bookNotFoundError tells apart the books that never existed from the deleted ones.
+func (bs *BooksServiceImpl) bookNotFoundError(ctx context.Context, bookId uuid.UUID) error {
+	if bs.booksRepository.CheckIfBookDeleted(ctx, bookId) {
+		return ErrBookDeleted
+	}
+	return ErrBookNotFound
+}
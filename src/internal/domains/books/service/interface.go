@@ -1,10 +1,16 @@
 package service
 
 import (
+	"context"
+
 	"errors"
 
 	"github.com/betterreads/internal/domains/books/models"
+	"github.com/betterreads/internal/pkg/auth"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -17,20 +23,42 @@ var (
 	ErrReviewNotFound      = errors.New("review not found")
 	ErrAuthorNotFound      = errors.New("author not found")
 	ErrUserNotAuthor       = errors.New("user is not the author")
+	ErrUserNotReviewer     = errors.New("user is not the reviewer")
 	ErrUserNotFound        = errors.New("user not found")
 	ErrRatingOwnBook       = errors.New("author can't rate his own book")
 	ErrDirectionWhenNoSort = errors.New("direction must be empty when sort is empty")
+	ErrBookDeleted         = errors.New("book was deleted by its author")
 
 	ErrGenreRequired = er.ErrorParam{
 		Name:   "genre",
 		Reason: "genre is required",
 	}
 
+	ErrInvalidISBN = er.ErrorParam{
+		Name:   "isbn",
+		Reason: "isbn must be a valid ISBN-10 or ISBN-13",
+	}
+
+	ErrISBNAlreadyExists = er.ErrorParam{
+		Name:   "isbn",
+		Reason: "there is already a book with this isbn",
+	}
+
 	ErrGenreNotFound = er.ErrorParam{
 		Name:   "genre",
 		Reason: "genre not in available genres",
 	}
 
+	ErrEmptyBookField = er.ErrorParam{
+		Name:   "book",
+		Reason: "title, description, publication_date and language can't be empty",
+	}
+
+	ErrInvalidAmountOfPages = er.ErrorParam{
+		Name:   "amount_of_pages",
+		Reason: "amount_of_pages must be greater than 0",
+	}
+
 	ErrRatingAmount = er.ErrorParam{
 		Name:   "rating",
 		Reason: "rating must be between 1 and 5",
@@ -41,6 +69,11 @@ var (
 		Reason: "sort must be one of the following: publication_date, total_ratings, avg_ratings",
 	}
 
+	ErrInvalidSearchSort = er.ErrorParam{
+		Name:   "sort",
+		Reason: "sort must be one of the following: relevance, publication_date, total_ratings, avg_ratings",
+	}
+
 	ErrInvalidDirection = er.ErrorParam{
 		Name:   "direction",
 		Reason: "direction must be either 'asc' or 'desc'",
@@ -50,22 +83,28 @@ var (
 	AvailableSorts = []string{"publication_date", "total_ratings", "avg_ratings"}
 )
 
+// Only available when searching books, sorts by how well the book matches the search
+const SortRelevance = "relevance"
+
 type BooksService interface {
-	PublishBook(req *models.NewBookRequest, author uuid.UUID) (*models.BookResponse, error)
-	GetBookInfo(bookId uuid.UUID, userId uuid.UUID) (*models.BookResponseWithReview, error)
-	GetBooksOfAuthor(authorId uuid.UUID, userId uuid.UUID) ([]*models.BookResponseWithReview, error)
-	SearchBooks(name string, genre string, userId uuid.UUID, sort string, isAscDirection string) ([]*models.BookResponseWithReview, error)
-	GetBookPicture(id uuid.UUID) ([]byte, error)
-	GetBooksInfo(userId uuid.UUID) ([]*models.BookResponseWithReview, error)
-	RateBook(bookId uuid.UUID, userId uuid.UUID, rateAmount int) (*models.Rating, error)
-	UpdateRating(bookId uuid.UUID, userId uuid.UUID, rateAmount int) error
-	GetBookReviews(bookId uuid.UUID) ([]*models.ReviewOfBook, error)
-	GetAllReviewsOfUser(userId uuid.UUID) ([]*models.ReviewOfUser, error)
-	AddReview(bookId uuid.UUID, userId uuid.UUID, review models.NewReviewRequest) error
-	CheckIfUserExists(userId uuid.UUID) bool
-	CheckIfAuthorIsRatingOwnBook(bookId uuid.UUID, userId uuid.UUID) (bool, error)
-	GetGenres() ([]string, error)
-	DeleteReview(bookId uuid.UUID, userId uuid.UUID) error
-	DeleteRating(bookId uuid.UUID, userId uuid.UUID) error
-	EditReview(bookId uuid.UUID, userId uuid.UUID, review models.NewReviewRequest) error
+	PublishBook(ctx context.Context, req *models.NewBookRequest, author uuid.UUID) (*models.BookResponse, error)
+	GetBookInfo(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (*models.BookResponseWithReview, error)
+	GetBooksOfAuthor(ctx context.Context, authorId uuid.UUID, userId uuid.UUID) ([]*models.BookResponseWithReview, error)
+	SearchBooks(ctx context.Context, name string, genre string, userId uuid.UUID, sort string, isAscDirection string) ([]*models.BookResponseWithReview, error)
+	GetBookPicture(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error)
+	GetBooksInfo(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[*models.BookResponseWithReview], error)
+	RateBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rateAmount int) (*models.Rating, error)
+	UpdateRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, rateAmount int) error
+	GetBookReviews(ctx context.Context, bookId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfBook], error)
+	GetAllReviewsOfUser(ctx context.Context, userId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*models.ReviewOfUser], error)
+	AddReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, review models.NewReviewRequest) error
+	CheckIfUserExists(ctx context.Context, userId uuid.UUID) bool
+	CheckIfAuthorIsRatingOwnBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) (bool, error)
+	GetGenres(ctx context.Context) ([]string, error)
+	DeleteReview(ctx context.Context, bookId uuid.UUID, reviewerId uuid.UUID, userId uuid.UUID, role auth.Role) error
+	DeleteRating(ctx context.Context, bookId uuid.UUID, userId uuid.UUID) error
+	EditReview(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, review models.NewReviewRequest) error
+	UpdateBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, role auth.Role, req *models.UpdateBookRequest) (*models.BookResponse, error)
+	DeleteBook(ctx context.Context, bookId uuid.UUID, userId uuid.UUID, role auth.Role) error
+	CheckIfBookExists(ctx context.Context, bookId uuid.UUID) bool
 }
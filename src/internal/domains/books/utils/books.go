@@ -32,6 +32,7 @@ func MapBookToBookResponse(book *models.Book) *models.BookResponse {
 		Id:              book.Id,
 		TotalRatings:    book.TotalRatings,
 		AverageRating:   book.AverageRating,
+		UpdatedAt:       book.UpdatedAt,
 	}
 }
 
@@ -48,6 +49,7 @@ func MapBookDbToBook(book *models.BookDb, genres []string, ratings *models.Ratin
 		Id:              book.Id,
 		TotalRatings:    ratings.Total_ratings,
 		AverageRating:   ratings.Avg_ratings,
+		UpdatedAt:       book.UpdatedAt,
 	}
 }
 
@@ -64,5 +66,17 @@ func MapBookRecordToBook(book *models.BookRecord, genres []string) *models.Book
 		Id:              book.Id,
 		TotalRatings:    book.TotalRatings,
 		AverageRating:   book.AverageRating,
+		UpdatedAt:       book.UpdatedAt,
+	}
+}
+
+func MapBookSearchRecordToBookSearchResult(record *models.BookSearchRecord, genres []string) *models.BookSearchResult {
+	return &models.BookSearchResult{
+		Book: MapBookRecordToBook(&record.BookRecord, genres),
+		Match: &models.BookSearchMatch{
+			Relevance:            record.Relevance,
+			TitleHighlight:       record.TitleHighlight,
+			DescriptionHighlight: record.DescriptionHighlight,
+		},
 	}
 }
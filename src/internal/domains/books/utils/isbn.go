@@ -0,0 +1,80 @@
+package utils
+
+import "strings"
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13, ignoring hyphens and spaces, and
+// returns it as an ISBN-13. The second value is false when the ISBN is invalid.
+func NormalizeISBN(isbn string) (string, bool) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(isbn)))
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", false
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), true
+	case 13:
+		for _, r := range digits {
+			if r < '0' || r > '9' {
+				return "", false
+			}
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", false
+		}
+		return digits, true
+	}
+	return "", false
+}
+
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i, r := range isbn {
+		var value int
+		switch {
+		case r >= '0' && r <= '9':
+			value = int(r - '0')
+		case r == 'X' && i == 9:
+			value = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * value
+	}
+	return sum%11 == 0
+}
+
+func isbn13CheckDigit(first12 string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		value := int(first12[i] - '0')
+		if i%2 == 1 {
+			value *= 3
+		}
+		sum += value
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ISBN13To10 returns the ISBN-10 of an ISBN-13, only the ones starting with 978 have one.
+func ISBN13To10(isbn string) (string, bool) {
+	if len(isbn) != 13 || !strings.HasPrefix(isbn, "978") {
+		return "", false
+	}
+	first9 := isbn[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(first9[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return first9 + "X", true
+	}
+	return first9 + string(rune('0'+check)), true
+}
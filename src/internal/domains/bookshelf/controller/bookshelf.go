@@ -10,10 +10,17 @@ import (
 	"github.com/betterreads/internal/domains/bookshelf/service"
 	aux "github.com/betterreads/internal/pkg/controller"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// Goodreads exports are a few MB even for large libraries
+const MaxImportFileSize = 10 << 20
+
+// Biggest body of an import request, the file plus room for the rest of the form
+const MaxImportRequestSize = MaxImportFileSize + 1<<20
+
 type BookshelfController struct {
 	service service.BookshelfService
 }
@@ -30,7 +37,10 @@ func NewBookshelfController(service service.BookshelfService) BookshelfControlle
 // @Tags bookshelf
 // @Param userId path string true "User ID"
 // @Param status query string true "Shelf Type"
-// @Success 200 {object} []models.BookInShelfResponse
+// @Param shelf query string false "Custom shelf name"
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[models.BookInShelfResponse]
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetails
 // @Failure 500 {object} errors.ErrorDetails
@@ -50,11 +60,20 @@ func (bc *BookshelfController) GetBookShelf(c *gin.Context) {
 		return
 	}
 
-	shelf, err := bc.service.GetBookShelf(userId, shelfType)
+	page, errDetails := aux.GetPageRequest(c)
+	if errDetails != nil {
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	shelf, err := bc.service.GetBookShelf(c.Request.Context(), userId, shelfType, c.Query("shelf"), page)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting shelf", err, http.StatusNotFound)
 			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrShelfNotFound) {
+			errDetails := er.NewErrorDetails("Error when getting shelf", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
 		} else if errors.Is(err, service.ErrInvalidStatusType) {
 			errDetails := er.NewErrorDetails("Error when getting shelf", err, http.StatusBadRequest)
 			c.AbortWithError(errDetails.Status, errDetails)
@@ -72,6 +91,7 @@ func (bc *BookshelfController) GetBookShelf(c *gin.Context) {
 // @Summary Search books in shelf of an user
 // @Description Search books in shelf of an user. The search can be filtered by genre, sorted by avg_ratings, total_ratings and date. The direction can be asc or desc.
 // @Param status query string true "Shelf Type: all, read, plan-to-read, reading "
+// @Param shelf query string false "Custom shelf name"
 // @Param genre query string false "Book Genre"
 // @Param sort query string false "Sort by publication_date, total_ratings, avg_rating"
 // @Param direction query string false "Sort direction asc or desc"
@@ -102,9 +122,12 @@ func (bc *BookshelfController) SearchBookShelf(c *gin.Context) {
 	genre := c.Query("genre")
 	sort := c.Query("sort")
 	direction := c.Query("direction")
-	books, err := bc.service.SearchBookShelf(userId, shelfType, genre, sort, direction)
+	books, err := bc.service.SearchBookShelf(c.Request.Context(), userId, shelfType, c.Query("shelf"), genre, sort, direction)
 	if err != nil {
-		if errors.Is(err, bookService.ErrInvalidSort) {
+		if errors.Is(err, service.ErrShelfNotFound) {
+			errDetails := er.NewErrorDetails("Error when searching books in shelf", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, bookService.ErrInvalidSort) {
 			errDetails := er.NewErrorDetailsWithParams(
 				"Error when searching books", http.StatusBadRequest, err)
 			c.AbortWithError(errDetails.Status, errDetails)
@@ -132,7 +155,7 @@ func (bc *BookshelfController) SearchBookShelf(c *gin.Context) {
 
 // AddBookToShelf godoc
 // @Summary Add book to shelf
-// @Description Add book to shelf
+// @Description Add book to shelf, reading starts a read and read or did-not-finish adds a finished one
 // @ID add-book
 // @Accept  json
 // @Produce  json
@@ -157,9 +180,9 @@ func (bc *BookshelfController) AddBookToShelf(c *gin.Context) {
 		return
 	}
 
-	err := bc.service.AddBookToShelf(userId, &req)
+	err := bc.service.AddBookToShelf(c.Request.Context(), userId, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrBookNotFound) {
 			errDetails := er.NewErrorDetails("Error when adding book to shelf", err, http.StatusNotFound)
 			c.AbortWithError(errDetails.Status, errDetails)
 		} else if errors.Is(err, service.ErrBookAlreadyInLibrary) {
@@ -168,6 +191,9 @@ func (bc *BookshelfController) AddBookToShelf(c *gin.Context) {
 		} else if errors.Is(err, service.ErrInvalidStatusType) {
 			errDetails := er.NewErrorDetails("Error when adding book to shelf", err, http.StatusBadRequest)
 			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrInvalidReadDates) || errors.Is(err, service.ErrInvalidAbandonedPage) {
+			errDetails := er.NewErrorDetailsWithParams("Error when adding book to shelf", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
 		} else {
 			errDetails := er.NewErrorDetails("Error when adding book to shelf", err, http.StatusInternalServerError)
 			c.AbortWithError(errDetails.Status, errDetails)
@@ -179,7 +205,7 @@ func (bc *BookshelfController) AddBookToShelf(c *gin.Context) {
 
 // EditBookIn godoc
 // @Summary Edit book in shelf
-// @Description Edit book in shelf
+// @Description Edit book in shelf, changing the status starts or finishes the read of the book and moving a read book to reading starts a re-read
 // @ID edit-book
 // @Accept  json
 // @Produce  json
@@ -201,7 +227,7 @@ func (bc *BookshelfController) EditBookInShelf(c *gin.Context) {
 		er.AbortWithJsonErorr(c, err)
 		return
 	}
-	err := bc.service.EditBookInShelf(userId, &req)
+	err := bc.service.EditBookInShelf(c.Request.Context(), userId, &req)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when editing book in shelf", err, http.StatusNotFound)
@@ -212,6 +238,9 @@ func (bc *BookshelfController) EditBookInShelf(c *gin.Context) {
 		} else if errors.Is(err, service.ErrInvalidStatusType) {
 			errDetails := er.NewErrorDetails("Error when editing book in shelf", err, http.StatusBadRequest)
 			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrInvalidReadDates) || errors.Is(err, service.ErrInvalidAbandonedPage) {
+			errDetails := er.NewErrorDetailsWithParams("Error when editing book in shelf", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
 		} else {
 			errDetails := er.NewErrorDetails("Error when editing book in shelf", err, http.StatusInternalServerError)
 			c.AbortWithError(errDetails.Status, errDetails)
@@ -248,7 +277,7 @@ func (bc *BookshelfController) DeleteBookFromShelf(c *gin.Context) {
 		return
 	}
 
-	err = bc.service.DeleteBookFromShelf(userId, bookId)
+	err = bc.service.DeleteBookFromShelf(c.Request.Context(), userId, bookId)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when editing book in shelf", err, http.StatusNotFound)
@@ -264,3 +293,128 @@ func (bc *BookshelfController) DeleteBookFromShelf(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Book deleted from shelf"})
 }
+
+// ImportGoodreads godoc
+// @Summary Import a Goodreads library
+// @Description Imports the library export CSV of Goodreads into the shelf of the logged user. Books are matched by ISBN and then by title and author, ratings, reviews and custom shelves are imported too. Each row of the report is created (added to the shelf), matched (already in the shelf), skipped (book not found or shelf not supported) or failed.
+// @Tags bookshelf
+// @Accept  mpfd
+// @Produce  json
+// @Param file formData file true "Goodreads library export"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 413 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/import [post]
+func (bc *BookshelfController) ImportGoodreads(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	if err := aux.ParseMultipartForm(c, MaxImportRequestSize); errors.Is(err, aux.ErrBodyTooLarge) {
+		errDetails := er.NewErrorDetails("Error when importing shelf", fmt.Errorf("file can't be bigger than %d MB", MaxImportFileSize>>20), http.StatusRequestEntityTooLarge)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	} else if err != nil {
+		errDetails := er.NewErrorDetails("Error when importing shelf", fmt.Errorf("Error when parsing file: %w", err), http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		errDetails := er.NewErrorDetails("Error when importing shelf", fmt.Errorf("Error when parsing file: %w", err), http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > MaxImportFileSize {
+		errDetails := er.NewErrorDetails("Error when importing shelf", fmt.Errorf("file can't be bigger than %d MB", MaxImportFileSize>>20), http.StatusRequestEntityTooLarge)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	report, err := bc.service.ImportGoodreads(c.Request.Context(), userId, file)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when importing shelf", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrInvalidGoodreadsCSV) {
+			errDetails := er.NewErrorDetails("Error when importing shelf", err, http.StatusBadRequest)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when importing shelf", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportLibrary godoc
+// @Summary Export the library of the logged user
+// @Description Exports every book in the shelf or reviewed by the logged user with its status, date added, rating, review and book data. The csv format has the columns of the Goodreads library export.
+// @Tags bookshelf
+// @Produce  json
+// @Produce  text/csv
+// @Param format query string false "Export format: csv or json, defaults to csv"
+// @Success 200 {file} file
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/export [get]
+func (bc *BookshelfController) ExportLibrary(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	format := c.DefaultQuery("format", models.ExportFormatCSV)
+	w := &exportWriter{c: c, format: format}
+	err := bc.service.ExportLibrary(c.Request.Context(), userId, format, w)
+	if err != nil {
+		if w.started {
+			// The status was already sent, the export is left truncated
+			logger.FromContext(c.Request.Context()).Error("failed to export library", "user_id", userId, "error", err)
+			return
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when exporting library", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrInvalidExportFormat) {
+			errDetails := er.NewErrorDetailsWithParams("Error when exporting library", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when exporting library", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+}
+
+// exportWriter sends the headers of the export with its first write, so errors before
+// that can still be answered as json.
+type exportWriter struct {
+	c       *gin.Context
+	format  string
+	started bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		contentType := "text/csv; charset=utf-8"
+		if w.format == models.ExportFormatJSON {
+			contentType = "application/json; charset=utf-8"
+		}
+		w.c.Header("Content-Type", contentType)
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="betterreads_library_export.%s"`, w.format))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGoals godoc
+// @Summary Get reading goals of an user
+// @Description Get the reading goals of every year of an user with their progress, the last year first
+// @Tags bookshelf
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} []models.ReadingGoal
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/goals [get]
+func (bc *BookshelfController) GetGoals(c *gin.Context) {
+	userId, ok := parseIdParam(c, "id", "Error when getting goals")
+	if !ok {
+		return
+	}
+
+	goals, err := bc.service.GetGoals(c.Request.Context(), userId)
+	if err != nil {
+		abortWithGoalError(c, "Error when getting goals", err)
+		return
+	}
+	c.JSON(http.StatusOK, goals)
+}
+
+// GetGoal godoc
+// @Summary Get the reading goal of a year
+// @Description Get the goal of an user for a year with the books and pages read, and if the user is ahead or behind schedule
+// @Tags bookshelf
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param year path int true "Year"
+// @Success 200 {object} models.ReadingGoal
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/goals/{year} [get]
+func (bc *BookshelfController) GetGoal(c *gin.Context) {
+	userId, ok := parseIdParam(c, "id", "Error when getting goal")
+	if !ok {
+		return
+	}
+
+	goal, err := bc.service.GetGoal(c.Request.Context(), userId, c.Param("year"))
+	if err != nil {
+		abortWithGoalError(c, "Error when getting goal", err)
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+// GetGoalBooks godoc
+// @Summary Get the books of a reading goal
+// @Description Get the books an user finished in the year of a goal, the last finished first
+// @Tags bookshelf
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param year path int true "Year"
+// @Success 200 {object} []models.GoalBook
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/goals/{year}/books [get]
+func (bc *BookshelfController) GetGoalBooks(c *gin.Context) {
+	userId, ok := parseIdParam(c, "id", "Error when getting books of goal")
+	if !ok {
+		return
+	}
+
+	books, err := bc.service.GetGoalBooks(c.Request.Context(), userId, c.Param("year"))
+	if err != nil {
+		abortWithGoalError(c, "Error when getting books of goal", err)
+		return
+	}
+	c.JSON(http.StatusOK, books)
+}
+
+// SetGoal godoc
+// @Summary Set the reading goal of a year
+// @Description Creates or replaces the goal of books or pages of the logged user for a year
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param year path int true "Year"
+// @Param goal body models.GoalRequest true "Goal"
+// @Success 200 {object} models.ReadingGoal
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/goals/{year} [put]
+func (bc *BookshelfController) SetGoal(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	var req models.GoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	goal, err := bc.service.SetGoal(c.Request.Context(), userId, c.Param("year"), &req)
+	if err != nil {
+		abortWithGoalError(c, "Error when setting goal", err)
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+// DeleteGoal godoc
+// @Summary Delete the reading goal of a year
+// @Description Deletes the goal of the logged user for a year, the books read are kept
+// @Tags bookshelf
+// @Produce  json
+// @Param year path int true "Year"
+// @Success 200 {object} string
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/goals/{year} [delete]
+func (bc *BookshelfController) DeleteGoal(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	if err := bc.service.DeleteGoal(c.Request.Context(), userId, c.Param("year")); err != nil {
+		abortWithGoalError(c, "Error when deleting goal", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Goal deleted"})
+}
+
+func abortWithGoalError(c *gin.Context, title string, err error) {
+	if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrGoalNotFound) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusNotFound)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrInvalidYear) || errors.Is(err, service.ErrInvalidGoal) {
+		errDetails := er.NewErrorDetailsWithParams(title, http.StatusBadRequest, err)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else {
+		errDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+	}
+}
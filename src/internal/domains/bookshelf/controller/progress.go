@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// AddProgress godoc
+// @Summary Post a reading progress update
+// @Description Saves the current page or percent of a book in the reading shelf of the logged user, reaching 100% moves the book to read
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Param progress body models.ProgressRequest true "Page or percent, and an optional note"
+// @Success 201 {object} models.ReadingProgress
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/progress [post]
+func (bc *BookshelfController) AddProgress(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when adding progress")
+	if !ok {
+		return
+	}
+
+	var req models.ProgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	progress, err := bc.service.AddProgress(c.Request.Context(), userId, bookId, &req)
+	if err != nil {
+		abortWithProgressError(c, "Error when adding progress", err)
+		return
+	}
+	c.JSON(http.StatusCreated, progress)
+}
+
+// GetProgressHistory godoc
+// @Summary Get the reading progress history of a book
+// @Description Get every progress update of a book in the bookshelf of the logged user, the newest first
+// @Tags bookshelf
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Success 200 {object} []models.ReadingProgress
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/progress [get]
+func (bc *BookshelfController) GetProgressHistory(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when getting progress")
+	if !ok {
+		return
+	}
+
+	history, err := bc.service.GetProgressHistory(c.Request.Context(), userId, bookId)
+	if err != nil {
+		abortWithProgressError(c, "Error when getting progress", err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+func abortWithProgressError(c *gin.Context, title string, err error) {
+	if errors.Is(err, service.ErrBookNotFoundInLibrary) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusNotFound)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrBookNotReading) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusConflict)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrInvalidProgress) {
+		errDetails := er.NewErrorDetailsWithParams(title, http.StatusBadRequest, err)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else {
+		errDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+	}
+}
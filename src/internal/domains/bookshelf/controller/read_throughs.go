@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// GetReadThroughs godoc
+// @Summary Get the reads of a book
+// @Description Get every read of a book in the bookshelf of the logged user, the one in progress first and then the last finished
+// @Tags bookshelf
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Success 200 {object} []models.ReadThrough
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/reads [get]
+func (bc *BookshelfController) GetReadThroughs(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when getting reads")
+	if !ok {
+		return
+	}
+
+	reads, err := bc.service.GetReadThroughs(c.Request.Context(), userId, bookId)
+	if err != nil {
+		abortWithReadError(c, "Error when getting reads", err)
+		return
+	}
+	c.JSON(http.StatusOK, reads)
+}
+
+// AddReadThrough godoc
+// @Summary Log a finished read of a book
+// @Description Adds a past read or re-read of a book in the bookshelf of the logged user, the status of the book is not changed
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Param read body models.ReadThroughRequest true "Finished read"
+// @Success 201 {object} models.ReadThrough
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/reads [post]
+func (bc *BookshelfController) AddReadThrough(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when adding read")
+	if !ok {
+		return
+	}
+
+	var req models.ReadThroughRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	read, err := bc.service.AddReadThrough(c.Request.Context(), userId, bookId, &req)
+	if err != nil {
+		abortWithReadError(c, "Error when adding read", err)
+		return
+	}
+	c.JSON(http.StatusCreated, read)
+}
+
+// UpdateReadThrough godoc
+// @Summary Edit a finished read of a book
+// @Description Changes the status, dates and abandoned page of a finished read, the read in progress changes with the status of the book
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Param readId path string true "Read ID"
+// @Param read body models.ReadThroughRequest true "Finished read"
+// @Success 200 {object} models.ReadThrough
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/reads/{readId} [put]
+func (bc *BookshelfController) UpdateReadThrough(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when editing read")
+	if !ok {
+		return
+	}
+	readId, ok := parseIdParam(c, "readId", "Error when editing read")
+	if !ok {
+		return
+	}
+
+	var req models.ReadThroughRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	read, err := bc.service.UpdateReadThrough(c.Request.Context(), userId, bookId, readId, &req)
+	if err != nil {
+		abortWithReadError(c, "Error when editing read", err)
+		return
+	}
+	c.JSON(http.StatusOK, read)
+}
+
+// DeleteReadThrough godoc
+// @Summary Delete a read of a book
+// @Description Deletes a read and its progress updates, the status of the book is not changed
+// @Tags bookshelf
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Param readId path string true "Read ID"
+// @Success 200 {object} string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/reads/{readId} [delete]
+func (bc *BookshelfController) DeleteReadThrough(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when deleting read")
+	if !ok {
+		return
+	}
+	readId, ok := parseIdParam(c, "readId", "Error when deleting read")
+	if !ok {
+		return
+	}
+
+	if err := bc.service.DeleteReadThrough(c.Request.Context(), userId, bookId, readId); err != nil {
+		abortWithReadError(c, "Error when deleting read", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Read deleted"})
+}
+
+func abortWithReadError(c *gin.Context, title string, err error) {
+	if errors.Is(err, service.ErrBookNotFoundInLibrary) || errors.Is(err, service.ErrReadThroughNotFound) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusNotFound)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrReadThroughInProgress) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusConflict)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrInvalidReadStatus) || errors.Is(err, service.ErrInvalidReadDates) || errors.Is(err, service.ErrInvalidAbandonedPage) {
+		errDetails := er.NewErrorDetailsWithParams(title, http.StatusBadRequest, err)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else {
+		errDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+	}
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetShelves godoc
+// @Summary Get custom shelves of an user
+// @Description Get the shelves created by an user with the amount of books in each one
+// @Tags bookshelf
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} []models.Shelf
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/shelves [get]
+func (bc *BookshelfController) GetShelves(c *gin.Context) {
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errDetails := er.NewErrorDetails("Error when getting shelves", fmt.Errorf("invalid user id"), http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	shelves, err := bc.service.GetShelves(c.Request.Context(), userId)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when getting shelves", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when getting shelves", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, shelves)
+}
+
+// CreateShelf godoc
+// @Summary Create a custom shelf
+// @Description Create a shelf for the logged user, names are unique per user ignoring case and can't be a status
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param shelf body models.ShelfRequest true "Shelf"
+// @Success 201 {object} models.Shelf
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/custom [post]
+func (bc *BookshelfController) CreateShelf(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	var req models.ShelfRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	shelf, err := bc.service.CreateShelf(c.Request.Context(), userId, &req)
+	if err != nil {
+		abortWithShelfError(c, "Error when creating shelf", err)
+		return
+	}
+	c.JSON(http.StatusCreated, shelf)
+}
+
+// RenameShelf godoc
+// @Summary Rename a custom shelf
+// @Description Rename a shelf of the logged user
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param shelfId path string true "Shelf ID"
+// @Param shelf body models.ShelfRequest true "Shelf"
+// @Success 200 {object} models.Shelf
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/custom/{shelfId} [put]
+func (bc *BookshelfController) RenameShelf(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	shelfId, ok := parseIdParam(c, "shelfId", "Error when renaming shelf")
+	if !ok {
+		return
+	}
+
+	var req models.ShelfRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	shelf, err := bc.service.RenameShelf(c.Request.Context(), userId, shelfId, &req)
+	if err != nil {
+		abortWithShelfError(c, "Error when renaming shelf", err)
+		return
+	}
+	c.JSON(http.StatusOK, shelf)
+}
+
+// DeleteShelf godoc
+// @Summary Delete a custom shelf
+// @Description Delete a shelf of the logged user, its books stay in the bookshelf
+// @Tags bookshelf
+// @Produce  json
+// @Param shelfId path string true "Shelf ID"
+// @Success 200 {object} string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/custom/{shelfId} [delete]
+func (bc *BookshelfController) DeleteShelf(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	shelfId, ok := parseIdParam(c, "shelfId", "Error when deleting shelf")
+	if !ok {
+		return
+	}
+
+	if err := bc.service.DeleteShelf(c.Request.Context(), userId, shelfId); err != nil {
+		abortWithShelfError(c, "Error when deleting shelf", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Shelf deleted"})
+}
+
+// AddBookToCustomShelf godoc
+// @Summary Add a book to a custom shelf
+// @Description Add a book of the bookshelf of the logged user to one of its shelves
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param shelfId path string true "Shelf ID"
+// @Param book body models.ShelfBookRequest true "Book"
+// @Success 201 {object} string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/custom/{shelfId}/books [post]
+func (bc *BookshelfController) AddBookToCustomShelf(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	shelfId, ok := parseIdParam(c, "shelfId", "Error when adding book to shelf")
+	if !ok {
+		return
+	}
+
+	var req models.ShelfBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	if err := bc.service.AddBookToCustomShelf(c.Request.Context(), userId, shelfId, req.BookId); err != nil {
+		abortWithShelfError(c, "Error when adding book to shelf", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Book added to shelf"})
+}
+
+// RemoveBookFromCustomShelf godoc
+// @Summary Remove a book from a custom shelf
+// @Description Remove a book from a shelf of the logged user, it stays in the bookshelf
+// @Tags bookshelf
+// @Produce  json
+// @Param shelfId path string true "Shelf ID"
+// @Param bookId path string true "Book ID"
+// @Success 200 {object} string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/custom/{shelfId}/books/{bookId} [delete]
+func (bc *BookshelfController) RemoveBookFromCustomShelf(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	shelfId, ok := parseIdParam(c, "shelfId", "Error when removing book from shelf")
+	if !ok {
+		return
+	}
+	bookId, ok := parseIdParam(c, "bookId", "Error when removing book from shelf")
+	if !ok {
+		return
+	}
+
+	if err := bc.service.RemoveBookFromCustomShelf(c.Request.Context(), userId, shelfId, bookId); err != nil {
+		abortWithShelfError(c, "Error when removing book from shelf", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Book removed from shelf"})
+}
+
+// SetBookCustomShelves godoc
+// @Summary Set the custom shelves of a book
+// @Description Puts a book of the bookshelf of the logged user in the given shelves and removes it from the others
+// @Tags bookshelf
+// @Accept  json
+// @Produce  json
+// @Param bookId path string true "Book ID"
+// @Param shelves body models.BookShelvesRequest true "Shelves"
+// @Success 200 {object} string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/shelf/books/{bookId}/shelves [put]
+func (bc *BookshelfController) SetBookCustomShelves(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	bookId, ok := parseIdParam(c, "bookId", "Error when setting shelves of book")
+	if !ok {
+		return
+	}
+
+	var req models.BookShelvesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	if err := bc.service.SetBookCustomShelves(c.Request.Context(), userId, bookId, &req); err != nil {
+		abortWithShelfError(c, "Error when setting shelves of book", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Shelves of book updated"})
+}
+
+func parseIdParam(c *gin.Context, name string, title string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		errDetails := er.NewErrorDetails(title, fmt.Errorf("invalid %s", name), http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func abortWithShelfError(c *gin.Context, title string, err error) {
+	if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrShelfNotFound) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusNotFound)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrBookNotFoundInLibrary) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrShelfAlreadyExists) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusConflict)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else if errors.Is(err, service.ErrInvalidShelfName) {
+		errDetails := er.NewErrorDetailsWithParams(title, http.StatusBadRequest, err)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else {
+		errDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+	}
+}
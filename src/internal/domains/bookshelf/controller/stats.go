@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	_ "github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/service"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// GetStats godoc
+// @Summary Get reading stats of an user
+// @Description Get the books and pages read by year and month, ratings given, genres, longest and shortest books, most read authors and average days to finish a book
+// @Tags bookshelf
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param year query int false "Only the reads finished in this year"
+// @Success 200 {object} models.ReadingStats
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/stats [get]
+func (bc *BookshelfController) GetStats(c *gin.Context) {
+	userId, ok := parseIdParam(c, "id", "Error when getting stats")
+	if !ok {
+		return
+	}
+
+	stats, err := bc.service.GetStats(c.Request.Context(), userId, c.Query("year"))
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when getting stats", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrInvalidYear) {
+			errDetails := er.NewErrorDetailsWithParams("Error when getting stats", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when getting stats", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
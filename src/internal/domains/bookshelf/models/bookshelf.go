@@ -1,6 +1,9 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
 
 type BookInShelf struct {
 	Date   string    `json:"date" db:"date"`
@@ -34,27 +37,41 @@ type BookShelfRatingStats struct {
 }
 
 type BookInShelfResponse struct {
-	Title           string    `json:"title" db:"title"`
-	Author          string    `json:"author" db:"author_id"`
-	AuthorName      string    `json:"author_name" db:"author_name"`
-	Description     string    `json:"description" db:"description"`
-	PublicationDate string    `json:"publication_date" db:"publication_date"`
-	Date            string    `json:"date_added" db:"date"`
-	Language        string    `json:"language" db:"language"`
-	Genres          string    `json:"ignore,omitempty" db:"genres"`
-	GenresArray     *[]string `json:"genres" db:"genres_array"`
-	AmountOfPages   int       `json:"amount_of_pages" db:"amount_of_pages"`
-	TotalRatings    int       `json:"total_ratings" db:"total_ratings"`
-	AvgRating       float64   `json:"avg_ratings" db:"avg_ratings"`
-	Status          string    `json:"status" db:"status"`
-	UserReview      string    `json:"user_review" db:"user_review"`
-	UserRating      int       `json:"user_rating" db:"user_rating"`
-	Id              uuid.UUID `json:"book_id" db:"id"`
+	Title           string         `json:"title" db:"title"`
+	Author          string         `json:"author" db:"author_id"`
+	AuthorName      string         `json:"author_name" db:"author_name"`
+	Description     string         `json:"description" db:"description"`
+	PublicationDate string         `json:"publication_date" db:"publication_date"`
+	Date            string         `json:"date_added" db:"date"`
+	Language        string         `json:"language" db:"language"`
+	Genres          string         `json:"ignore,omitempty" db:"genres"`
+	GenresArray     *[]string      `json:"genres" db:"genres_array"`
+	AmountOfPages   int            `json:"amount_of_pages" db:"amount_of_pages"`
+	TotalRatings    int            `json:"total_ratings" db:"total_ratings"`
+	AvgRating       float64        `json:"avg_ratings" db:"avg_ratings"`
+	Status          string         `json:"status" db:"status"`
+	UserReview      string         `json:"user_review" db:"user_review"`
+	UserRating      int            `json:"user_rating" db:"user_rating"`
+	CustomShelves   pq.StringArray `json:"shelves" db:"custom_shelves"`
+	Deleted         bool           `json:"deleted" db:"deleted"` // The author deleted the book, it isn't in the catalogue anymore
+	// Last read of the book and how many times it was finished
+	StartedAt  *string `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt *string `json:"finished_at,omitempty" db:"finished_at"`
+	TimesRead  int     `json:"times_read" db:"times_read"`
+	// Last progress update of the last read, only when there is one
+	ProgressPage      *int      `json:"progress_page,omitempty" db:"progress_page"`
+	ProgressPercent   *float64  `json:"progress_percent,omitempty" db:"progress_percent"`
+	ProgressUpdatedAt *string   `json:"progress_updated_at,omitempty" db:"progress_updated_at"`
+	Id                uuid.UUID `json:"book_id" db:"id"`
 }
 
+// Dates are optional and in YYYY-MM-DD, they default to today when the status starts or finishes a read.
 type BookShelfRequest struct {
-	Status string    `json:"status" binding:"required"`
-	BookId uuid.UUID `json:"book_id" binding:"required"`
+	Status        string    `json:"status" binding:"required"`
+	BookId        uuid.UUID `json:"book_id" binding:"required"`
+	StartedAt     string    `json:"started_at"`
+	FinishedAt    string    `json:"finished_at"`
+	AbandonedPage *int      `json:"abandoned_page"`
 }
 
 type BookShelfType string
@@ -63,7 +80,187 @@ const (
 	BookShelfTypeWantToRead BookShelfType = "plan-to-read"
 	BookShelfTypeReading    BookShelfType = "reading"
 	BookShelfTypeRead       BookShelfType = "read"
+	BookShelfTypeDNF        BookShelfType = "did-not-finish"
 	BookShelfAll            BookShelfType = "all"
 )
 
-var ValidBookShelfTypes = []BookShelfType{BookShelfTypeWantToRead, BookShelfTypeReading, BookShelfTypeRead, BookShelfAll}
+var ValidBookShelfTypes = []BookShelfType{BookShelfTypeWantToRead, BookShelfTypeReading, BookShelfTypeRead, BookShelfTypeDNF, BookShelfAll}
+
+// Shelves of the Goodreads export mapped to the bookshelf types, other shelves are not imported.
+var GoodreadsShelves = map[string]BookShelfType{
+	"to-read":           BookShelfTypeWantToRead,
+	"currently-reading": BookShelfTypeReading,
+	"read":              BookShelfTypeRead,
+	"did-not-finish":    BookShelfTypeDNF,
+}
+
+// A row of the Goodreads library export, dates are already in YYYY-MM-DD.
+type GoodreadsRow struct {
+	Row       int
+	Title     string
+	Author    string
+	ISBN      string
+	ISBN13    string
+	Rating    string
+	Shelf     string
+	DateRead  string
+	DateAdded string
+	Review    string
+	Shelves   []string
+}
+
+// A matched Goodreads row ready to be saved in the shelf and reviews.
+type ImportEntry struct {
+	BookId     uuid.UUID
+	Status     BookShelfType
+	Date       string
+	StartedAt  *string
+	FinishedAt *string
+	Rating     int
+	Review     string
+}
+
+type ImportRowStatus string
+
+const (
+	// The book was found and added to the shelf
+	ImportRowCreated ImportRowStatus = "created"
+	// The book was found but it was already in the shelf, it's left as it was
+	ImportRowMatched ImportRowStatus = "matched"
+	// The book is not in Betterreads or the shelf can't be imported
+	ImportRowSkipped ImportRowStatus = "skipped"
+	// The row is invalid or couldn't be saved
+	ImportRowFailed ImportRowStatus = "failed"
+)
+
+type ImportRowResult struct {
+	Row    int             `json:"row"`
+	Title  string          `json:"title"`
+	Status ImportRowStatus `json:"status"`
+	BookId *uuid.UUID      `json:"book_id,omitempty"`
+	Reason string          `json:"reason,omitempty"`
+}
+
+type ImportReport struct {
+	Created int               `json:"created"`
+	Matched int               `json:"matched"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+func (r *ImportReport) Add(row ImportRowResult) {
+	switch row.Status {
+	case ImportRowCreated:
+		r.Created++
+	case ImportRowMatched:
+		r.Matched++
+	case ImportRowSkipped:
+		r.Skipped++
+	case ImportRowFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// ExclusiveGoodreadsShelf is the Goodreads shelf of a status, books with only a review are read.
+func ExclusiveGoodreadsShelf(status string) string {
+	for shelf, shelfType := range GoodreadsShelves {
+		if string(shelfType) == status {
+			return shelf
+		}
+	}
+	return "read"
+}
+
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// A book of the export, it's in the shelf, reviewed or both. Dates are in YYYY-MM-DD.
+type ExportEntry struct {
+	BookId          uuid.UUID      `json:"book_id" db:"book_id"`
+	Title           string         `json:"title" db:"title"`
+	AuthorId        uuid.UUID      `json:"author_id" db:"author_id"`
+	AuthorName      string         `json:"author_name" db:"author_name"`
+	AuthorFullName  string         `json:"author_full_name" db:"author_full_name"`
+	AuthorFirstName string         `json:"-" db:"author_first_name"`
+	AuthorLastName  string         `json:"-" db:"author_last_name"`
+	ISBN            *string        `json:"isbn,omitempty" db:"isbn"`
+	Description     string         `json:"description" db:"description"`
+	AmountOfPages   int            `json:"amount_of_pages" db:"amount_of_pages"`
+	PublicationDate string         `json:"publication_date" db:"publication_date"`
+	Language        string         `json:"language" db:"language"`
+	Genres          string         `json:"-" db:"genres"`
+	GenresArray     []string       `json:"genres" db:"-"`
+	AvgRating       float64        `json:"avg_rating" db:"avg_rating"`
+	Status          string         `json:"status,omitempty" db:"status"`
+	DateAdded       string         `json:"date_added,omitempty" db:"date_added"`
+	DateRead        string         `json:"date_read,omitempty" db:"date_read"`
+	ReadCount       int            `json:"read_count" db:"read_count"`
+	UserRating      int            `json:"user_rating" db:"user_rating"`
+	UserReview      string         `json:"user_review" db:"user_review"`
+	ReviewDate      string         `json:"review_date,omitempty" db:"review_date"`
+	CustomShelves   pq.StringArray `json:"shelves" db:"custom_shelves"`
+}
+
+// A shelf created by the user, besides the reading status of the book.
+type Shelf struct {
+	Id        uuid.UUID `json:"id" db:"id"`
+	UserId    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	BookCount int       `json:"book_count" db:"book_count"`
+	CreatedAt string    `json:"created_at" db:"created_at"`
+}
+
+type ShelfRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type ShelfBookRequest struct {
+	BookId uuid.UUID `json:"book_id" binding:"required"`
+}
+
+// The custom shelves a book should be in, the ones left out are removed.
+type BookShelvesRequest struct {
+	Shelves []uuid.UUID `json:"shelves"`
+}
+
+// A progress update of a book being read, page and percent are both always set.
+type ReadingProgress struct {
+	Id        uuid.UUID  `json:"id" db:"id"`
+	BookId    uuid.UUID  `json:"book_id" db:"book_id"`
+	ReadId    *uuid.UUID `json:"read_id,omitempty" db:"read_through_id"`
+	Page      int        `json:"page" db:"page"`
+	Percent   float64    `json:"percent" db:"percent"`
+	Note      string     `json:"note" db:"note"`
+	CreatedAt string     `json:"created_at" db:"created_at"`
+}
+
+// Only one of page or percent should be sent, the other one is calculated with the pages of the book.
+type ProgressRequest struct {
+	Page    *int     `json:"page"`
+	Percent *float64 `json:"percent"`
+	Note    string   `json:"note"`
+}
+
+// A read of a book, the open one has the status reading. Did not finish reads can have
+// the page where the book was abandoned. Dates are in YYYY-MM-DD.
+type ReadThrough struct {
+	Id            uuid.UUID     `json:"id" db:"id"`
+	BookId        uuid.UUID     `json:"book_id" db:"book_id"`
+	Status        BookShelfType `json:"status" db:"status"`
+	StartedAt     *string       `json:"started_at" db:"started_at"`
+	FinishedAt    *string       `json:"finished_at" db:"finished_at"`
+	AbandonedPage *int          `json:"abandoned_page,omitempty" db:"abandoned_page"`
+	CreatedAt     string        `json:"created_at" db:"created_at"`
+}
+
+// A finished read, used to log past reads and to correct them. Status is 'read' or 'did-not-finish'.
+type ReadThroughRequest struct {
+	Status        string `json:"status" binding:"required"`
+	StartedAt     string `json:"started_at"`
+	FinishedAt    string `json:"finished_at" binding:"required"`
+	AbandonedPage *int   `json:"abandoned_page"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "github.com/google/uuid"
+
+type GoalType string
+
+const (
+	GoalTypeBooks GoalType = "books"
+	GoalTypePages GoalType = "pages"
+)
+
+// How the books or pages read compare with the ones expected at this point of the year.
+const (
+	GoalCompleted = "completed"
+	GoalAhead     = "ahead"
+	GoalOnTrack   = "on-track"
+	GoalBehind    = "behind"
+)
+
+type GoalRequest struct {
+	Type   string `json:"type" binding:"required"`
+	Target int    `json:"target" binding:"required"`
+}
+
+// The goal of a year with the books and pages of the reads finished in that year.
+type ReadingGoal struct {
+	UserId    uuid.UUID `json:"user_id" db:"user_id"`
+	Year      int       `json:"year" db:"year"`
+	Type      GoalType  `json:"type" db:"goal_type"`
+	Target    int       `json:"target" db:"target"`
+	Books     int       `json:"books_read" db:"books_read"`
+	Pages     int       `json:"pages_read" db:"pages_read"`
+	CreatedAt string    `json:"created_at" db:"created_at"`
+	UpdatedAt string    `json:"updated_at" db:"updated_at"`
+	// Calculated with the type of the goal
+	Completed int     `json:"completed" db:"-"`
+	Percent   float64 `json:"percent" db:"-"`
+	// Books or pages that should be read by today to finish on time, and how far the user is from it
+	Expected   int    `json:"expected" db:"-"`
+	Difference int    `json:"difference" db:"-"`
+	Schedule   string `json:"schedule" db:"-"`
+}
+
+// A book finished in the year of a goal, re-read books appear once per read.
+type GoalBook struct {
+	ReadId        uuid.UUID `json:"read_id" db:"read_id"`
+	BookId        uuid.UUID `json:"book_id" db:"book_id"`
+	Title         string    `json:"title" db:"title"`
+	AuthorId      uuid.UUID `json:"author_id" db:"author_id"`
+	AuthorName    string    `json:"author_name" db:"author_name"`
+	AmountOfPages int       `json:"amount_of_pages" db:"amount_of_pages"`
+	FinishedAt    string    `json:"finished_at" db:"finished_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "github.com/google/uuid"
+
+// Statistics of the reads finished by an user, of every year or only one. Re-reads count
+// as books read, the genres and authors count each book once.
+type ReadingStats struct {
+	UserId          uuid.UUID      `json:"user_id" db:"-"`
+	Year            *int           `json:"year,omitempty" db:"-"`
+	BooksRead       int            `json:"books_read" db:"books_read"`
+	UniqueBooks     int            `json:"unique_books" db:"unique_books"`
+	PagesRead       int            `json:"pages_read" db:"pages_read"`
+	AvgDaysToFinish *float64       `json:"avg_days_to_finish" db:"avg_days_to_finish"`
+	RatingsGiven    int            `json:"ratings_given" db:"-"`
+	AvgRatingGiven  float64        `json:"avg_rating_given" db:"-"`
+	ByYear          []*PeriodStats `json:"by_year" db:"-"`
+	ByMonth         []*PeriodStats `json:"by_month" db:"-"`
+	Genres          []*GenreStats  `json:"genres" db:"-"`
+	LongestBook     *BookStats     `json:"longest_book" db:"-"`
+	ShortestBook    *BookStats     `json:"shortest_book" db:"-"`
+	TopAuthors      []*AuthorStats `json:"top_authors" db:"-"`
+}
+
+// Books and pages read in a year (YYYY) or month (YYYY-MM).
+type PeriodStats struct {
+	Period string `json:"period" db:"period"`
+	Books  int    `json:"books" db:"books"`
+	Pages  int    `json:"pages" db:"pages"`
+}
+
+type GenreStats struct {
+	GenreId int     `json:"-" db:"genre_id"`
+	Genre   string  `json:"genre" db:"-"`
+	Books   int     `json:"books" db:"books"`
+	Percent float64 `json:"percent" db:"-"`
+}
+
+type BookStats struct {
+	BookId        uuid.UUID `json:"book_id" db:"book_id"`
+	Title         string    `json:"title" db:"title"`
+	AmountOfPages int       `json:"amount_of_pages" db:"amount_of_pages"`
+}
+
+type AuthorStats struct {
+	AuthorId   uuid.UUID `json:"author_id" db:"author_id"`
+	AuthorName string    `json:"author_name" db:"author_name"`
+	Books      int       `json:"books" db:"books"`
+}
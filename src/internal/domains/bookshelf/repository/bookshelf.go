@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"context"
+
 	"errors"
 
 	"github.com/betterreads/internal/domains/bookshelf/models"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
@@ -13,6 +16,9 @@ var (
 	ErrBookAlreadyInLibrary  = errors.New("book already in library")
 	ErrBookNotInLibrary      = errors.New("book not in library")
 	ErrGenreNotFound         = errors.New("genre not found")
+	ErrShelfNotFound         = errors.New("shelf not found")
+	ErrReadThroughNotFound   = errors.New("read not found")
+	ErrGoalNotFound          = errors.New("goal not found")
 
 	ErrInvaliStatusType = er.ErrorParam{
 		Name:   "status",
@@ -21,10 +27,36 @@ var (
 )
 
 type BookshelfDatabase interface {
-	GetBookShelf(usedId uuid.UUID, ShelfType models.BookShelfType) ([]*models.BookInShelfResponse, error)
-	AddBookToShelf(userId uuid.UUID, req *models.BookShelfRequest) error
-	EditBookInShelf(userId uuid.UUID, req *models.BookShelfRequest) error
-	SearchBookShelf(userId uuid.UUID, shelfType models.BookShelfType, genre string, sort string, isDirAsc bool) ([]*models.BookInShelfResponse, error)
-	CheckIfBookIsInUserShelf(userId uuid.UUID, bookId uuid.UUID) bool
-	DeleteBookFromShelf(userId uuid.UUID, bookId uuid.UUID) error
+	GetBookShelf(ctx context.Context, usedId uuid.UUID, ShelfType models.BookShelfType, shelfId *uuid.UUID, page pagination.Request) (pagination.Page[*models.BookInShelfResponse], error)
+	AddBookToShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error
+	EditBookInShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error
+	SearchBookShelf(ctx context.Context, userId uuid.UUID, shelfType models.BookShelfType, shelfId *uuid.UUID, genre string, sort string, isDirAsc bool) ([]*models.BookInShelfResponse, error)
+	CheckIfBookIsInUserShelf(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) bool
+	DeleteBookFromShelf(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) error
+	FindBookToImport(ctx context.Context, isbns []string, titles []string, author string) (uuid.UUID, error)
+	ImportBookToShelf(ctx context.Context, userId uuid.UUID, entry *models.ImportEntry) (bool, error)
+	ExportLibrary(ctx context.Context, userId uuid.UUID, each func(entry *models.ExportEntry) error) error
+	CreateShelf(ctx context.Context, userId uuid.UUID, name string) (*models.Shelf, error)
+	GetShelves(ctx context.Context, userId uuid.UUID) ([]*models.Shelf, error)
+	GetShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID) (*models.Shelf, error)
+	GetShelfByName(ctx context.Context, userId uuid.UUID, name string) (*models.Shelf, error)
+	RenameShelf(ctx context.Context, shelfId uuid.UUID, name string) error
+	DeleteShelf(ctx context.Context, shelfId uuid.UUID) error
+	AddBookToCustomShelf(ctx context.Context, shelfId uuid.UUID, bookId uuid.UUID) error
+	RemoveBookFromCustomShelf(ctx context.Context, shelfId uuid.UUID, bookId uuid.UUID) error
+	SetBookCustomShelves(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, shelfIds []uuid.UUID) error
+	GetBookStatusAndPages(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) (models.BookShelfType, int, error)
+	AddProgress(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, page int, percent float64, note string, finished bool) (*models.ReadingProgress, error)
+	GetProgressHistory(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadingProgress, error)
+	GetReadThroughs(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadThrough, error)
+	GetReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID) (*models.ReadThrough, error)
+	AddReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error)
+	UpdateReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error)
+	DeleteReadThrough(ctx context.Context, readId uuid.UUID) error
+	SetGoal(ctx context.Context, userId uuid.UUID, year int, goalType models.GoalType, target int) error
+	GetGoals(ctx context.Context, userId uuid.UUID) ([]*models.ReadingGoal, error)
+	GetGoal(ctx context.Context, userId uuid.UUID, year int) (*models.ReadingGoal, error)
+	DeleteGoal(ctx context.Context, userId uuid.UUID, year int) error
+	GetGoalBooks(ctx context.Context, userId uuid.UUID, year int) ([]*models.GoalBook, error)
+	GetStats(ctx context.Context, userId uuid.UUID, year *int) (*models.ReadingStats, error)
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"strconv"
 	"strings"
@@ -9,37 +10,19 @@ import (
 
 	booksRepo "github.com/betterreads/internal/domains/books/repository"
 	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type PostgresBookShelfRepository struct {
 	c *sqlx.DB
 }
 
-func NewPostgresBookShelfRepository(c *sqlx.DB) (BookshelfDatabase, error) {
-	enableUUIDExtension := `CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`
-	if _, err := c.Exec(enableUUIDExtension); err != nil {
-		return nil, fmt.Errorf("failed to enable uuid extension: %w", err)
-	}
-
-	schemaBookshelf := `
-        CREATE TABLE IF NOT EXISTS bookshelf (
-            user_id UUID NOT NULL, 
-            book_id UUID NOT NULL, 
-            status VARCHAR(50) NOT NULL,
-            date DATE NOT NULL,
-            PRIMARY KEY (user_id, book_iD),
-            FOREIGN KEY (user_id) REFERENCES users(id),
-            FOREIGN KEY (book_id) REFERENCES books(id)
-        );
-    `
-
-	if _, err := c.Exec(schemaBookshelf); err != nil {
-		return nil, fmt.Errorf("failed to create bookshelf table: %w", err)
-	}
-
-	return &PostgresBookShelfRepository{c: c}, nil
+func NewPostgresBookShelfRepository(c *sqlx.DB) BookshelfDatabase {
+	return &PostgresBookShelfRepository{c: c}
 }
 
 const query_beggining = `
@@ -74,6 +57,23 @@ const query_beggining = `
         bs.status,
         COALESCE(ur.review, '') as user_review,
         COALESCE(ur.rating, 0) as user_rating,
+        COALESCE((
+            SELECT array_agg(s.name ORDER BY s.name)
+            FROM shelves_books sb
+            JOIN shelves s ON s.id = sb.shelf_id
+            WHERE sb.book_id = bk.id AND s.user_id = $1
+        ), '{}') as custom_shelves,
+        lr.started_at,
+        lr.finished_at,
+        (
+            SELECT COUNT(*)
+            FROM read_throughs rt
+            WHERE rt.user_id = $1 AND rt.book_id = bk.id AND rt.status = 'read'
+        ) as times_read,
+        pr.page as progress_page,
+        pr.percent as progress_percent,
+        pr.created_at as progress_updated_at,
+        bk.deleted_at IS NOT NULL as deleted,
         bk.id as id
     FROM bookshelf bs
     JOIN books bk ON bs.book_id = bk.id
@@ -81,6 +81,20 @@ const query_beggining = `
     LEFT JOIN ratings r ON r.book_id = bk.id
     LEFT JOIN user_ratings ur ON ur.book_id = bk.id
     LEFT JOIN genres_books bg ON bg.book_id = bk.id 
+    LEFT JOIN LATERAL (
+        SELECT rt.id, to_char(rt.started_at, 'YYYY-MM-DD') as started_at, to_char(rt.finished_at, 'YYYY-MM-DD') as finished_at
+        FROM read_throughs rt
+        WHERE rt.user_id = bs.user_id AND rt.book_id = bk.id
+        ORDER BY ` + lastReadOrder + `
+        LIMIT 1
+    ) lr ON true
+    LEFT JOIN LATERAL (
+        SELECT p.page, p.percent, p.created_at
+        FROM reading_progress p
+        WHERE p.read_through_id = lr.id
+        ORDER BY p.created_at DESC
+        LIMIT 1
+    ) pr ON true
 	WHERE
 `
 
@@ -88,6 +102,13 @@ const query_normal_cond = `
 	($2::VARCHAR IS NULL OR bs.status=$2) AND bs.user_id=$1
 `
 
+// Filters by a custom shelf, n is the number of the param with the shelf id
+func customShelfCond(n int) string {
+	return fmt.Sprintf(`
+	AND EXISTS(SELECT 1 FROM shelves_books sb WHERE sb.shelf_id = $%d AND sb.book_id = bk.id)
+`, n)
+}
+
 const query_group_by = `
     GROUP BY
 	bk.id,                 
@@ -103,10 +124,16 @@ const query_group_by = `
 	total_ratings,
 	avg_ratings,
 	ur.review,
-	ur.rating
+	ur.rating,
+	lr.started_at,
+	lr.finished_at,
+	pr.page,
+	pr.percent,
+	pr.created_at,
+	bk.deleted_at
 `
 
-func (p *PostgresBookShelfRepository) GetBookShelf(userId uuid.UUID, shelfType models.BookShelfType) ([]*models.BookInShelfResponse, error) {
+func (p *PostgresBookShelfRepository) GetBookShelf(ctx context.Context, userId uuid.UUID, shelfType models.BookShelfType, shelfId *uuid.UUID, page pagination.Request) (pagination.Page[*models.BookInShelfResponse], error) {
 	var status *models.BookShelfType
 
 	if shelfType == models.BookShelfAll {
@@ -116,13 +143,25 @@ func (p *PostgresBookShelfRepository) GetBookShelf(userId uuid.UUID, shelfType m
 	}
 
 	books := []*models.BookInShelfResponse{}
-	query := query_beggining + query_normal_cond + query_group_by + `
-	ORDER BY bs.date DESC;
-    `
+	query := query_beggining + query_normal_cond
+	args := []interface{}{userId, status}
+	if shelfId != nil {
+		args = append(args, *shelfId)
+		query += customShelfCond(len(args))
+	}
+	if page.Cursor != nil {
+		query += fmt.Sprintf(` AND (to_char(bs.date, `+pagination.TimeKeyFormat+`), bk.id::TEXT) < ($%d, $%d)`, len(args)+1, len(args)+2)
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += query_group_by + fmt.Sprintf(`
+	ORDER BY bs.date DESC, bk.id::TEXT DESC
+	LIMIT $%d;
+    `, len(args)+1)
+	args = append(args, page.Fetch())
 
-	if err := p.c.Select(&books, query, userId, status); err != nil {
+	if err := p.c.SelectContext(ctx, &books, query, args...); err != nil {
 		if err != sql.ErrNoRows {
-			return nil, fmt.Errorf("failed to get bookshelf: %w", err)
+			return pagination.Page[*models.BookInShelfResponse]{}, fmt.Errorf("failed to get bookshelf: %w", err)
 		}
 	}
 
@@ -131,56 +170,275 @@ func (p *PostgresBookShelfRepository) GetBookShelf(userId uuid.UUID, shelfType m
 		book.Genres = ""
 	}
 
-	return books, nil
+	return pagination.NewPage(books, page, func(book *models.BookInShelfResponse) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(book.Date), Id: book.Id.String()}
+	}), nil
 
 }
 
-func (p *PostgresBookShelfRepository) AddBookToShelf(userId uuid.UUID, req *models.BookShelfRequest) error {
+func (p *PostgresBookShelfRepository) AddBookToShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error {
+	tx, err := p.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add book to shelf: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO bookshelf (user_id, book_id, status, date)
                       VALUES ($1, $2, $3, now())`
 
-	_, err := p.c.Exec(query, userId, req.BookId, req.Status)
+	_, err = tx.ExecContext(ctx, query, userId, req.BookId, req.Status)
 	if err != nil {
 		return fmt.Errorf("failed to add book to shelf: %w", err)
 	}
 
+	if err := updateReadThroughs(ctx, tx, userId, req); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to add book to shelf: %w", err)
+	}
 	return nil
 }
 
-func (p *PostgresBookShelfRepository) EditBookInShelf(userId uuid.UUID, req *models.BookShelfRequest) error {
-	query := ` UPDATE bookshelf
-                      SET status=$1, date=now()
+// EditBookInShelf changes the status of the book, the date it was added is kept and the
+// reads of the book are started or finished with the change.
+func (p *PostgresBookShelfRepository) EditBookInShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error {
+	tx, err := p.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to edit book in shelf: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous models.BookShelfType
+	query := `SELECT status FROM bookshelf WHERE user_id=$1 AND book_id=$2 FOR UPDATE;`
+	if err := tx.GetContext(ctx, &previous, query, userId, req.BookId); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBookNotInLibrary
+		}
+		return fmt.Errorf("failed to edit book in shelf: %w", err)
+	}
+
+	query = ` UPDATE bookshelf
+                      SET status=$1
                       WHERE user_id=$2 AND book_id=$3;`
-	_, err := p.c.Exec(query, req.Status, userId, req.BookId)
+	_, err = tx.ExecContext(ctx, query, req.Status, userId, req.BookId)
 	if err != nil {
 		return fmt.Errorf("failed to edit book in shelf: %w", err)
 	}
 
+	// Setting the same status again doesn't add a new read, finished reads are edited on their own
+	status := models.BookShelfType(req.Status)
+	if previous != status || status == models.BookShelfTypeReading {
+		if err := updateReadThroughs(ctx, tx, userId, req); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to edit book in shelf: %w", err)
+	}
 	return nil
 }
 
-func (p *PostgresBookShelfRepository) CheckIfBookIsInUserShelf(userId uuid.UUID, bookId uuid.UUID) bool {
+func (p *PostgresBookShelfRepository) CheckIfBookIsInUserShelf(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) bool {
 	exists := false
 	query := `SELECT EXISTS(SELECT 1 FROM bookshelf WHERE user_id=$1 AND book_id=$2);`
-	err := p.c.Get(&exists, query, userId, bookId)
+	err := p.c.GetContext(ctx, &exists, query, userId, bookId)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if book is in user shelf", "error", err)
 		return false
 	}
 
 	return exists
 }
 
-func (p *PostgresBookShelfRepository) DeleteBookFromShelf(userId uuid.UUID, bookId uuid.UUID) error {
-	query := `DELETE FROM bookshelf WHERE user_id=$1 AND book_id=$2;`
-	_, err := p.c.Exec(query, userId, bookId)
+func (p *PostgresBookShelfRepository) DeleteBookFromShelf(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) error {
+	tx, err := p.c.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete book from shelf: %w", err)
 	}
+	defer tx.Rollback()
+
+	// A book out of the bookshelf can't be in custom shelves
+	query := `DELETE FROM shelves_books
+			  WHERE book_id=$2 AND shelf_id IN (SELECT id FROM shelves WHERE user_id=$1);`
+	if _, err := tx.ExecContext(ctx, query, userId, bookId); err != nil {
+		return fmt.Errorf("failed to delete book from custom shelves: %w", err)
+	}
+
+	// Finished reads stay in the history, but a book out of the bookshelf is no longer being read
+	query = `DELETE FROM read_throughs WHERE user_id=$1 AND book_id=$2 AND status='reading';`
+	if _, err := tx.ExecContext(ctx, query, userId, bookId); err != nil {
+		return fmt.Errorf("failed to delete open read: %w", err)
+	}
+
+	query = `DELETE FROM bookshelf WHERE user_id=$1 AND book_id=$2;`
+	if _, err := tx.ExecContext(ctx, query, userId, bookId); err != nil {
+		return fmt.Errorf("failed to delete book from shelf: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete book from shelf: %w", err)
+	}
+	return nil
+}
+
+// FindBookToImport looks for a book by any of its ISBNs and then by title and author,
+// the author can be the username or the full name. Titles must be in lower case.
+func (p *PostgresBookShelfRepository) FindBookToImport(ctx context.Context, isbns []string, titles []string, author string) (uuid.UUID, error) {
+	var bookId uuid.UUID
 
+	if len(isbns) > 0 {
+		query := `SELECT id FROM books WHERE isbn = ANY($1) AND deleted_at IS NULL LIMIT 1;`
+		err := p.c.GetContext(ctx, &bookId, query, pq.Array(isbns))
+		if err == nil {
+			return bookId, nil
+		}
+		if err != sql.ErrNoRows {
+			return uuid.Nil, fmt.Errorf("failed to find book: %w", err)
+		}
+	}
+
+	query := `SELECT bk.id FROM books bk
+			  JOIN users u ON bk.author = u.id
+			  WHERE LOWER(bk.title) = ANY($1) AND bk.deleted_at IS NULL
+			  AND (LOWER(u.first_name || ' ' || u.last_name) = LOWER($2) OR LOWER(u.username) = LOWER($2))
+			  ORDER BY bk.id
+			  LIMIT 1;`
+	err := p.c.GetContext(ctx, &bookId, query, pq.Array(titles), author)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, ErrBookNotFoundInLibrary
+		}
+		return uuid.Nil, fmt.Errorf("failed to find book: %w", err)
+	}
+	return bookId, nil
+}
+
+// ImportBookToShelf adds the book to the shelf and saves its rating and review. Books that
+// are already in the shelf and existing reviews are left as they are. Returns true if the
+// book was added to the shelf.
+func (p *PostgresBookShelfRepository) ImportBookToShelf(ctx context.Context, userId uuid.UUID, entry *models.ImportEntry) (bool, error) {
+	tx, err := p.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to import book: %w", err)
+	}
+	defer tx.Rollback()
+
+	created := false
+	query := `INSERT INTO bookshelf (user_id, book_id, status, date)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (user_id, book_id) DO NOTHING
+			  RETURNING true;`
+	err = tx.GetContext(ctx, &created, query, userId, entry.BookId, entry.Status, entry.Date)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to import book to shelf: %w", err)
+	}
+
+	if created && entry.Status != models.BookShelfTypeWantToRead {
+		query = `INSERT INTO read_throughs (user_id, book_id, status, started_at, finished_at)
+				 VALUES ($1, $2, $3, $4, $5);`
+		_, err = tx.ExecContext(ctx, query, userId, entry.BookId, entry.Status, entry.StartedAt, entry.FinishedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to import read: %w", err)
+		}
+	}
+
+	if entry.Rating > 0 {
+		query = `INSERT INTO reviews (user_id, book_id, rating, review, publication_date)
+				 VALUES ($1, $2, $3, $4, $5)
+				 ON CONFLICT (user_id, book_id) DO NOTHING;`
+		_, err = tx.ExecContext(ctx, query, userId, entry.BookId, entry.Rating, entry.Review, entry.Date)
+		if err != nil {
+			return false, fmt.Errorf("failed to import review: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to import book: %w", err)
+	}
+	return created, nil
+}
+
+// ExportLibrary calls each with every book in the shelf or reviewed by the user, rows are
+// read one by one so the export can be streamed.
+func (p *PostgresBookShelfRepository) ExportLibrary(ctx context.Context, userId uuid.UUID, each func(entry *models.ExportEntry) error) error {
+	query := `
+	SELECT
+		bk.id AS book_id,
+		bk.title,
+		u.id AS author_id,
+		u.username AS author_name,
+		u.first_name || ' ' || u.last_name AS author_full_name,
+		u.first_name AS author_first_name,
+		u.last_name AS author_last_name,
+		bk.isbn,
+		bk.description,
+		bk.amount_of_pages,
+		bk.publication_date,
+		bk.language,
+		COALESCE((
+			SELECT string_agg(g.name, ',' ORDER BY g.name)
+			FROM genres_books gb
+			JOIN genres g ON g.id = gb.genre_id
+			WHERE gb.book_id = bk.id
+		), '') AS genres,
+		COALESCE((SELECT AVG(ar.rating) FROM reviews ar WHERE ar.book_id = bk.id), 0) AS avg_rating,
+		COALESCE(bs.status, '') AS status,
+		COALESCE(to_char(bs.date, 'YYYY-MM-DD'), '') AS date_added,
+		COALESCE((
+			SELECT to_char(MAX(rt.finished_at), 'YYYY-MM-DD')
+			FROM read_throughs rt
+			WHERE rt.user_id = $1 AND rt.book_id = bk.id AND rt.status = 'read'
+		), '') AS date_read,
+		(
+			SELECT COUNT(*)
+			FROM read_throughs rt
+			WHERE rt.user_id = $1 AND rt.book_id = bk.id AND rt.status = 'read'
+		) AS read_count,
+		COALESCE(r.rating, 0) AS user_rating,
+		COALESCE(r.review, '') AS user_review,
+		COALESCE(r.publication_date, '') AS review_date,
+		COALESCE((
+			SELECT array_agg(s.name ORDER BY s.name)
+			FROM shelves_books sb
+			JOIN shelves s ON s.id = sb.shelf_id
+			WHERE sb.book_id = bk.id AND s.user_id = $1
+		), '{}') AS custom_shelves
+	FROM (SELECT * FROM bookshelf WHERE user_id = $1) bs
+	FULL OUTER JOIN (SELECT * FROM reviews WHERE user_id = $1) r ON r.book_id = bs.book_id
+	JOIN books bk ON bk.id = COALESCE(bs.book_id, r.book_id)
+	JOIN users u ON bk.author = u.id
+	ORDER BY bk.title, bk.id;
+	`
+
+	rows, err := p.c.QueryxContext(ctx, query, userId)
+	if err != nil {
+		return fmt.Errorf("failed to export library: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &models.ExportEntry{}
+		if err := rows.StructScan(entry); err != nil {
+			return fmt.Errorf("failed to export library: %w", err)
+		}
+		entry.GenresArray = []string{}
+		if entry.Genres != "" {
+			entry.GenresArray = strings.Split(entry.Genres, ",")
+		}
+		if err := each(entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export library: %w", err)
+	}
 	return nil
 }
 
-func (p *PostgresBookShelfRepository) SearchBookShelf(userId uuid.UUID, shelfType models.BookShelfType, genre string, sort string, isDirAsc bool) ([]*models.BookInShelfResponse, error) {
+func (p *PostgresBookShelfRepository) SearchBookShelf(ctx context.Context, userId uuid.UUID, shelfType models.BookShelfType, shelfId *uuid.UUID, genre string, sort string, isDirAsc bool) ([]*models.BookInShelfResponse, error) {
 	var status *models.BookShelfType
 	if shelfType == models.BookShelfAll {
 		status = nil
@@ -205,7 +463,12 @@ func (p *PostgresBookShelfRepository) SearchBookShelf(userId uuid.UUID, shelfTyp
 		args = append(args, genreId)
 	}
 
-	query += query_normal_cond + query_group_by
+	query += query_normal_cond
+	if shelfId != nil {
+		args = append(args, *shelfId)
+		query += customShelfCond(len(args))
+	}
+	query += query_group_by
 
 	if sort != "" {
 		var direciton string
@@ -217,7 +480,7 @@ func (p *PostgresBookShelfRepository) SearchBookShelf(userId uuid.UUID, shelfTyp
 		query += " ORDER BY " + sort + " " + direciton
 	}
 
-	if err := p.c.Select(&books, query, args...); err != nil {
+	if err := p.c.SelectContext(ctx, &books, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to search books in shelf: %w", err)
 	}
 
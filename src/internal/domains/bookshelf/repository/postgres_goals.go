@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+)
+
+// Reads finished in the year of the goal, the date range lets the finished_at index be used.
+const query_goals = `
+	SELECT g.user_id, g.year, g.goal_type, g.target, g.created_at, g.updated_at,
+		COUNT(rt.id) AS books_read,
+		COALESCE(SUM(bk.amount_of_pages), 0) AS pages_read
+	FROM reading_goals g
+	LEFT JOIN read_throughs rt ON rt.user_id = g.user_id
+		AND rt.status = 'read'
+		AND rt.finished_at >= make_date(g.year, 1, 1)
+		AND rt.finished_at < make_date(g.year + 1, 1, 1)
+	LEFT JOIN books bk ON bk.id = rt.book_id
+`
+
+func (p *PostgresBookShelfRepository) SetGoal(ctx context.Context, userId uuid.UUID, year int, goalType models.GoalType, target int) error {
+	query := `INSERT INTO reading_goals (user_id, year, goal_type, target)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (user_id, year) DO UPDATE
+			  SET goal_type = EXCLUDED.goal_type, target = EXCLUDED.target, updated_at = now();`
+	if _, err := p.c.ExecContext(ctx, query, userId, year, goalType, target); err != nil {
+		return fmt.Errorf("failed to set goal: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresBookShelfRepository) GetGoals(ctx context.Context, userId uuid.UUID) ([]*models.ReadingGoal, error) {
+	goals := []*models.ReadingGoal{}
+	query := query_goals + `
+	WHERE g.user_id = $1
+	GROUP BY g.user_id, g.year
+	ORDER BY g.year DESC;`
+	if err := p.c.SelectContext(ctx, &goals, query, userId); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get goals: %w", err)
+		}
+	}
+	return goals, nil
+}
+
+func (p *PostgresBookShelfRepository) GetGoal(ctx context.Context, userId uuid.UUID, year int) (*models.ReadingGoal, error) {
+	goal := &models.ReadingGoal{}
+	query := query_goals + `
+	WHERE g.user_id = $1 AND g.year = $2
+	GROUP BY g.user_id, g.year;`
+	if err := p.c.GetContext(ctx, goal, query, userId, year); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrGoalNotFound
+		}
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	return goal, nil
+}
+
+func (p *PostgresBookShelfRepository) DeleteGoal(ctx context.Context, userId uuid.UUID, year int) error {
+	query := `DELETE FROM reading_goals WHERE user_id = $1 AND year = $2;`
+	if _, err := p.c.ExecContext(ctx, query, userId, year); err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	return nil
+}
+
+// GetGoalBooks returns the books finished in the year, the last finished first.
+func (p *PostgresBookShelfRepository) GetGoalBooks(ctx context.Context, userId uuid.UUID, year int) ([]*models.GoalBook, error) {
+	books := []*models.GoalBook{}
+	query := `
+	SELECT rt.id AS read_id, bk.id AS book_id, bk.title, u.id AS author_id, u.username AS author_name,
+		bk.amount_of_pages, to_char(rt.finished_at, 'YYYY-MM-DD') AS finished_at
+	FROM read_throughs rt
+	JOIN books bk ON bk.id = rt.book_id
+	JOIN users u ON u.id = bk.author
+	WHERE rt.user_id = $1
+		AND rt.status = 'read'
+		AND rt.finished_at >= make_date($2, 1, 1)
+		AND rt.finished_at < make_date($2 + 1, 1, 1)
+	ORDER BY rt.finished_at DESC, rt.created_at DESC;`
+	if err := p.c.SelectContext(ctx, &books, query, userId, year); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get books of goal: %w", err)
+		}
+	}
+	return books, nil
+}
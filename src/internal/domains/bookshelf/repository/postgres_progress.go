@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+)
+
+// GetBookStatusAndPages returns the status of the book in the user's bookshelf and its amount of pages.
+func (p *PostgresBookShelfRepository) GetBookStatusAndPages(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) (models.BookShelfType, int, error) {
+	row := struct {
+		Status        models.BookShelfType `db:"status"`
+		AmountOfPages int                  `db:"amount_of_pages"`
+	}{}
+	query := `SELECT bs.status, bk.amount_of_pages
+			  FROM bookshelf bs
+			  JOIN books bk ON bk.id = bs.book_id
+			  WHERE bs.user_id = $1 AND bs.book_id = $2;`
+	if err := p.c.GetContext(ctx, &row, query, userId, bookId); err != nil {
+		if err == sql.ErrNoRows {
+			return "", 0, ErrBookNotInLibrary
+		}
+		return "", 0, fmt.Errorf("failed to get book in shelf: %w", err)
+	}
+	return row.Status, row.AmountOfPages, nil
+}
+
+// AddProgress saves a progress update of the open read, when finished the read is closed
+// and the book is moved to the read shelf too.
+func (p *PostgresBookShelfRepository) AddProgress(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, page int, percent float64, note string, finished bool) (*models.ReadingProgress, error) {
+	tx, err := p.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add progress: %w", err)
+	}
+	defer tx.Rollback()
+
+	readId, err := openReadThrough(ctx, tx, userId, bookId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &models.ReadingProgress{}
+	query := `INSERT INTO reading_progress (user_id, book_id, read_through_id, page, percent, note)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, book_id, read_through_id, page, percent, note, created_at;`
+	if err := tx.GetContext(ctx, progress, query, userId, bookId, readId, page, percent, note); err != nil {
+		return nil, fmt.Errorf("failed to add progress: %w", err)
+	}
+
+	if finished {
+		query = `UPDATE bookshelf SET status=$1 WHERE user_id=$2 AND book_id=$3;`
+		if _, err := tx.ExecContext(ctx, query, models.BookShelfTypeRead, userId, bookId); err != nil {
+			return nil, fmt.Errorf("failed to move book to read: %w", err)
+		}
+		if err := finishReadThrough(ctx, tx, userId, bookId, models.BookShelfTypeRead, nil, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to add progress: %w", err)
+	}
+	return progress, nil
+}
+
+func (p *PostgresBookShelfRepository) GetProgressHistory(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadingProgress, error) {
+	history := []*models.ReadingProgress{}
+	query := `SELECT id, book_id, read_through_id, page, percent, note, created_at
+			  FROM reading_progress
+			  WHERE user_id = $1 AND book_id = $2
+			  ORDER BY created_at DESC;`
+	if err := p.c.SelectContext(ctx, &history, query, userId, bookId); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get progress history: %w", err)
+		}
+	}
+	return history, nil
+}
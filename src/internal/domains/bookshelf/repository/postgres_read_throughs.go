@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// The open read goes first and then the ones finished or started last.
+const lastReadOrder = `rt.status = 'reading' DESC, COALESCE(rt.finished_at, rt.started_at) DESC NULLS LAST, rt.created_at DESC`
+
+const query_read_throughs = `
+	SELECT rt.id, rt.book_id, rt.status,
+		to_char(rt.started_at, 'YYYY-MM-DD') AS started_at,
+		to_char(rt.finished_at, 'YYYY-MM-DD') AS finished_at,
+		rt.abandoned_page, rt.created_at
+	FROM read_throughs rt
+`
+
+// Empty dates are saved as null.
+func nullableDate(date string) *string {
+	if date == "" {
+		return nil
+	}
+	return &date
+}
+
+// updateReadThroughs starts or finishes the read of the book according to the new status.
+func updateReadThroughs(ctx context.Context, tx *sqlx.Tx, userId uuid.UUID, req *models.BookShelfRequest) error {
+	switch status := models.BookShelfType(req.Status); status {
+	case models.BookShelfTypeReading:
+		_, err := openReadThrough(ctx, tx, userId, req.BookId, nullableDate(req.StartedAt))
+		return err
+	case models.BookShelfTypeRead, models.BookShelfTypeDNF:
+		return finishReadThrough(ctx, tx, userId, req.BookId, status, nullableDate(req.StartedAt), nullableDate(req.FinishedAt), req.AbandonedPage)
+	}
+	return nil
+}
+
+// openReadThrough returns the open read of the book, starting one today if there isn't.
+// The start date of the open read is changed when one is given.
+func openReadThrough(ctx context.Context, tx *sqlx.Tx, userId uuid.UUID, bookId uuid.UUID, startedAt *string) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT id FROM read_throughs WHERE user_id=$1 AND book_id=$2 AND status=$3;`
+	err := tx.GetContext(ctx, &id, query, userId, bookId, models.BookShelfTypeReading)
+	if err != nil && err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("failed to get open read: %w", err)
+	}
+
+	if err == nil {
+		if startedAt != nil {
+			query = `UPDATE read_throughs SET started_at=$1 WHERE id=$2;`
+			if _, err := tx.ExecContext(ctx, query, *startedAt, id); err != nil {
+				return uuid.Nil, fmt.Errorf("failed to update open read: %w", err)
+			}
+		}
+		return id, nil
+	}
+
+	query = `INSERT INTO read_throughs (user_id, book_id, status, started_at)
+			 VALUES ($1, $2, $3, COALESCE($4::DATE, CURRENT_DATE))
+			 RETURNING id;`
+	if err := tx.GetContext(ctx, &id, query, userId, bookId, models.BookShelfTypeReading, startedAt); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to start read: %w", err)
+	}
+	return id, nil
+}
+
+// finishReadThrough closes the open read of the book, finishing it today if there is no date.
+// Books finished without an open read get a new read, like a re-read that wasn't tracked.
+// Did not finish reads keep the abandoned page, or the page of the last progress update.
+func finishReadThrough(ctx context.Context, tx *sqlx.Tx, userId uuid.UUID, bookId uuid.UUID, status models.BookShelfType, startedAt *string, finishedAt *string, abandonedPage *int) error {
+	if status != models.BookShelfTypeDNF {
+		abandonedPage = nil
+	}
+
+	query := `UPDATE read_throughs rt
+			  SET status=$3,
+				  started_at=COALESCE($4::DATE, rt.started_at),
+				  finished_at=COALESCE($5::DATE, CURRENT_DATE),
+				  abandoned_page=CASE WHEN $3 = $7 THEN COALESCE($6::INTEGER, (
+					  SELECT p.page FROM reading_progress p
+					  WHERE p.read_through_id = rt.id
+					  ORDER BY p.created_at DESC
+					  LIMIT 1
+				  )) END
+			  WHERE rt.user_id=$1 AND rt.book_id=$2 AND rt.status=$8;`
+	res, err := tx.ExecContext(ctx, query, userId, bookId, status, startedAt, finishedAt, abandonedPage, models.BookShelfTypeDNF, models.BookShelfTypeReading)
+	if err != nil {
+		return fmt.Errorf("failed to finish read: %w", err)
+	}
+	if updated, err := res.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+
+	query = `INSERT INTO read_throughs (user_id, book_id, status, started_at, finished_at, abandoned_page)
+			 VALUES ($1, $2, $3, $4, COALESCE($5::DATE, CURRENT_DATE), $6);`
+	if _, err := tx.ExecContext(ctx, query, userId, bookId, status, startedAt, finishedAt, abandonedPage); err != nil {
+		return fmt.Errorf("failed to add read: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresBookShelfRepository) GetReadThroughs(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadThrough, error) {
+	reads := []*models.ReadThrough{}
+	query := query_read_throughs + `
+	WHERE rt.user_id = $1 AND rt.book_id = $2
+	ORDER BY ` + lastReadOrder + `;`
+	if err := p.c.SelectContext(ctx, &reads, query, userId, bookId); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get reads: %w", err)
+		}
+	}
+	return reads, nil
+}
+
+// GetReadThrough only finds the read if it's of the book in the user's bookshelf.
+func (p *PostgresBookShelfRepository) GetReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID) (*models.ReadThrough, error) {
+	read := &models.ReadThrough{}
+	query := query_read_throughs + `
+	WHERE rt.user_id = $1 AND rt.book_id = $2 AND rt.id = $3;`
+	if err := p.c.GetContext(ctx, read, query, userId, bookId, readId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReadThroughNotFound
+		}
+		return nil, fmt.Errorf("failed to get read: %w", err)
+	}
+	return read, nil
+}
+
+// AddReadThrough logs a finished read of the book, like a past re-read.
+func (p *PostgresBookShelfRepository) AddReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error) {
+	var id uuid.UUID
+	query := `INSERT INTO read_throughs (user_id, book_id, status, started_at, finished_at, abandoned_page)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id;`
+	err := p.c.GetContext(ctx, &id, query, userId, bookId, req.Status, nullableDate(req.StartedAt), req.FinishedAt, req.AbandonedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add read: %w", err)
+	}
+	return p.GetReadThrough(ctx, userId, bookId, id)
+}
+
+// UpdateReadThrough changes the status and dates of a finished read.
+func (p *PostgresBookShelfRepository) UpdateReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error) {
+	query := `UPDATE read_throughs
+			  SET status=$1, started_at=$2, finished_at=$3, abandoned_page=$4
+			  WHERE id=$5;`
+	_, err := p.c.ExecContext(ctx, query, req.Status, nullableDate(req.StartedAt), req.FinishedAt, req.AbandonedPage, readId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update read: %w", err)
+	}
+	return p.GetReadThrough(ctx, userId, bookId, readId)
+}
+
+// DeleteReadThrough removes the read and its progress updates.
+func (p *PostgresBookShelfRepository) DeleteReadThrough(ctx context.Context, readId uuid.UUID) error {
+	query := `DELETE FROM read_throughs WHERE id=$1;`
+	if _, err := p.c.ExecContext(ctx, query, readId); err != nil {
+		return fmt.Errorf("failed to delete read: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+)
+
+const query_shelves = `
+	SELECT s.id, s.user_id, s.name, s.created_at, COUNT(sb.book_id) AS book_count
+	FROM shelves s
+	LEFT JOIN shelves_books sb ON sb.shelf_id = s.id
+`
+
+func (p *PostgresBookShelfRepository) CreateShelf(ctx context.Context, userId uuid.UUID, name string) (*models.Shelf, error) {
+	shelf := &models.Shelf{}
+	query := `INSERT INTO shelves (user_id, name) VALUES ($1, $2)
+			  RETURNING id, user_id, name, created_at, 0 AS book_count;`
+	if err := p.c.GetContext(ctx, shelf, query, userId, name); err != nil {
+		return nil, fmt.Errorf("failed to create shelf: %w", err)
+	}
+	return shelf, nil
+}
+
+func (p *PostgresBookShelfRepository) GetShelves(ctx context.Context, userId uuid.UUID) ([]*models.Shelf, error) {
+	shelves := []*models.Shelf{}
+	query := query_shelves + `
+	WHERE s.user_id = $1
+	GROUP BY s.id
+	ORDER BY LOWER(s.name);`
+	if err := p.c.SelectContext(ctx, &shelves, query, userId); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get shelves: %w", err)
+		}
+	}
+	return shelves, nil
+}
+
+// GetShelf only finds the shelf if it belongs to the user.
+func (p *PostgresBookShelfRepository) GetShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID) (*models.Shelf, error) {
+	shelf := &models.Shelf{}
+	query := query_shelves + `
+	WHERE s.user_id = $1 AND s.id = $2
+	GROUP BY s.id;`
+	if err := p.c.GetContext(ctx, shelf, query, userId, shelfId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrShelfNotFound
+		}
+		return nil, fmt.Errorf("failed to get shelf: %w", err)
+	}
+	return shelf, nil
+}
+
+// GetShelfByName finds a shelf of the user ignoring case.
+func (p *PostgresBookShelfRepository) GetShelfByName(ctx context.Context, userId uuid.UUID, name string) (*models.Shelf, error) {
+	shelf := &models.Shelf{}
+	query := query_shelves + `
+	WHERE s.user_id = $1 AND LOWER(s.name) = LOWER($2)
+	GROUP BY s.id;`
+	if err := p.c.GetContext(ctx, shelf, query, userId, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrShelfNotFound
+		}
+		return nil, fmt.Errorf("failed to get shelf: %w", err)
+	}
+	return shelf, nil
+}
+
+func (p *PostgresBookShelfRepository) RenameShelf(ctx context.Context, shelfId uuid.UUID, name string) error {
+	query := `UPDATE shelves SET name=$1 WHERE id=$2;`
+	if _, err := p.c.ExecContext(ctx, query, name, shelfId); err != nil {
+		return fmt.Errorf("failed to rename shelf: %w", err)
+	}
+	return nil
+}
+
+// DeleteShelf removes the shelf, its books are removed by the cascade.
+func (p *PostgresBookShelfRepository) DeleteShelf(ctx context.Context, shelfId uuid.UUID) error {
+	query := `DELETE FROM shelves WHERE id=$1;`
+	if _, err := p.c.ExecContext(ctx, query, shelfId); err != nil {
+		return fmt.Errorf("failed to delete shelf: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresBookShelfRepository) AddBookToCustomShelf(ctx context.Context, shelfId uuid.UUID, bookId uuid.UUID) error {
+	query := `INSERT INTO shelves_books (shelf_id, book_id) VALUES ($1, $2)
+			  ON CONFLICT (shelf_id, book_id) DO NOTHING;`
+	if _, err := p.c.ExecContext(ctx, query, shelfId, bookId); err != nil {
+		return fmt.Errorf("failed to add book to shelf: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresBookShelfRepository) RemoveBookFromCustomShelf(ctx context.Context, shelfId uuid.UUID, bookId uuid.UUID) error {
+	query := `DELETE FROM shelves_books WHERE shelf_id=$1 AND book_id=$2;`
+	if _, err := p.c.ExecContext(ctx, query, shelfId, bookId); err != nil {
+		return fmt.Errorf("failed to remove book from shelf: %w", err)
+	}
+	return nil
+}
+
+// SetBookCustomShelves leaves the book only in the given shelves of the user.
+func (p *PostgresBookShelfRepository) SetBookCustomShelves(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, shelfIds []uuid.UUID) error {
+	tx, err := p.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set shelves of book: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM shelves_books
+			  WHERE book_id=$2 AND shelf_id IN (SELECT id FROM shelves WHERE user_id=$1);`
+	if _, err := tx.ExecContext(ctx, query, userId, bookId); err != nil {
+		return fmt.Errorf("failed to set shelves of book: %w", err)
+	}
+
+	query = `INSERT INTO shelves_books (shelf_id, book_id) VALUES ($1, $2)
+			 ON CONFLICT (shelf_id, book_id) DO NOTHING;`
+	for _, shelfId := range shelfIds {
+		if _, err := tx.ExecContext(ctx, query, shelfId, bookId); err != nil {
+			return fmt.Errorf("failed to set shelves of book: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to set shelves of book: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	booksRepo "github.com/betterreads/internal/domains/books/repository"
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Finished reads of the user, of every year when $2 is null. Reads imported without a finish
+// date only count when there is no year.
+const query_stats_finished = `
+	WITH finished AS (
+		SELECT rt.book_id, rt.started_at, rt.finished_at
+		FROM read_throughs rt
+		WHERE rt.user_id = $1 AND rt.status = 'read'
+			AND ($2::INTEGER IS NULL OR (
+				rt.finished_at >= make_date($2::INTEGER, 1, 1)
+				AND rt.finished_at < make_date($2::INTEGER + 1, 1, 1)
+			))
+	)
+`
+
+const MaxTopAuthors = 5
+
+// GetStats aggregates the stats in the database, every query runs in the same snapshot.
+func (p *PostgresBookShelfRepository) GetStats(ctx context.Context, userId uuid.UUID, year *int) (*models.ReadingStats, error) {
+	tx, err := p.c.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	defer tx.Rollback()
+
+	stats := &models.ReadingStats{UserId: userId, Year: year}
+	query := query_stats_finished + `
+	SELECT
+		COUNT(*) AS books_read,
+		COUNT(DISTINCT f.book_id) AS unique_books,
+		COALESCE(SUM(bk.amount_of_pages), 0) AS pages_read,
+		AVG(f.finished_at - f.started_at) AS avg_days_to_finish
+	FROM finished f
+	JOIN books bk ON bk.id = f.book_id;`
+	if err := tx.GetContext(ctx, stats, query, userId, year); err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	if stats.ByYear, err = getPeriodStats(ctx, tx, userId, year, "YYYY"); err != nil {
+		return nil, err
+	}
+	if stats.ByMonth, err = getPeriodStats(ctx, tx, userId, year, "YYYY-MM"); err != nil {
+		return nil, err
+	}
+
+	stats.Genres = []*models.GenreStats{}
+	query = query_stats_finished + `
+	SELECT gb.genre_id, COUNT(DISTINCT f.book_id) AS books
+	FROM finished f
+	JOIN genres_books gb ON gb.book_id = f.book_id
+	GROUP BY gb.genre_id
+	ORDER BY books DESC, gb.genre_id;`
+	if err := tx.SelectContext(ctx, &stats.Genres, query, userId, year); err != nil {
+		return nil, fmt.Errorf("failed to get genre stats: %w", err)
+	}
+	for _, genre := range stats.Genres {
+		genre.Genre = booksRepo.GetGenre(genre.GenreId)
+	}
+
+	if stats.LongestBook, err = getBookStats(ctx, tx, userId, year, "DESC"); err != nil {
+		return nil, err
+	}
+	if stats.ShortestBook, err = getBookStats(ctx, tx, userId, year, "ASC"); err != nil {
+		return nil, err
+	}
+
+	stats.TopAuthors = []*models.AuthorStats{}
+	query = query_stats_finished + fmt.Sprintf(`
+	SELECT u.id AS author_id, u.username AS author_name, COUNT(DISTINCT f.book_id) AS books
+	FROM finished f
+	JOIN books bk ON bk.id = f.book_id
+	JOIN users u ON u.id = bk.author
+	GROUP BY u.id, u.username
+	ORDER BY books DESC, u.username
+	LIMIT %d;`, MaxTopAuthors)
+	if err := tx.SelectContext(ctx, &stats.TopAuthors, query, userId, year); err != nil {
+		return nil, fmt.Errorf("failed to get author stats: %w", err)
+	}
+
+	// Reviews only have the date they were written
+	ratings := struct {
+		Count int     `db:"ratings_given"`
+		Avg   float64 `db:"avg_rating_given"`
+	}{}
+	query = `
+	SELECT COUNT(*) AS ratings_given, COALESCE(AVG(r.rating), 0) AS avg_rating_given
+	FROM reviews r
+	WHERE r.user_id = $1 AND r.rating > 0
+		AND ($2::INTEGER IS NULL OR LEFT(r.publication_date, 4) = $2::INTEGER::TEXT);`
+	if err := tx.GetContext(ctx, &ratings, query, userId, year); err != nil {
+		return nil, fmt.Errorf("failed to get rating stats: %w", err)
+	}
+	stats.RatingsGiven = ratings.Count
+	stats.AvgRatingGiven = ratings.Avg
+
+	return stats, nil
+}
+
+// Books and pages read grouped by the finish date with the to_char format.
+func getPeriodStats(ctx context.Context, tx *sqlx.Tx, userId uuid.UUID, year *int, format string) ([]*models.PeriodStats, error) {
+	periods := []*models.PeriodStats{}
+	query := query_stats_finished + `
+	SELECT to_char(f.finished_at, $3) AS period, COUNT(*) AS books, COALESCE(SUM(bk.amount_of_pages), 0) AS pages
+	FROM finished f
+	JOIN books bk ON bk.id = f.book_id
+	WHERE f.finished_at IS NOT NULL
+	GROUP BY period
+	ORDER BY period;`
+	if err := tx.SelectContext(ctx, &periods, query, userId, year, format); err != nil {
+		return nil, fmt.Errorf("failed to get stats by period: %w", err)
+	}
+	return periods, nil
+}
+
+// The longest or shortest book read depending on the direction, books without pages are skipped.
+func getBookStats(ctx context.Context, tx *sqlx.Tx, userId uuid.UUID, year *int, direction string) (*models.BookStats, error) {
+	book := &models.BookStats{}
+	query := query_stats_finished + fmt.Sprintf(`
+	SELECT bk.id AS book_id, bk.title, bk.amount_of_pages
+	FROM finished f
+	JOIN books bk ON bk.id = f.book_id
+	WHERE bk.amount_of_pages > 0
+	ORDER BY bk.amount_of_pages %s, bk.title
+	LIMIT 1;`, direction)
+	if err := tx.GetContext(ctx, book, query, userId, year); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get book stats: %w", err)
+	}
+	return book, nil
+}
@@ -1,10 +1,23 @@
 package service
 
 import (
+	"context"
     "errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	bookService "github.com/betterreads/internal/domains/books/service"
+	bookUtils "github.com/betterreads/internal/domains/books/utils"
 	"github.com/betterreads/internal/domains/bookshelf/models"
 	"github.com/betterreads/internal/domains/bookshelf/repository"
+	"github.com/betterreads/internal/domains/bookshelf/utils"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
@@ -17,27 +30,32 @@ func NewBookShelfServiceImpl(r repository.BookshelfDatabase, bs bookService.Book
 	return &BookShelfServiceImpl{r: r, bookService: bs}
 }
 
-func (bs *BookShelfServiceImpl) GetBookShelf(userId uuid.UUID, shelfType string) ([]*models.BookInShelfResponse, error) {
-	userExists := bs.bookService.CheckIfUserExists(userId)
+func (bs *BookShelfServiceImpl) GetBookShelf(ctx context.Context, userId uuid.UUID, shelfType string, shelf string, page pagination.Request) (pagination.Page[*models.BookInShelfResponse], error) {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
 	if !userExists {
-		return nil, ErrUserNotFound
+		return pagination.Page[*models.BookInShelfResponse]{}, ErrUserNotFound
 	}
 
 	status := models.BookShelfType(shelfType)
 	if !validate_status(status) {
-		return nil, ErrInvalidStatusType
+		return pagination.Page[*models.BookInShelfResponse]{}, ErrInvalidStatusType
 	}
 
-	bookShelf, err := bs.r.GetBookShelf(userId, status)
+	shelfId, err := bs.getCustomShelfId(ctx, userId, shelf)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*models.BookInShelfResponse]{}, err
+	}
+
+	bookShelf, err := bs.r.GetBookShelf(ctx, userId, status, shelfId, page)
+	if err != nil {
+		return pagination.Page[*models.BookInShelfResponse]{}, err
 	}
 
 	return bookShelf, nil
 }
 
-func (bs *BookShelfServiceImpl) AddBookToShelf(userId uuid.UUID, req *models.BookShelfRequest) error {
-	userExists := bs.bookService.CheckIfUserExists(userId)
+func (bs *BookShelfServiceImpl) AddBookToShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
 	if !userExists {
 		return ErrUserNotFound
 	}
@@ -47,28 +65,40 @@ func (bs *BookShelfServiceImpl) AddBookToShelf(userId uuid.UUID, req *models.Boo
 		return ErrInvalidStatusType
 	}
 
-	exists := bs.r.CheckIfBookIsInUserShelf(userId, req.BookId)
+	exists := bs.r.CheckIfBookIsInUserShelf(ctx, userId, req.BookId)
 	if exists {
 		return ErrBookAlreadyInLibrary
 	}
 
-	err := bs.r.AddBookToShelf(userId, req)
+	if !bs.bookService.CheckIfBookExists(ctx, req.BookId) {
+		return ErrBookNotFound
+	}
+
+	if err := validateReadChange(status, req.StartedAt, req.FinishedAt, req.AbandonedPage, 0); err != nil {
+		return err
+	}
+
+	err := bs.r.AddBookToShelf(ctx, userId, req)
 	if err != nil {
 		return err
 	}
 
+	metrics.ShelfChanges.WithLabelValues(metrics.ShelfAdd).Inc()
 	return nil
 }
 
-func (bs *BookShelfServiceImpl) EditBookInShelf(userId uuid.UUID, req *models.BookShelfRequest) error {
-	userExists := bs.bookService.CheckIfUserExists(userId)
+func (bs *BookShelfServiceImpl) EditBookInShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
 	if !userExists {
 		return ErrUserNotFound
 	}
 
-	exits := bs.r.CheckIfBookIsInUserShelf(userId, req.BookId)
-	if !exits {
-		return ErrBookNotFoundInLibrary
+	_, pages, err := bs.r.GetBookStatusAndPages(ctx, userId, req.BookId)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookNotInLibrary) {
+			return ErrBookNotFoundInLibrary
+		}
+		return err
 	}
 
 	status := models.BookShelfType(req.Status)
@@ -76,11 +106,19 @@ func (bs *BookShelfServiceImpl) EditBookInShelf(userId uuid.UUID, req *models.Bo
 		return ErrInvalidStatusType
 	}
 
-	err := bs.r.EditBookInShelf(userId, req)
+	if err := validateReadChange(status, req.StartedAt, req.FinishedAt, req.AbandonedPage, pages); err != nil {
+		return err
+	}
+
+	err = bs.r.EditBookInShelf(ctx, userId, req)
 	if err != nil {
+		if errors.Is(err, repository.ErrBookNotInLibrary) {
+			return ErrBookNotFoundInLibrary
+		}
 		return err
 	}
 
+	metrics.ShelfChanges.WithLabelValues(metrics.ShelfEdit).Inc()
 	return nil
 }
 
@@ -94,27 +132,28 @@ func validate_status(status models.BookShelfType) bool {
 }
 
 
-func (bs *BookShelfServiceImpl) DeleteBookFromShelf(userId uuid.UUID, bookId uuid.UUID) error {
-	userExists := bs.bookService.CheckIfUserExists(userId)
+func (bs *BookShelfServiceImpl) DeleteBookFromShelf(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) error {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
 	if !userExists {
 		return ErrUserNotFound
 	}
 
-	exits := bs.r.CheckIfBookIsInUserShelf(userId, bookId)
+	exits := bs.r.CheckIfBookIsInUserShelf(ctx, userId, bookId)
 	if !exits {
 		return ErrBookNotFoundInLibrary
 	}
 
-	err := bs.r.DeleteBookFromShelf(userId, bookId)
+	err := bs.r.DeleteBookFromShelf(ctx, userId, bookId)
 	if err != nil {
 		return err
 	}
 
+	metrics.ShelfChanges.WithLabelValues(metrics.ShelfRemove).Inc()
 	return nil
 }
 
-func ( bs * BookShelfServiceImpl) SearchBookShelf(userId uuid.UUID, shelfType string, genre string, sort string, direction string) ([]*models.BookInShelfResponse, error) {
-    userExists := bs.bookService.CheckIfUserExists(userId)
+func ( bs * BookShelfServiceImpl) SearchBookShelf(ctx context.Context, userId uuid.UUID, shelfType string, shelf string, genre string, sort string, direction string) ([]*models.BookInShelfResponse, error) {
+    userExists := bs.bookService.CheckIfUserExists(ctx, userId)
     if !userExists {
         return nil, ErrUserNotFound
     }
@@ -140,7 +179,12 @@ func ( bs * BookShelfServiceImpl) SearchBookShelf(userId uuid.UUID, shelfType st
 
     isDirAsc := direction == "asc"
 
-    books , err := bs.r.SearchBookShelf(userId, status, genre, sort, isDirAsc)
+	shelfId, err := bs.getCustomShelfId(ctx, userId, shelf)
+	if err != nil {
+		return nil, err
+	}
+
+    books , err := bs.r.SearchBookShelf(ctx, userId, status, shelfId, genre, sort, isDirAsc)
     if err != nil {
         if errors.Is(err, repository.ErrGenreNotFound) {
             return nil, ErrGenreNotFound
@@ -149,3 +193,285 @@ func ( bs * BookShelfServiceImpl) SearchBookShelf(userId uuid.UUID, shelfType st
     }
     return books, nil
 }
+
+func (bs *BookShelfServiceImpl) ImportGoodreads(ctx context.Context, userId uuid.UUID, file io.Reader) (*models.ImportReport, error) {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
+	if !userExists {
+		return nil, ErrUserNotFound
+	}
+
+	rows, rowErrors, err := utils.ParseGoodreadsCSV(file)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidGoodreadsCSV) {
+			return nil, ErrInvalidGoodreadsCSV
+		}
+		return nil, err
+	}
+
+	report := &models.ImportReport{Rows: []models.ImportRowResult{}}
+	for line, err := range rowErrors {
+		report.Add(models.ImportRowResult{Row: line, Status: models.ImportRowFailed, Reason: err.Error()})
+	}
+	for _, row := range rows {
+		report.Add(bs.importGoodreadsRow(ctx, userId, row))
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Row < report.Rows[j].Row
+	})
+
+	return report, nil
+}
+
+func (bs *BookShelfServiceImpl) importGoodreadsRow(ctx context.Context, userId uuid.UUID, row models.GoodreadsRow) models.ImportRowResult {
+	result := models.ImportRowResult{Row: row.Row, Title: row.Title}
+
+	status, ok := models.GoodreadsShelves[row.Shelf]
+	if !ok {
+		result.Status = models.ImportRowSkipped
+		result.Reason = fmt.Sprintf("shelf %q can't be imported", row.Shelf)
+		return result
+	}
+
+	rating, err := strconv.Atoi(row.Rating)
+	if err != nil || rating < 0 || rating > 5 {
+		result.Status = models.ImportRowFailed
+		result.Reason = fmt.Sprintf("invalid rating %q", row.Rating)
+		return result
+	}
+
+	isbns := []string{}
+	for _, isbn := range []string{row.ISBN13, row.ISBN} {
+		if normalized, ok := bookUtils.NormalizeISBN(isbn); ok {
+			isbns = append(isbns, normalized)
+		}
+	}
+	titles := []string{strings.ToLower(row.Title), strings.ToLower(utils.RemoveSeriesSuffix(row.Title))}
+
+	bookId, err := bs.r.FindBookToImport(ctx, isbns, titles, row.Author)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookNotFoundInLibrary) {
+			result.Status = models.ImportRowSkipped
+			result.Reason = "book not found"
+		} else {
+			result.Status = models.ImportRowFailed
+			result.Reason = "failed to find book"
+		}
+		return result
+	}
+	result.BookId = &bookId
+
+	date := row.DateAdded
+	if date == "" {
+		date = time.Now().Format(time.DateOnly)
+	}
+
+	entry := &models.ImportEntry{
+		BookId: bookId,
+		Status: status,
+		Date:   date,
+		Rating: rating,
+		Review: row.Review,
+	}
+	// Goodreads only knows when a book was finished
+	if status != models.BookShelfTypeReading && row.DateRead != "" {
+		entry.FinishedAt = &row.DateRead
+	}
+	created, err := bs.r.ImportBookToShelf(ctx, userId, entry)
+	if err != nil {
+		result.Status = models.ImportRowFailed
+		result.Reason = "failed to save book in shelf"
+		return result
+	}
+
+	for _, name := range row.Shelves {
+		if err := bs.importToCustomShelf(ctx, userId, name, bookId); err != nil {
+			result.Status = models.ImportRowFailed
+			result.Reason = fmt.Sprintf("failed to add book to shelf %q", name)
+			return result
+		}
+	}
+
+	if created {
+		result.Status = models.ImportRowCreated
+	} else {
+		result.Status = models.ImportRowMatched
+	}
+	return result
+}
+
+// Adds the book to the shelf with that name, creating it if the user doesn't have it.
+func (bs *BookShelfServiceImpl) importToCustomShelf(ctx context.Context, userId uuid.UUID, name string, bookId uuid.UUID) error {
+	shelf, err := bs.r.GetShelfByName(ctx, userId, name)
+	if errors.Is(err, repository.ErrShelfNotFound) {
+		name, err = validateShelfName(name)
+		if err != nil {
+			return err
+		}
+		shelf, err = bs.r.CreateShelf(ctx, userId, name)
+	}
+	if err != nil {
+		return err
+	}
+	return bs.r.AddBookToCustomShelf(ctx, shelf.Id, bookId)
+}
+
+func (bs *BookShelfServiceImpl) ExportLibrary(ctx context.Context, userId uuid.UUID, format string, w io.Writer) error {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
+	if !userExists {
+		return ErrUserNotFound
+	}
+
+	var writer utils.LibraryWriter
+	var err error
+	switch format {
+	case models.ExportFormatCSV:
+		writer, err = utils.NewGoodreadsWriter(w)
+	case models.ExportFormatJSON:
+		writer, err = utils.NewJSONWriter(w, userId)
+	default:
+		return ErrInvalidExportFormat
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := bs.r.ExportLibrary(ctx, userId, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// Returns the id of the custom shelf to filter by, nil when there is no filter.
+func (bs *BookShelfServiceImpl) getCustomShelfId(ctx context.Context, userId uuid.UUID, name string) (*uuid.UUID, error) {
+	if name == "" {
+		return nil, nil
+	}
+	shelf, err := bs.r.GetShelfByName(ctx, userId, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrShelfNotFound) {
+			return nil, ErrShelfNotFound
+		}
+		return nil, err
+	}
+	return &shelf.Id, nil
+}
+
+func (bs *BookShelfServiceImpl) GetShelves(ctx context.Context, userId uuid.UUID) ([]*models.Shelf, error) {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
+	if !userExists {
+		return nil, ErrUserNotFound
+	}
+
+	return bs.r.GetShelves(ctx, userId)
+}
+
+func (bs *BookShelfServiceImpl) CreateShelf(ctx context.Context, userId uuid.UUID, req *models.ShelfRequest) (*models.Shelf, error) {
+	userExists := bs.bookService.CheckIfUserExists(ctx, userId)
+	if !userExists {
+		return nil, ErrUserNotFound
+	}
+
+	name, err := validateShelfName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := bs.r.GetShelfByName(ctx, userId, name); err == nil {
+		return nil, ErrShelfAlreadyExists
+	} else if !errors.Is(err, repository.ErrShelfNotFound) {
+		return nil, err
+	}
+
+	return bs.r.CreateShelf(ctx, userId, name)
+}
+
+func (bs *BookShelfServiceImpl) RenameShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID, req *models.ShelfRequest) (*models.Shelf, error) {
+	shelf, err := bs.getShelf(ctx, userId, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := validateShelfName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := bs.r.GetShelfByName(ctx, userId, name); err == nil && existing.Id != shelfId {
+		return nil, ErrShelfAlreadyExists
+	} else if err != nil && !errors.Is(err, repository.ErrShelfNotFound) {
+		return nil, err
+	}
+
+	if err := bs.r.RenameShelf(ctx, shelfId, name); err != nil {
+		return nil, err
+	}
+	shelf.Name = name
+	return shelf, nil
+}
+
+func (bs *BookShelfServiceImpl) DeleteShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID) error {
+	if _, err := bs.getShelf(ctx, userId, shelfId); err != nil {
+		return err
+	}
+
+	return bs.r.DeleteShelf(ctx, shelfId)
+}
+
+func (bs *BookShelfServiceImpl) AddBookToCustomShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID, bookId uuid.UUID) error {
+	if _, err := bs.getShelf(ctx, userId, shelfId); err != nil {
+		return err
+	}
+
+	if !bs.r.CheckIfBookIsInUserShelf(ctx, userId, bookId) {
+		return ErrBookNotFoundInLibrary
+	}
+
+	return bs.r.AddBookToCustomShelf(ctx, shelfId, bookId)
+}
+
+func (bs *BookShelfServiceImpl) RemoveBookFromCustomShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID, bookId uuid.UUID) error {
+	if _, err := bs.getShelf(ctx, userId, shelfId); err != nil {
+		return err
+	}
+
+	return bs.r.RemoveBookFromCustomShelf(ctx, shelfId, bookId)
+}
+
+func (bs *BookShelfServiceImpl) SetBookCustomShelves(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.BookShelvesRequest) error {
+	if !bs.r.CheckIfBookIsInUserShelf(ctx, userId, bookId) {
+		return ErrBookNotFoundInLibrary
+	}
+
+	for _, shelfId := range req.Shelves {
+		if _, err := bs.getShelf(ctx, userId, shelfId); err != nil {
+			return err
+		}
+	}
+
+	return bs.r.SetBookCustomShelves(ctx, userId, bookId, req.Shelves)
+}
+
+func (bs *BookShelfServiceImpl) getShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID) (*models.Shelf, error) {
+	shelf, err := bs.r.GetShelf(ctx, userId, shelfId)
+	if err != nil {
+		if errors.Is(err, repository.ErrShelfNotFound) {
+			return nil, ErrShelfNotFound
+		}
+		return nil, err
+	}
+	return shelf, nil
+}
+
+// Names are trimmed and can't be a reading status, so filters by status and shelf can't be confused.
+func validateShelfName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxShelfNameLength {
+		return "", ErrInvalidShelfName
+	}
+	for _, status := range models.ValidBookShelfTypes {
+		if strings.EqualFold(name, string(status)) {
+			return "", ErrInvalidShelfName
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/repository"
+	"github.com/google/uuid"
+)
+
+const MinYear = 1900
+
+func (bs *BookShelfServiceImpl) GetGoals(ctx context.Context, userId uuid.UUID) ([]*models.ReadingGoal, error) {
+	if !bs.bookService.CheckIfUserExists(ctx, userId) {
+		return nil, ErrUserNotFound
+	}
+
+	goals, err := bs.r.GetGoals(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, goal := range goals {
+		calculateGoalProgress(goal, now)
+	}
+	return goals, nil
+}
+
+func (bs *BookShelfServiceImpl) GetGoal(ctx context.Context, userId uuid.UUID, year string) (*models.ReadingGoal, error) {
+	if !bs.bookService.CheckIfUserExists(ctx, userId) {
+		return nil, ErrUserNotFound
+	}
+
+	goalYear, err := parseYear(year)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs.getGoal(ctx, userId, goalYear)
+}
+
+// SetGoal creates the goal of the year or replaces it.
+func (bs *BookShelfServiceImpl) SetGoal(ctx context.Context, userId uuid.UUID, year string, req *models.GoalRequest) (*models.ReadingGoal, error) {
+	goalYear, err := parseYear(year)
+	if err != nil {
+		return nil, err
+	}
+
+	goalType := models.GoalType(req.Type)
+	if (goalType != models.GoalTypeBooks && goalType != models.GoalTypePages) || req.Target <= 0 {
+		return nil, ErrInvalidGoal
+	}
+
+	if err := bs.r.SetGoal(ctx, userId, goalYear, goalType, req.Target); err != nil {
+		return nil, err
+	}
+
+	return bs.getGoal(ctx, userId, goalYear)
+}
+
+func (bs *BookShelfServiceImpl) DeleteGoal(ctx context.Context, userId uuid.UUID, year string) error {
+	goalYear, err := parseYear(year)
+	if err != nil {
+		return err
+	}
+
+	if _, err := bs.getGoal(ctx, userId, goalYear); err != nil {
+		return err
+	}
+
+	return bs.r.DeleteGoal(ctx, userId, goalYear)
+}
+
+// GetGoalBooks returns the books that count for the goal of the year.
+func (bs *BookShelfServiceImpl) GetGoalBooks(ctx context.Context, userId uuid.UUID, year string) ([]*models.GoalBook, error) {
+	if !bs.bookService.CheckIfUserExists(ctx, userId) {
+		return nil, ErrUserNotFound
+	}
+
+	goalYear, err := parseYear(year)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := bs.getGoal(ctx, userId, goalYear); err != nil {
+		return nil, err
+	}
+
+	return bs.r.GetGoalBooks(ctx, userId, goalYear)
+}
+
+func (bs *BookShelfServiceImpl) getGoal(ctx context.Context, userId uuid.UUID, year int) (*models.ReadingGoal, error) {
+	goal, err := bs.r.GetGoal(ctx, userId, year)
+	if err != nil {
+		if errors.Is(err, repository.ErrGoalNotFound) {
+			return nil, ErrGoalNotFound
+		}
+		return nil, err
+	}
+
+	calculateGoalProgress(goal, time.Now())
+	return goal, nil
+}
+
+func parseYear(year string) (int, error) {
+	goalYear, err := strconv.Atoi(year)
+	if err != nil || goalYear < MinYear || goalYear > time.Now().Year()+1 {
+		return 0, ErrInvalidYear
+	}
+	return goalYear, nil
+}
+
+// calculateGoalProgress compares what was read with what should have been read by now, reading
+// at the same pace the whole year. Past goals expect the target and future ones nothing.
+func calculateGoalProgress(goal *models.ReadingGoal, now time.Time) {
+	goal.Completed = goal.Books
+	if goal.Type == models.GoalTypePages {
+		goal.Completed = goal.Pages
+	}
+	if goal.Target > 0 {
+		goal.Percent = math.Round(float64(goal.Completed)*10000/float64(goal.Target)) / 100
+	}
+
+	switch {
+	case goal.Year < now.Year():
+		goal.Expected = goal.Target
+	case goal.Year > now.Year():
+		goal.Expected = 0
+	default:
+		start := time.Date(goal.Year, time.January, 1, 0, 0, 0, 0, now.Location())
+		end := start.AddDate(1, 0, 0)
+		elapsed := float64(now.Sub(start)) / float64(end.Sub(start))
+		goal.Expected = int(float64(goal.Target) * elapsed)
+	}
+	goal.Difference = goal.Completed - goal.Expected
+
+	switch {
+	case goal.Completed >= goal.Target:
+		goal.Schedule = models.GoalCompleted
+	case goal.Difference > 0:
+		goal.Schedule = models.GoalAhead
+	case goal.Difference == 0:
+		goal.Schedule = models.GoalOnTrack
+	default:
+		goal.Schedule = models.GoalBehind
+	}
+}
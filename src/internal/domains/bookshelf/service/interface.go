@@ -1,27 +1,95 @@
 package service
 
 import (
+	"context"
+
 	"errors"
+	"io"
+
 	"github.com/betterreads/internal/domains/bookshelf/models"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
+const MaxShelfNameLength = 100
+
 var (
 	ErrBookNotFoundInLibrary = errors.New("book not found")
+	ErrBookNotFound          = errors.New("book doesn't exist or was deleted")
 	ErrBookAlreadyInLibrary  = errors.New("book already in library")
 	ErrUserNotFound          = errors.New("user not found")
 	ErrInvalidStatusType     = er.ErrorParam{
 		Name:   "status",
-		Reason: "status should be: 'plan-to-read', 'reading', 'read' or 'all",
+		Reason: "status should be: 'plan-to-read', 'reading', 'read', 'did-not-finish' or 'all",
 	}
     ErrGenreNotFound         = errors.New("genre not found")
+	ErrInvalidGoodreadsCSV   = errors.New("file is not a Goodreads library export")
+	ErrShelfNotFound         = errors.New("shelf not found")
+	ErrShelfAlreadyExists    = errors.New("shelf already exists")
+	ErrInvalidShelfName      = er.ErrorParam{
+		Name:   "name",
+		Reason: "name should have between 1 and 100 characters and can't be a status",
+	}
+	ErrInvalidExportFormat   = er.ErrorParam{
+		Name:   "format",
+		Reason: "format should be: 'csv' or 'json'",
+	}
+	ErrBookNotReading        = errors.New("book is not in the reading shelf")
+	ErrInvalidProgress       = er.ErrorParam{
+		Name:   "progress",
+		Reason: "send either page, between 0 and the pages of the book, or percent, between 0 and 100",
+	}
+	ErrReadThroughNotFound   = errors.New("read not found")
+	ErrReadThroughInProgress = errors.New("the read in progress changes with the status of the book")
+	ErrInvalidReadDates      = er.ErrorParam{
+		Name:   "dates",
+		Reason: "dates should be YYYY-MM-DD, not in the future and a read can't finish before it starts",
+	}
+	ErrInvalidReadStatus     = er.ErrorParam{
+		Name:   "status",
+		Reason: "status of a finished read should be: 'read' or 'did-not-finish'",
+	}
+	ErrInvalidAbandonedPage  = er.ErrorParam{
+		Name:   "abandoned_page",
+		Reason: "abandoned page should be between 0 and the pages of the book, only for 'did-not-finish'",
+	}
+	ErrGoalNotFound          = errors.New("goal not found")
+	ErrInvalidYear           = er.ErrorParam{
+		Name:   "year",
+		Reason: "year should be a number between 1900 and next year",
+	}
+	ErrInvalidGoal           = er.ErrorParam{
+		Name:   "goal",
+		Reason: "type should be: 'books' or 'pages', and target a number greater than 0",
+	}
 )
 
 type BookshelfService interface {
-	GetBookShelf(usedId uuid.UUID, shelfType string) ([]*models.BookInShelfResponse, error)
-	AddBookToShelf(userId uuid.UUID, req *models.BookShelfRequest) error
-	EditBookInShelf(userId uuid.UUID, req *models.BookShelfRequest) error
-	DeleteBookFromShelf(userId uuid.UUID, bookId uuid.UUID) error
-    SearchBookShelf(userId uuid.UUID, shelfType string, genre string, sort string, direction string) ([]*models.BookInShelfResponse, error)
+	GetBookShelf(ctx context.Context, usedId uuid.UUID, shelfType string, shelf string, page pagination.Request) (pagination.Page[*models.BookInShelfResponse], error)
+	AddBookToShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error
+	EditBookInShelf(ctx context.Context, userId uuid.UUID, req *models.BookShelfRequest) error
+	DeleteBookFromShelf(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) error
+    SearchBookShelf(ctx context.Context, userId uuid.UUID, shelfType string, shelf string, genre string, sort string, direction string) ([]*models.BookInShelfResponse, error)
+	ImportGoodreads(ctx context.Context, userId uuid.UUID, file io.Reader) (*models.ImportReport, error)
+	ExportLibrary(ctx context.Context, userId uuid.UUID, format string, w io.Writer) error
+	GetShelves(ctx context.Context, userId uuid.UUID) ([]*models.Shelf, error)
+	CreateShelf(ctx context.Context, userId uuid.UUID, req *models.ShelfRequest) (*models.Shelf, error)
+	RenameShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID, req *models.ShelfRequest) (*models.Shelf, error)
+	DeleteShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID) error
+	AddBookToCustomShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID, bookId uuid.UUID) error
+	RemoveBookFromCustomShelf(ctx context.Context, userId uuid.UUID, shelfId uuid.UUID, bookId uuid.UUID) error
+	SetBookCustomShelves(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.BookShelvesRequest) error
+	AddProgress(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.ProgressRequest) (*models.ReadingProgress, error)
+	GetProgressHistory(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadingProgress, error)
+	GetReadThroughs(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadThrough, error)
+	AddReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error)
+	UpdateReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error)
+	DeleteReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID) error
+	GetGoals(ctx context.Context, userId uuid.UUID) ([]*models.ReadingGoal, error)
+	GetGoal(ctx context.Context, userId uuid.UUID, year string) (*models.ReadingGoal, error)
+	SetGoal(ctx context.Context, userId uuid.UUID, year string, req *models.GoalRequest) (*models.ReadingGoal, error)
+	DeleteGoal(ctx context.Context, userId uuid.UUID, year string) error
+	GetGoalBooks(ctx context.Context, userId uuid.UUID, year string) ([]*models.GoalBook, error)
+	GetStats(ctx context.Context, userId uuid.UUID, year string) (*models.ReadingStats, error)
 }
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/repository"
+	"github.com/google/uuid"
+)
+
+// AddProgress only accepts updates of books in the reading shelf, reaching 100% moves the book to read.
+func (bs *BookShelfServiceImpl) AddProgress(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.ProgressRequest) (*models.ReadingProgress, error) {
+	status, pages, err := bs.r.GetBookStatusAndPages(ctx, userId, bookId)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookNotInLibrary) {
+			return nil, ErrBookNotFoundInLibrary
+		}
+		return nil, err
+	}
+	if status != models.BookShelfTypeReading {
+		return nil, ErrBookNotReading
+	}
+
+	page, percent, err := calculateProgress(req, pages)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs.r.AddProgress(ctx, userId, bookId, page, percent, strings.TrimSpace(req.Note), percent >= 100)
+}
+
+func (bs *BookShelfServiceImpl) GetProgressHistory(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadingProgress, error) {
+	if !bs.r.CheckIfBookIsInUserShelf(ctx, userId, bookId) {
+		return nil, ErrBookNotFoundInLibrary
+	}
+
+	return bs.r.GetProgressHistory(ctx, userId, bookId)
+}
+
+// calculateProgress fills the page or the percent that wasn't sent using the pages of the book.
+// Books without pages can only be updated by percent, and then the page is always 0.
+func calculateProgress(req *models.ProgressRequest, pages int) (int, float64, error) {
+	if (req.Page == nil) == (req.Percent == nil) {
+		return 0, 0, ErrInvalidProgress
+	}
+
+	if req.Page != nil {
+		page := *req.Page
+		if pages <= 0 || page < 0 || page > pages {
+			return 0, 0, ErrInvalidProgress
+		}
+		return page, roundPercent(float64(page) * 100 / float64(pages)), nil
+	}
+
+	percent := *req.Percent
+	if math.IsNaN(percent) || percent < 0 || percent > 100 {
+		return 0, 0, ErrInvalidProgress
+	}
+	page := 0
+	if pages > 0 {
+		page = int(math.Round(percent * float64(pages) / 100))
+	}
+	return page, roundPercent(percent), nil
+}
+
+// Percents are stored with two decimals
+func roundPercent(percent float64) float64 {
+	return math.Round(percent*100) / 100
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/betterreads/internal/domains/bookshelf/repository"
+	"github.com/google/uuid"
+)
+
+func (bs *BookShelfServiceImpl) GetReadThroughs(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) ([]*models.ReadThrough, error) {
+	if !bs.r.CheckIfBookIsInUserShelf(ctx, userId, bookId) {
+		return nil, ErrBookNotFoundInLibrary
+	}
+
+	return bs.r.GetReadThroughs(ctx, userId, bookId)
+}
+
+// AddReadThrough logs a finished read of a book in the bookshelf, it doesn't change its status.
+func (bs *BookShelfServiceImpl) AddReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error) {
+	pages, err := bs.getBookPages(ctx, userId, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateReadThroughRequest(req, pages); err != nil {
+		return nil, err
+	}
+
+	return bs.r.AddReadThrough(ctx, userId, bookId, req)
+}
+
+// UpdateReadThrough corrects a finished read, the open one is changed with the status of the book.
+func (bs *BookShelfServiceImpl) UpdateReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID, req *models.ReadThroughRequest) (*models.ReadThrough, error) {
+	pages, err := bs.getBookPages(ctx, userId, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	read, err := bs.getReadThrough(ctx, userId, bookId, readId)
+	if err != nil {
+		return nil, err
+	}
+	if read.Status == models.BookShelfTypeReading {
+		return nil, ErrReadThroughInProgress
+	}
+
+	if err := validateReadThroughRequest(req, pages); err != nil {
+		return nil, err
+	}
+
+	return bs.r.UpdateReadThrough(ctx, userId, bookId, readId, req)
+}
+
+// DeleteReadThrough removes a read with its progress updates, the status of the book is kept.
+func (bs *BookShelfServiceImpl) DeleteReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID) error {
+	if _, err := bs.getReadThrough(ctx, userId, bookId, readId); err != nil {
+		return err
+	}
+
+	return bs.r.DeleteReadThrough(ctx, readId)
+}
+
+func (bs *BookShelfServiceImpl) getBookPages(ctx context.Context, userId uuid.UUID, bookId uuid.UUID) (int, error) {
+	_, pages, err := bs.r.GetBookStatusAndPages(ctx, userId, bookId)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookNotInLibrary) {
+			return 0, ErrBookNotFoundInLibrary
+		}
+		return 0, err
+	}
+	return pages, nil
+}
+
+func (bs *BookShelfServiceImpl) getReadThrough(ctx context.Context, userId uuid.UUID, bookId uuid.UUID, readId uuid.UUID) (*models.ReadThrough, error) {
+	read, err := bs.r.GetReadThrough(ctx, userId, bookId, readId)
+	if err != nil {
+		if errors.Is(err, repository.ErrReadThroughNotFound) {
+			return nil, ErrReadThroughNotFound
+		}
+		return nil, err
+	}
+	return read, nil
+}
+
+func validateReadThroughRequest(req *models.ReadThroughRequest, pages int) error {
+	status := models.BookShelfType(req.Status)
+	if status != models.BookShelfTypeRead && status != models.BookShelfTypeDNF {
+		return ErrInvalidReadStatus
+	}
+	return validateReadChange(status, req.StartedAt, req.FinishedAt, req.AbandonedPage, pages)
+}
+
+// validateReadChange checks the optional dates and abandoned page sent with a status, pages
+// is 0 when the pages of the book are unknown.
+func validateReadChange(status models.BookShelfType, startedAt string, finishedAt string, abandonedPage *int, pages int) error {
+	today := time.Now()
+	var started, finished time.Time
+	var err error
+	if startedAt != "" {
+		if started, err = time.Parse(time.DateOnly, startedAt); err != nil || started.After(today) {
+			return ErrInvalidReadDates
+		}
+	}
+	if finishedAt != "" {
+		if finished, err = time.Parse(time.DateOnly, finishedAt); err != nil || finished.After(today) {
+			return ErrInvalidReadDates
+		}
+		if startedAt != "" && finished.Before(started) {
+			return ErrInvalidReadDates
+		}
+	}
+
+	if abandonedPage != nil {
+		if status != models.BookShelfTypeDNF || *abandonedPage < 0 || (pages > 0 && *abandonedPage > pages) {
+			return ErrInvalidAbandonedPage
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+)
+
+// GetStats returns the stats of every year, or only of one when a year is given. The stats are
+// as visible as the bookshelf they come from.
+func (bs *BookShelfServiceImpl) GetStats(ctx context.Context, userId uuid.UUID, year string) (*models.ReadingStats, error) {
+	if !bs.bookService.CheckIfUserExists(ctx, userId) {
+		return nil, ErrUserNotFound
+	}
+
+	var statsYear *int
+	if year != "" {
+		parsed, err := parseYear(year)
+		if err != nil {
+			return nil, err
+		}
+		statsYear = &parsed
+	}
+
+	stats, err := bs.r.GetStats(ctx, userId, statsYear)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.AvgDaysToFinish != nil {
+		days := math.Round(*stats.AvgDaysToFinish*10) / 10
+		stats.AvgDaysToFinish = &days
+	}
+	stats.AvgRatingGiven = math.Round(stats.AvgRatingGiven*100) / 100
+	for _, genre := range stats.Genres {
+		if stats.UniqueBooks > 0 {
+			genre.Percent = math.Round(float64(genre.Books)*10000/float64(stats.UniqueBooks)) / 100
+		}
+	}
+	return stats, nil
+}
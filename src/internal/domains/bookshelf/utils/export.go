@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	bookUtils "github.com/betterreads/internal/domains/books/utils"
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+)
+
+// LibraryWriter writes the entries of an export as they are read from the database.
+type LibraryWriter interface {
+	Write(entry *models.ExportEntry) error
+	Close() error
+}
+
+var goodreadsColumns = []string{
+	"Book Id", "Title", "Author", "Author l-f", "Additional Authors", "ISBN", "ISBN13",
+	"My Rating", "Average Rating", "Publisher", "Binding", "Number of Pages", "Year Published",
+	"Original Publication Year", "Date Read", "Date Added", "Bookshelves", "Bookshelves with positions",
+	"Exclusive Shelf", "My Review", "Spoiler", "Private Notes", "Read Count", "Owned Copies",
+}
+
+// Spreadsheets take cells that start with these as formulas
+const formulaPrefixes = "=+-@"
+
+type goodreadsWriter struct {
+	w *csv.Writer
+}
+
+// NewGoodreadsWriter writes the export with the same columns as the Goodreads library
+// export, so it can be imported back there or in Betterreads.
+func NewGoodreadsWriter(w io.Writer) (LibraryWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(goodreadsColumns); err != nil {
+		return nil, err
+	}
+	return &goodreadsWriter{w: writer}, nil
+}
+
+func (g *goodreadsWriter) Write(entry *models.ExportEntry) error {
+	shelf := models.ExclusiveGoodreadsShelf(entry.Status)
+	dateAdded := entry.DateAdded
+	if dateAdded == "" {
+		dateAdded = entry.ReviewDate
+	}
+	dateRead := goodreadsDate(entry.DateRead)
+	readCount := fmt.Sprint(entry.ReadCount)
+	// Reviewed books that are not in the shelf were read at least once
+	if shelf == "read" && entry.Status == "" {
+		dateRead = goodreadsDate(dateAdded)
+		readCount = "1"
+	}
+
+	isbn13 := ""
+	isbn10 := ""
+	if entry.ISBN != nil {
+		isbn13 = *entry.ISBN
+		isbn10, _ = bookUtils.ISBN13To10(isbn13)
+	}
+
+	return g.w.Write([]string{
+		entry.BookId.String(),
+		spreadsheetText(entry.Title),
+		spreadsheetText(entry.AuthorFullName),
+		spreadsheetText(authorLastFirst(entry.AuthorFirstName, entry.AuthorLastName)),
+		"",
+		goodreadsISBN(isbn10),
+		goodreadsISBN(isbn13),
+		fmt.Sprint(entry.UserRating),
+		fmt.Sprintf("%.2f", entry.AvgRating),
+		"",
+		"",
+		fmt.Sprint(entry.AmountOfPages),
+		publicationYear(entry.PublicationDate),
+		publicationYear(entry.PublicationDate),
+		dateRead,
+		goodreadsDate(dateAdded),
+		spreadsheetText(strings.Join(entry.CustomShelves, ", ")),
+		"",
+		shelf,
+		spreadsheetText(strings.ReplaceAll(entry.UserReview, "\n", "<br/>")),
+		"",
+		"",
+		readCount,
+		"0",
+	})
+}
+
+func (g *goodreadsWriter) Close() error {
+	g.w.Flush()
+	return g.w.Error()
+}
+
+type jsonWriter struct {
+	w       io.Writer
+	encoder *json.Encoder
+	first   bool
+}
+
+// NewJSONWriter writes the export as a single JSON document with the entries in "books".
+func NewJSONWriter(w io.Writer, userId uuid.UUID) (LibraryWriter, error) {
+	header, err := json.Marshal(userId)
+	if err != nil {
+		return nil, err
+	}
+	exportedAt, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, `{"user_id":%s,"exported_at":%s,"books":[`, header, exportedAt); err != nil {
+		return nil, err
+	}
+	return &jsonWriter{w: w, encoder: json.NewEncoder(w), first: true}, nil
+}
+
+func (j *jsonWriter) Write(entry *models.ExportEntry) error {
+	if !j.first {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.first = false
+	return j.encoder.Encode(entry)
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
+
+// Goodreads wants ="..." so spreadsheets keep the leading zeros.
+func goodreadsISBN(isbn string) string {
+	return `="` + isbn + `"`
+}
+
+// spreadsheetText keeps the text users write from running as a formula when the export is opened
+// in a spreadsheet, the ' makes it text. The import removes it.
+func spreadsheetText(text string) string {
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func goodreadsDate(date string) string {
+	return strings.ReplaceAll(date, "-", "/")
+}
+
+func authorLastFirst(firstName string, lastName string) string {
+	if lastName == "" {
+		return firstName
+	}
+	return lastName + ", " + firstName
+}
+
+func publicationYear(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	for _, r := range date[:4] {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return date[:4]
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+	"github.com/google/uuid"
+)
+
+func TestGoodreadsWriterFormulas(t *testing.T) {
+	isbn := "9780439023481"
+	entry := &models.ExportEntry{
+		BookId:         uuid.New(),
+		Title:          `=HYPERLINK("http://evil.test","Click")`,
+		AuthorFullName: "@SUM(A1:A2)",
+		AuthorLastName: "+Collins",
+		ISBN:           &isbn,
+		Status:         "read",
+		DateAdded:      "2024-05-01",
+		UserReview:     "-2+3\nloved it",
+		CustomShelves:  []string{"-owned", "favorites"},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewGoodreadsWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cell := range records[1] {
+		column := goodreadsColumns[i]
+		if column == "ISBN" || column == "ISBN13" {
+			continue
+		}
+		if cell != "" && cell[0] != '\'' && slices.Contains([]byte(formulaPrefixes), cell[0]) {
+			t.Errorf("%s is a formula: %s", column, cell)
+		}
+	}
+
+	// The import gets back what was exported
+	rows, rowErrors, err := ParseGoodreadsCSV(bytes.NewReader(buf.Bytes()))
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatal(err, rowErrors)
+	}
+	row := rows[0]
+	if row.Title != entry.Title || row.Author != entry.AuthorFullName || row.Review != entry.UserReview {
+		t.Errorf("imported %+v", row)
+	}
+	if !slices.Equal(row.Shelves, entry.CustomShelves) {
+		t.Errorf("imported the shelves %v", row.Shelves)
+	}
+	if row.ISBN13 != isbn {
+		t.Errorf("imported the ISBN %s", row.ISBN13)
+	}
+}
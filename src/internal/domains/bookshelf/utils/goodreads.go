@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/betterreads/internal/domains/bookshelf/models"
+)
+
+var (
+	ErrInvalidGoodreadsCSV = errors.New("file is not a Goodreads library export")
+	ErrInvalidGoodreadsRow = errors.New("row has fewer columns than the header")
+)
+
+const goodreadsDateLayout = "2006/01/02"
+
+var goodreadsRequiredColumns = []string{"Title", "Author", "ISBN", "ISBN13", "My Rating", "Exclusive Shelf", "Date Added"}
+
+// ParseGoodreadsCSV reads the library export of Goodreads. Rows that can't be read
+// are returned with an error for the row instead of stopping the whole import.
+func ParseGoodreadsCSV(file io.Reader) ([]models.GoodreadsRow, map[int]error, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidGoodreadsCSV, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range goodreadsRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %s", ErrInvalidGoodreadsCSV, name)
+		}
+	}
+
+	rows := []models.GoodreadsRow{}
+	rowErrors := map[int]error{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors[parseErr.StartLine] = err
+				continue
+			}
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidGoodreadsCSV, err)
+		}
+		if len(record) < len(header) {
+			rowErrors[line] = ErrInvalidGoodreadsRow
+			continue
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			return cleanSpreadsheetText(strings.TrimSpace(record[i]))
+		}
+
+		dateRead, errRead := parseGoodreadsDate(field("Date Read"))
+		dateAdded, errAdded := parseGoodreadsDate(field("Date Added"))
+		if err := errors.Join(errRead, errAdded); err != nil {
+			rowErrors[line] = err
+			continue
+		}
+
+		rows = append(rows, models.GoodreadsRow{
+			Row:       line,
+			Title:     field("Title"),
+			Author:    strings.Join(strings.Fields(field("Author")), " "),
+			ISBN:      cleanGoodreadsISBN(field("ISBN")),
+			ISBN13:    cleanGoodreadsISBN(field("ISBN13")),
+			Rating:    field("My Rating"),
+			Shelf:     field("Exclusive Shelf"),
+			DateRead:  dateRead,
+			DateAdded: dateAdded,
+			Review:    cleanGoodreadsReview(field("My Review")),
+			Shelves:   parseGoodreadsShelves(field("Bookshelves")),
+		})
+	}
+
+	return rows, rowErrors, nil
+}
+
+// Bookshelves has the custom shelves separated by commas, it can include the exclusive one.
+func parseGoodreadsShelves(shelves string) []string {
+	res := []string{}
+	for _, shelf := range strings.Split(shelves, ",") {
+		shelf = strings.TrimSpace(shelf)
+		if _, exclusive := models.GoodreadsShelves[shelf]; shelf == "" || exclusive {
+			continue
+		}
+		res = append(res, shelf)
+	}
+	return res
+}
+
+// Goodreads writes ISBNs as ="0439023483" so spreadsheets keep the leading zeros.
+func cleanGoodreadsISBN(isbn string) string {
+	return strings.Trim(strings.TrimPrefix(isbn, "="), `"`)
+}
+
+// The export writes a ' before the text that spreadsheets would take as a formula.
+func cleanSpreadsheetText(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(text[1])) {
+		return text[1:]
+	}
+	return text
+}
+
+// Reviews are exported with html line breaks.
+func cleanGoodreadsReview(review string) string {
+	replacer := strings.NewReplacer("<br/>", "\n", "<br />", "\n", "<br>", "\n")
+	return strings.TrimSpace(replacer.Replace(review))
+}
+
+func parseGoodreadsDate(date string) (string, error) {
+	if date == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse(goodreadsDateLayout, date)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", date)
+	}
+	return parsed.Format(time.DateOnly), nil
+}
+
+// RemoveSeriesSuffix removes the series Goodreads adds to titles, "Catching Fire (The Hunger Games, #2)"
+// becomes "Catching Fire".
+func RemoveSeriesSuffix(title string) string {
+	if !strings.HasSuffix(title, ")") {
+		return title
+	}
+	i := strings.LastIndex(title, " (")
+	if i == -1 || !strings.Contains(title[i:], "#") {
+		return title
+	}
+	return strings.TrimSpace(title[:i])
+}
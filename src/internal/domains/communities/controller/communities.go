@@ -3,13 +3,13 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/betterreads/internal/domains/communities/model"
 	"github.com/betterreads/internal/domains/communities/service"
 	aux "github.com/betterreads/internal/pkg/controller"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -32,10 +32,12 @@ func NewCommunitiesController(communitiesService service.CommunitiesService) *Co
 // @Accept  mpfd
 // @Produce  json
 // @Param data formData string true "Community Data" follows model NewCommunityRequest
-// @Param file formData file true "Community Picture"
+// @Param file formData file true "Community Picture, a JPEG, PNG or WebP image up to 5 MB"
 // @Param community body model.NewCommunityRequest true "Don't need to send this in json, this param is only here to reference NewCommunityRequest, DONT SEND PICTURE in JSON"
 // @Success 201 {object} model.CommunityResponse
 // @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 413 {object} errors.ErrorDetailsWithParams
+// @Failure 415 {object} errors.ErrorDetailsWithParams
 // @Failure 500 {object} errors.ErrorDetails
 // @Router /communities [post]
 func (c *CommunitiesController) CreateCommunity(ctx *gin.Context) {
@@ -51,8 +53,12 @@ func (c *CommunitiesController) CreateCommunity(ctx *gin.Context) {
 		return
 	}
 
-	community, err := c.communitiesService.CreateCommunity(*newCommunityRequest, userId)
+	community, err := c.communitiesService.CreateCommunity(ctx.Request.Context(), *newCommunityRequest, userId)
 	if err != nil {
+		if errDetail := aux.GetPictureError("Error when creating community", err); errDetail != nil {
+			ctx.AbortWithError(errDetail.Status, errDetail)
+			return
+		}
 		errDetail := er.NewErrorDetails("Error when creating community", err, http.StatusInternalServerError)
 		ctx.AbortWithError(errDetail.Status, errDetail)
 		return
@@ -68,11 +74,20 @@ func (c *CommunitiesController) CreateCommunity(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {array} model.CommunityResponse
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[model.CommunityResponse]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
 // @Router /communities [get]
 func (c *CommunitiesController) GetCommunities(ctx *gin.Context) {
 	userId := aux.GetUserIdIfLogged(ctx)
-	communities, err := c.communitiesService.GetCommunities(userId)
+	page, errDetail := aux.GetPageRequest(ctx)
+	if errDetail != nil {
+		ctx.AbortWithError(errDetail.Status, errDetail)
+		return
+	}
+
+	communities, err := c.communitiesService.GetCommunities(ctx.Request.Context(), userId, page)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -107,8 +122,13 @@ func (c *CommunitiesController) JoinCommunity(ctx *gin.Context) {
 		return
 	}
 
-	err2 := c.communitiesService.JoinCommunity(communityIdParsed, userId)
+	err2 := c.communitiesService.JoinCommunity(ctx.Request.Context(), communityIdParsed, userId)
 	if err2 != nil {
+		if err2 == service.ErrBlockedByOwner {
+			details := er.NewErrorDetails("Error when joining community", err2, http.StatusForbidden)
+			ctx.AbortWithError(details.Status, details)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err2.Error()})
 		return
 	}
@@ -124,7 +144,9 @@ func (c *CommunitiesController) JoinCommunity(ctx *gin.Context) {
 // @Produce json
 // @Param id path string true "Community ID"
 // @Security ApiKeyAuth
-// @Success 200 {array} models.UserStageResponse
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[models.UserStageResponse]
 // @Router /communities/{id}/users [get]
 func (c *CommunitiesController) GetCommunityUsers(ctx *gin.Context) {
 	communityId := ctx.Param("id")
@@ -136,7 +158,13 @@ func (c *CommunitiesController) GetCommunityUsers(ctx *gin.Context) {
 		return
 	}
 
-	users, err := c.communitiesService.GetCommunityUsers(communityIdParsed)
+	page, errDetail := aux.GetPageRequest(ctx)
+	if errDetail != nil {
+		ctx.AbortWithError(errDetail.Status, errDetail)
+		return
+	}
+
+	users, err := c.communitiesService.GetCommunityUsers(ctx.Request.Context(), communityIdParsed, page)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -150,8 +178,12 @@ func (c *CommunitiesController) GetCommunityUsers(ctx *gin.Context) {
 // @Description Get community picture
 // @Tags communities
 // @Param id path string true "Community id"
-// @Produce jpeg
+// @Param size query string false "thumb, medium or large (default)"
+// @Param If-None-Match header string false "ETag of the cached picture"
+// @Produce jpeg,png
 // @Success 200 {file} []byte
+// @Success 204
+// @Success 304
 // @Failure 400 {object} errors.ErrorDetails
 // @Router /communities/{id}/picture [get]
 func (c *CommunitiesController) GetCommunityPicture(ctx *gin.Context) {
@@ -164,17 +196,21 @@ func (c *CommunitiesController) GetCommunityPicture(ctx *gin.Context) {
 		return
 	}
 
-	picture, err := c.communitiesService.GetCommunityPicture(communityIdParsed)
+	size, errSize := aux.GetPictureSize(ctx)
+	if errSize != nil {
+		ctx.AbortWithError(errSize.Status, errSize)
+		return
+	}
+
+	picture, err := c.communitiesService.GetCommunityPicture(ctx.Request.Context(), communityIdParsed, size)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(picture) == 0 {
-		ctx.JSON(http.StatusNoContent, gin.H{})
+	if err := aux.SendPicture(ctx, picture); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
-
-	ctx.Data(http.StatusOK, "image/jpeg", picture)
 }
 
 // SearchCommunities godoc
@@ -191,7 +227,7 @@ func (c CommunitiesController) SearchCommunities(ctx *gin.Context) {
 
 	userId := aux.GetUserIdIfLogged(ctx)
 
-	communities, err := c.communitiesService.SearchComunnity(search, userId)
+	communities, err := c.communitiesService.SearchComunnity(ctx.Request.Context(), search, userId)
 	if err != nil {
 		errDetail := er.NewErrorDetails("Error when searching communities", err, http.StatusInternalServerError)
 		ctx.AbortWithError(errDetail.Status, errDetail)
@@ -229,7 +265,7 @@ func (c *CommunitiesController) GetCommunityById(ctx *gin.Context) {
 
 	userId := aux.GetUserIdIfLogged(ctx)
 
-	community, err := c.communitiesService.GetCommunityById(communityIdParsed, userId)
+	community, err := c.communitiesService.GetCommunityById(ctx.Request.Context(), communityIdParsed, userId)
 	if err != nil {
 		if err == service.ErrCommunityNotFound {
 			details := er.NewErrorDetails("Error when getting Community", err, http.StatusNotFound)
@@ -251,8 +287,9 @@ func (c *CommunitiesController) GetCommunityById(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Community ID"
-// @Param content body string true "Post Content"
-// @Success 200 {string} model.CommunityPostResponse
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} pagination.Page[model.CommunityPostResponse]
 // @Router /communities/{id}/posts [get]
 func (c *CommunitiesController) GetCommunityPosts(ctx *gin.Context) {
 	communityId := ctx.Param("id")
@@ -264,7 +301,14 @@ func (c *CommunitiesController) GetCommunityPosts(ctx *gin.Context) {
 		return
 	}
 
-	posts, err := c.communitiesService.GetCommunityPosts(communityIdParsed)
+	page, errDetail := aux.GetPageRequest(ctx)
+	if errDetail != nil {
+		ctx.AbortWithError(errDetail.Status, errDetail)
+		return
+	}
+
+	viewerId := aux.GetUserIdIfLogged(ctx)
+	posts, err := c.communitiesService.GetCommunityPosts(ctx.Request.Context(), communityIdParsed, viewerId, page)
 	if err != nil {
 		if err == service.ErrCommunityNotFound {
 			details := er.NewErrorDetails("Error when getting posts", err, http.StatusNotFound)
@@ -311,11 +355,14 @@ func (c *CommunitiesController) CreateCommunityPost(ctx *gin.Context) {
 		return
 	}
 
-	err = c.communitiesService.CreateCommunityPost(communityIdParsed, userId, post.Content, post.Title)
+	err = c.communitiesService.CreateCommunityPost(ctx.Request.Context(), communityIdParsed, userId, post.Content, post.Title)
 	if err != nil {
 		if err == service.ErrUserNotInCommunity {
 			details := er.NewErrorDetails("Error when creating post", err, http.StatusBadRequest)
 			ctx.AbortWithError(details.Status, details)
+		} else if err == service.ErrBlockedByOwner {
+			details := er.NewErrorDetails("Error when creating post", err, http.StatusForbidden)
+			ctx.AbortWithError(details.Status, details)
 		} else {
 			details := er.NewErrorDetails("Error when creating post", err, http.StatusInternalServerError)
 			ctx.AbortWithError(details.Status, details)
@@ -326,6 +373,71 @@ func (c *CommunitiesController) CreateCommunityPost(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Post created"})
 }
 
+// EditCommunityPost godoc
+// @Summary Edit a post of a community
+// @Description Changes the title and content of a post, only its author, a moderator or an admin can do it
+// @Tags communities
+// @Accept json
+// @Produce json
+// @Param id path string true "Community ID"
+// @Param postId path string true "Post ID"
+// @Param post body model.NewCommunityPostRequest true "Post Data"
+// @Success 200 {string} string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /communities/{id}/posts/{postId} [put]
+func (c *CommunitiesController) EditCommunityPost(ctx *gin.Context) {
+	userId, communityId, postId, errDetail := getPostIds(ctx)
+	if errDetail != nil {
+		ctx.AbortWithError(errDetail.Status, errDetail)
+		return
+	}
+
+	post := &model.NewCommunityPostRequest{}
+	if err := ctx.ShouldBindJSON(post); err != nil {
+		er.AbortWithJsonErorr(ctx, err)
+		return
+	}
+
+	err := c.communitiesService.EditCommunityPost(ctx.Request.Context(), communityId, postId, userId, aux.GetLoggedUserRole(ctx), post.Content, post.Title)
+	if err != nil {
+		abortWithPostError(ctx, "Error when editing post", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Post edited"})
+}
+
+// DeleteCommunityPost godoc
+// @Summary Delete a post of a community
+// @Description Deletes a post, only its author, a moderator or an admin can do it
+// @Tags communities
+// @Param id path string true "Community ID"
+// @Param postId path string true "Post ID"
+// @Success 204
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /communities/{id}/posts/{postId} [delete]
+func (c *CommunitiesController) DeleteCommunityPost(ctx *gin.Context) {
+	userId, communityId, postId, errDetail := getPostIds(ctx)
+	if errDetail != nil {
+		ctx.AbortWithError(errDetail.Status, errDetail)
+		return
+	}
+
+	err := c.communitiesService.DeleteCommunityPost(ctx.Request.Context(), communityId, postId, userId, aux.GetLoggedUserRole(ctx))
+	if err != nil {
+		abortWithPostError(ctx, "Error when deleting post", err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
 // LeaveCommunity godoc
 // @Summary Leave a community
 // @Description Leave a community
@@ -352,7 +464,7 @@ func (c *CommunitiesController) LeaveCommunity(ctx *gin.Context) {
 		return
 	}
 
-	err = c.communitiesService.LeaveCommunity(communityIdParsed, userId)
+	err = c.communitiesService.LeaveCommunity(ctx.Request.Context(), communityIdParsed, userId)
 	if err != nil {
 		if err == service.ErrUserNotInCommunity {
 			details := er.NewErrorDetails("Error when leaving community", err, http.StatusBadRequest)
@@ -395,7 +507,7 @@ func (c *CommunitiesController) DeleteCommunity(ctx *gin.Context) {
 		return
 	}
 
-	err = c.communitiesService.DeleteCommunity(communityIdParsed, userId)
+	err = c.communitiesService.DeleteCommunity(ctx.Request.Context(), communityIdParsed, userId)
 	if err != nil {
 		if err == service.ErrUserNotCreator {
 			details := er.NewErrorDetails("Error when deleting community", err, http.StatusForbidden)
@@ -440,7 +552,47 @@ func getCommunityRequest(ctx *gin.Context) (*model.NewCommunityRequest, *er.Erro
 }
 
 // Aux
+func getPostIds(ctx *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, *er.ErrorDetails) {
+	userId, errDetail := aux.GetLoggedUserId(ctx)
+	if errDetail != nil {
+		return uuid.Nil, uuid.Nil, uuid.Nil, errDetail
+	}
+
+	communityId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		err_detail := fmt.Errorf("Invalid community id: %s", ctx.Param("id"))
+		return uuid.Nil, uuid.Nil, uuid.Nil, er.NewErrorDetails("Error Parsing Community ID", err_detail, http.StatusBadRequest)
+	}
+
+	postId, err := uuid.Parse(ctx.Param("postId"))
+	if err != nil {
+		err_detail := fmt.Errorf("Invalid post id: %s", ctx.Param("postId"))
+		return uuid.Nil, uuid.Nil, uuid.Nil, er.NewErrorDetails("Error Parsing Post ID", err_detail, http.StatusBadRequest)
+	}
+	return userId, communityId, postId, nil
+}
+
+func abortWithPostError(ctx *gin.Context, title string, err error) {
+	if err == service.ErrUserNotPostAuthor {
+		details := er.NewErrorDetails(title, err, http.StatusForbidden)
+		ctx.AbortWithError(details.Status, details)
+	} else if err == service.ErrCommunityNotFound || err == service.ErrPostNotFound {
+		details := er.NewErrorDetails(title, err, http.StatusNotFound)
+		ctx.AbortWithError(details.Status, details)
+	} else {
+		details := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		ctx.AbortWithError(details.Status, details)
+	}
+}
+
 func getPicture(ctx *gin.Context) ([]byte, *er.ErrorDetailsWithParams) {
+	if err := aux.ParsePictureForm(ctx); err != nil {
+		if errDetails := aux.GetPictureError("Error Creating Community", err); errDetails != nil {
+			return nil, errDetails
+		}
+		return nil, er.NewErrorDetailsWithParams("Error Creating Community", http.StatusBadRequest, err)
+	}
+
 	file, _, err := ctx.Request.FormFile("file")
 	if err != nil {
 		errParam := er.ErrorParam{
@@ -450,8 +602,11 @@ func getPicture(ctx *gin.Context) ([]byte, *er.ErrorDetailsWithParams) {
 		return nil, er.NewErrorDetailsWithParams("Error Creating Community", http.StatusBadRequest, errParam)
 	}
 	defer file.Close()
-	picture, err := io.ReadAll(file)
+	picture, err := images.ReadUpload(file)
 	if err != nil {
+		if errDetails := aux.GetPictureError("Error Creating Community", err); errDetails != nil {
+			return nil, errDetails
+		}
 		errParam := er.ErrorParam{
 			Name:   "picture",
 			Reason: "file is invalid",
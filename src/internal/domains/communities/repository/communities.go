@@ -1,30 +1,40 @@
 package repository
 
 import (
+	"context"
+
 	"errors"
 
 	"github.com/betterreads/internal/domains/communities/model"
 	userModel "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
 var (
 	ErrCommunityNotFound = errors.New("community not found")
+	ErrPostNotFound      = errors.New("post not found")
 )
 
 type CommunitiesDatabase interface {
-	CreateCommunity(community model.NewCommunityRequest, userId uuid.UUID) (*model.CommunityResponse, error)
-	GetCommunities(userId uuid.UUID) ([]*model.CommunityResponse, error)
-	JoinCommunity(communityId uuid.UUID, userId uuid.UUID) error
-	CheckIfUserIsInCommunity(communityId uuid.UUID, userId uuid.UUID) bool
-	CheckIFCommunityExists(communityId uuid.UUID) bool
-	GetCommunityUsers(communityId uuid.UUID) ([]*userModel.UserStageResponse, error)
-	GetCommunityPicture(communityId uuid.UUID) ([]byte, error)
-	SearchCommunities(search string, currId uuid.UUID) ([]*model.CommunityResponse, error)
-	GetCommunityById(id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error)
-	GetCommunityPosts(communityId uuid.UUID) ([]*model.CommunityPostResponse, error)
-	CreateCommunityPost(communityId uuid.UUID, userId uuid.UUID, content string, title string) error
-	LeaveCommunity(communityId uuid.UUID, userId uuid.UUID) error
-	CheckIfUserIsCreator(communityId uuid.UUID, userId uuid.UUID) bool
-	DeleteCommunity(communityId uuid.UUID) error
+	CreateCommunity(ctx context.Context, community model.NewCommunityRequest, userId uuid.UUID, picture *images.Picture) (*model.CommunityResponse, error)
+	GetCommunities(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityResponse], error)
+	JoinCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error
+	CheckIfUserIsInCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) bool
+	CheckIFCommunityExists(ctx context.Context, communityId uuid.UUID) bool
+	GetCommunityUsers(ctx context.Context, communityId uuid.UUID, page pagination.Request) (pagination.Page[*userModel.UserStageResponse], error)
+	GetCommunityPicture(ctx context.Context, communityId uuid.UUID, size images.Size) (*storage.Blob, error)
+	SearchCommunities(ctx context.Context, search string, currId uuid.UUID) ([]*model.CommunityResponse, error)
+	GetCommunityById(ctx context.Context, id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error)
+	GetCommunityPosts(ctx context.Context, communityId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityPostResponse], error)
+	CreateCommunityPost(ctx context.Context, communityId uuid.UUID, userId uuid.UUID, content string, title string) error
+	GetCommunityPostAuthor(ctx context.Context, communityId uuid.UUID, postId uuid.UUID) (uuid.UUID, error)
+	UpdateCommunityPost(ctx context.Context, postId uuid.UUID, content string, title string) error
+	DeleteCommunityPost(ctx context.Context, postId uuid.UUID) error
+	LeaveCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error
+	CheckIfUserIsCreator(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) bool
+	CheckIfBlockedByOwner(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) bool
+	DeleteCommunity(ctx context.Context, communityId uuid.UUID) error
 }
@@ -1,94 +1,45 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/betterreads/internal/domains/communities/model"
 	userModel "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type PostgresCommunitiesRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	blobs storage.BlobStore
 }
 
-func NewPostgresCommunitiesRepository(db *sqlx.DB) (CommunitiesDatabase, error) {
-	enableUUIDExtension := `CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`
-	if _, err := db.Exec(enableUUIDExtension); err != nil {
-		return nil, fmt.Errorf("failed to enable uuid extension: %w", err)
-	}
-
-	schemaCommunities := `
-		CREATE TABLE IF NOT EXISTS communities (
-			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-			name VARCHAR(255) NOT NULL,
-			description TEXT NOT NULL,
-			owner_id UUID NOT NULL,
-			FOREIGN KEY (owner_id) REFERENCES users(id)
-		);`
-
-	if _, err := db.Exec(schemaCommunities); err != nil {
-		return nil, fmt.Errorf("failed to create communities table: %w", err)
-	}
-
-	schemaCommunitiesUsers := `
-		CREATE TABLE IF NOT EXISTS communities_users (
-			user_id UUID NOT NULL,
-			community_id UUID NOT NULL,
-			PRIMARY KEY (user_id, community_id),	
-			FOREIGN KEY (user_id) REFERENCES users(id),
-			FOREIGN KEY (community_id) REFERENCES communities(id)
-		);`
-
-	if _, err := db.Exec(schemaCommunitiesUsers); err != nil {
-		return nil, fmt.Errorf("failed to create communities_users table: %w", err)
-	}
-
-	schemaCommunitiesPictures := `
-		CREATE TABLE IF NOT EXISTS communities_pictures (
-			community_id UUID NOT NULL,
-			picture BYTEA NOT NULL,
-			PRIMARY KEY (community_id),
-			FOREIGN KEY (community_id) REFERENCES communities(id)
-		);
-	`
-
-	if _, err := db.Exec(schemaCommunitiesPictures); err != nil {
-		return nil, fmt.Errorf("failed to create communities_pictures table: %w", err)
-	}
-
-	schemaCommunitiesPosts := `
-		CREATE TABLE IF NOT EXISTS communities_posts (
-			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-			community_id UUID NOT NULL,
-			user_id UUID NOT NULL,
-			content TEXT NOT NULL,
-			title VARCHAR(255) NOT NULL,
-			date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (community_id) REFERENCES communities(id),
-			FOREIGN KEY (user_id) REFERENCES users(id)
-		);`
-
-	if _, err := db.Exec(schemaCommunitiesPosts); err != nil {
-		return nil, fmt.Errorf("failed to create communities_posts table: %w", err)
-	}
-
-	return &PostgresCommunitiesRepository{db: db}, nil
+func NewPostgresCommunitiesRepository(db *sqlx.DB, blobs storage.BlobStore) CommunitiesDatabase {
+	return &PostgresCommunitiesRepository{db: db, blobs: blobs}
 }
 
-func (db *PostgresCommunitiesRepository) CreateCommunity(community model.NewCommunityRequest, userId uuid.UUID) (*model.CommunityResponse, error) {
+func (db *PostgresCommunitiesRepository) CreateCommunity(ctx context.Context, community model.NewCommunityRequest, userId uuid.UUID, picture *images.Picture) (*model.CommunityResponse, error) {
 	query := `INSERT INTO communities (name, description, owner_id) VALUES ($1, $2, $3) RETURNING id`
 
 	var id uuid.UUID
-	err := db.db.QueryRow(query, community.Name, community.Description, userId).Scan(&id)
+	err := db.db.QueryRowContext(ctx, query, community.Name, community.Description, userId).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create community: %w", err)
 	}
 
-	query = `INSERT INTO communities_pictures (community_id, picture) VALUES ($1, $2)`
-	_, err = db.db.Exec(query, id, community.Picture)
+	key := storage.CommunityPictureKey(id.String())
+	if err := picture.Save(ctx, db.blobs, key); err != nil {
+		return nil, fmt.Errorf("failed to create community picture: %w", err)
+	}
+
+	query = `INSERT INTO communities_pictures (community_id, blob_key, content_type, variants, etag) VALUES ($1, $2, $3, true, $4)`
+	_, err = db.db.ExecContext(ctx, query, id, key, picture.ContentType, picture.ETag)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create community picture: %w", err)
 	}
@@ -101,7 +52,7 @@ func (db *PostgresCommunitiesRepository) CreateCommunity(community model.NewComm
 		Joined:      true,
 	}
 
-	JoinCommunityErr := db.JoinCommunity(id, userId)
+	JoinCommunityErr := db.JoinCommunity(ctx, id, userId)
 	if JoinCommunityErr != nil {
 		return nil, fmt.Errorf("failed to join user to community: %w", JoinCommunityErr)
 	}
@@ -109,7 +60,7 @@ func (db *PostgresCommunitiesRepository) CreateCommunity(community model.NewComm
 	return &communityResponse, nil
 }
 
-func (db *PostgresCommunitiesRepository) GetCommunities(userId uuid.UUID) ([]*model.CommunityResponse, error) {
+func (db *PostgresCommunitiesRepository) GetCommunities(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityResponse], error) {
 	query := `SELECT 
     c.id AS id, 
     c.name AS name, 
@@ -124,9 +75,20 @@ func (db *PostgresCommunitiesRepository) GetCommunities(userId uuid.UUID) ([]*mo
 	LEFT JOIN 
     communities_users cu 
     ON c.id = cu.community_id AND cu.user_id = $1`
-	rows, err := db.db.Query(query, userId)
+	args := []interface{}{userId}
+	if page.Cursor != nil {
+		query += `
+	WHERE (c.name, c.id::TEXT) > ($2, $3)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(`
+	ORDER BY c.name, c.id::TEXT
+	LIMIT $%d`, len(args)+1)
+	args = append(args, page.Fetch())
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get communities: %w", err)
+		return pagination.Page[*model.CommunityResponse]{}, fmt.Errorf("failed to get communities: %w", err)
 	}
 	defer rows.Close()
 
@@ -136,17 +98,19 @@ func (db *PostgresCommunitiesRepository) GetCommunities(userId uuid.UUID) ([]*mo
 
 		err := rows.Scan(&community.ID, &community.Name, &community.Description, &community.OwnerID, &community.Joined)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan community: %w", err)
+			return pagination.Page[*model.CommunityResponse]{}, fmt.Errorf("failed to scan community: %w", err)
 		}
 		communities = append(communities, community)
 	}
 
-	return communities, nil
+	return pagination.NewPage(communities, page, func(community *model.CommunityResponse) pagination.Cursor {
+		return pagination.Cursor{Key: community.Name, Id: community.ID.String()}
+	}), nil
 }
 
-func (db *PostgresCommunitiesRepository) JoinCommunity(communityId uuid.UUID, userId uuid.UUID) error {
+func (db *PostgresCommunitiesRepository) JoinCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error {
 	query := `INSERT INTO communities_users (user_id, community_id) VALUES ($1, $2)`
-	_, err := db.db.Exec(query, userId, communityId)
+	_, err := db.db.ExecContext(ctx, query, userId, communityId)
 	if err != nil {
 		return fmt.Errorf("failed to join community: %w", err)
 	}
@@ -154,25 +118,36 @@ func (db *PostgresCommunitiesRepository) JoinCommunity(communityId uuid.UUID, us
 	return nil
 }
 
-func (db *PostgresCommunitiesRepository) CheckIfUserIsInCommunity(communityId uuid.UUID, userId uuid.UUID) bool {
+func (db *PostgresCommunitiesRepository) CheckIfUserIsInCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) bool {
 	query := `SELECT EXISTS(SELECT 1 FROM communities_users WHERE user_id=$1 AND community_id=$2)`
 
 	var exists bool
-	err := db.db.QueryRow(query, userId, communityId).Scan(&exists)
+	err := db.db.QueryRowContext(ctx, query, userId, communityId).Scan(&exists)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if user is in community", "error", err)
 		return false
 	}
 
 	return exists
 }
 
-func (db *PostgresCommunitiesRepository) GetCommunityUsers(communityId uuid.UUID) ([]*userModel.UserStageResponse, error) {
+func (db *PostgresCommunitiesRepository) GetCommunityUsers(ctx context.Context, communityId uuid.UUID, page pagination.Request) (pagination.Page[*userModel.UserStageResponse], error) {
 	query := `SELECT u.email, u.username, u.first_name, u.last_name, u.is_author, u.id FROM users u 
 			  JOIN communities_users cu ON u.id = cu.user_id 
 			  WHERE cu.community_id = $1`
-	rows, err := db.db.Query(query, communityId)
+	args := []interface{}{communityId}
+	if page.Cursor != nil {
+		query += ` AND (u.username, u.id::TEXT) > ($2, $3)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(`
+			  ORDER BY u.username, u.id::TEXT
+			  LIMIT $%d`, len(args)+1)
+	args = append(args, page.Fetch())
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get community users: %w", err)
+		return pagination.Page[*userModel.UserStageResponse]{}, fmt.Errorf("failed to get community users: %w", err)
 	}
 	defer rows.Close()
 
@@ -182,29 +157,31 @@ func (db *PostgresCommunitiesRepository) GetCommunityUsers(communityId uuid.UUID
 
 		err := rows.Scan(&user.Email, &user.Username, &user.First_name, &user.Last_name, &user.IsAuthor, &user.Id)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return pagination.Page[*userModel.UserStageResponse]{}, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
 	}
 
-	return users, nil
+	return pagination.NewPage(users, page, func(user *userModel.UserStageResponse) pagination.Cursor {
+		return pagination.Cursor{Key: user.Username, Id: user.Id.String()}
+	}), nil
 }
 
-func (db *PostgresCommunitiesRepository) GetCommunityPicture(communityId uuid.UUID) ([]byte, error) {
-	query := `SELECT picture FROM communities_pictures WHERE community_id = $1`
-	var picture []byte
-	err := db.db.QueryRow(query, communityId).Scan(&picture)
+func (db *PostgresCommunitiesRepository) GetCommunityPicture(ctx context.Context, communityId uuid.UUID, size images.Size) (*storage.Blob, error) {
+	query := `SELECT picture, blob_key, content_type, variants, etag, updated_at FROM communities_pictures WHERE community_id = $1`
+	record := &storage.PictureRecord{}
+	err := db.db.GetContext(ctx, record, query, communityId)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return picture, nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get community picture: %w", err)
 	}
 
-	return picture, nil
+	return storage.LoadPicture(ctx, db.blobs, record, string(size))
 }
 
-func (db *PostgresCommunitiesRepository) SearchCommunities(search string, curr_user uuid.UUID) ([]*model.CommunityResponse, error) {
+func (db *PostgresCommunitiesRepository) SearchCommunities(ctx context.Context, search string, curr_user uuid.UUID) ([]*model.CommunityResponse, error) {
 	query := `SELECT 
     c.id, 
     c.name, 
@@ -220,7 +197,7 @@ func (db *PostgresCommunitiesRepository) SearchCommunities(search string, curr_u
     WHERE c.name ILIKE '%' || $1 || '%'`
 
 	var communities []*model.CommunityResponse
-	err := db.db.Select(&communities, query, search, curr_user)
+	err := db.db.SelectContext(ctx, &communities, query, search, curr_user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search communities: %w", err)
 	}
@@ -228,7 +205,7 @@ func (db *PostgresCommunitiesRepository) SearchCommunities(search string, curr_u
 	return communities, nil
 }
 
-func (db *PostgresCommunitiesRepository) GetCommunityById(id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error) {
+func (db *PostgresCommunitiesRepository) GetCommunityById(ctx context.Context, id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error) {
 	query := `SELECT 
     c.id, 
     c.name, 
@@ -244,7 +221,7 @@ func (db *PostgresCommunitiesRepository) GetCommunityById(id uuid.UUID, userId u
     WHERE c.id = $2 `
 
 	var community model.CommunityResponse
-	err := db.db.Get(&community, query, userId, id)
+	err := db.db.GetContext(ctx, &community, query, userId, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrCommunityNotFound
@@ -254,7 +231,8 @@ func (db *PostgresCommunitiesRepository) GetCommunityById(id uuid.UUID, userId u
 	return &community, nil
 }
 
-func (db *PostgresCommunitiesRepository) GetCommunityPosts(communityId uuid.UUID) ([]*model.CommunityPostResponse, error) {
+// GetCommunityPosts hides the posts of the users blocked by the viewer or that blocked them
+func (db *PostgresCommunitiesRepository) GetCommunityPosts(ctx context.Context, communityId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityPostResponse], error) {
 	query := `SELECT 
 	cp.id, 
 	cp.title,
@@ -265,84 +243,152 @@ func (db *PostgresCommunitiesRepository) GetCommunityPosts(communityId uuid.UUID
 	FROM communities_posts cp
 	JOIN users u ON cp.user_id = u.id
 	WHERE cp.community_id = $1
-	ORDER BY cp.date DESC`
+	AND NOT EXISTS (SELECT 1 FROM blocks bl
+		WHERE (bl.blocker_id = $2 AND bl.blocked_id = cp.user_id) OR (bl.blocker_id = cp.user_id AND bl.blocked_id = $2))`
+	args := []interface{}{communityId, viewerId}
+	if page.Cursor != nil {
+		query += ` AND (to_char(cp.date, ` + pagination.TimeKeyFormat + `), cp.id::TEXT) < ($3, $4)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(`
+	ORDER BY cp.date DESC, cp.id::TEXT DESC
+	LIMIT $%d`, len(args)+1)
+	args = append(args, page.Fetch())
+
+	posts := []*model.CommunityPostResponse{}
+	err := db.db.SelectContext(ctx, &posts, query, args...)
+	if err != nil && err != sql.ErrNoRows {
+		return pagination.Page[*model.CommunityPostResponse]{}, fmt.Errorf("failed to get community posts: %w", err)
+	}
+	return pagination.NewPage(posts, page, func(post *model.CommunityPostResponse) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(post.Date), Id: post.ID.String()}
+	}), nil
+}
+
+func (db *PostgresCommunitiesRepository) CreateCommunityPost(ctx context.Context, communityId uuid.UUID, userId uuid.UUID, content string, title string) error {
+	query := `INSERT INTO communities_posts (community_id, user_id, content, title) VALUES ($1, $2, $3, $4)`
+	_, err := db.db.ExecContext(ctx, query, communityId, userId, content, title)
+	if err != nil {
+		return fmt.Errorf("failed to create community post: %w", err)
+	}
+	return nil
+}
+
+// GetCommunityPostAuthor returns the user that wrote the post, the post must belong to the community
+func (db *PostgresCommunitiesRepository) GetCommunityPostAuthor(ctx context.Context, communityId uuid.UUID, postId uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT user_id FROM communities_posts WHERE id = $1 AND community_id = $2`
 
-	var posts []*model.CommunityPostResponse
-	err := db.db.Select(&posts, query, communityId)
+	var userId uuid.UUID
+	err := db.db.GetContext(ctx, &userId, query, postId, communityId)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return []*model.CommunityPostResponse{}, nil
+			return uuid.Nil, ErrPostNotFound
 		}
-		return nil, fmt.Errorf("failed to get community posts: %w", err)
+		return uuid.Nil, fmt.Errorf("failed to get community post: %w", err)
 	}
-	return posts, nil
+	return userId, nil
 }
 
-func (db *PostgresCommunitiesRepository) CreateCommunityPost(communityId uuid.UUID, userId uuid.UUID, content string, title string) error {
-	query := `INSERT INTO communities_posts (community_id, user_id, content, title) VALUES ($1, $2, $3, $4)`
-	_, err := db.db.Exec(query, communityId, userId, content, title)
+func (db *PostgresCommunitiesRepository) UpdateCommunityPost(ctx context.Context, postId uuid.UUID, content string, title string) error {
+	query := `UPDATE communities_posts SET content = $1, title = $2 WHERE id = $3`
+	_, err := db.db.ExecContext(ctx, query, content, title, postId)
 	if err != nil {
-		return fmt.Errorf("failed to create community post: %w", err)
+		return fmt.Errorf("failed to update community post: %w", err)
 	}
 	return nil
 }
 
-func (db *PostgresCommunitiesRepository) LeaveCommunity(communityId uuid.UUID, userId uuid.UUID) error {
+func (db *PostgresCommunitiesRepository) DeleteCommunityPost(ctx context.Context, postId uuid.UUID) error {
+	query := `DELETE FROM communities_posts WHERE id = $1`
+	_, err := db.db.ExecContext(ctx, query, postId)
+	if err != nil {
+		return fmt.Errorf("failed to delete community post: %w", err)
+	}
+	return nil
+}
+
+func (db *PostgresCommunitiesRepository) LeaveCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error {
 	query := `DELETE FROM communities_users WHERE community_id = $1 AND user_id = $2`
-	_, err := db.db.Exec(query, communityId, userId)
+	_, err := db.db.ExecContext(ctx, query, communityId, userId)
 	if err != nil {
 		return fmt.Errorf("failed to leave community: %w", err)
 	}
 	return nil
 }
 
-func (db *PostgresCommunitiesRepository) CheckIFCommunityExists(communityId uuid.UUID) bool {
+func (db *PostgresCommunitiesRepository) CheckIFCommunityExists(ctx context.Context, communityId uuid.UUID) bool {
 	query := `SELECT EXISTS(SELECT 1 FROM communities WHERE id=$1)`
 
 	var exists bool
-	err := db.db.QueryRow(query, communityId).Scan(&exists)
+	err := db.db.QueryRowContext(ctx, query, communityId).Scan(&exists)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if community exists", "error", err)
 		return false
 	}
 
 	return exists
 }
 
-func (db *PostgresCommunitiesRepository) DeleteCommunity(communityId uuid.UUID) error {
+func (db *PostgresCommunitiesRepository) DeleteCommunity(ctx context.Context, communityId uuid.UUID) error {
 	query := `DELETE FROM communities_posts WHERE community_id = $1`
-	_, err := db.db.Exec(query, communityId)
+	_, err := db.db.ExecContext(ctx, query, communityId)
 	if err != nil {
 		return fmt.Errorf("failed to delete community: %w", err)
 	}
 
-	query = `DELETE FROM communities_pictures WHERE community_id = $1`
-	_, err = db.db.Exec(query, communityId)
-	if err != nil {
+	var blobKey *string
+	query = `DELETE FROM communities_pictures WHERE community_id = $1 RETURNING blob_key`
+	if err := db.db.GetContext(ctx, &blobKey, query, communityId); err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("failed to delete community: %w", err)
 	}
 
 	// Deletes from users
 	query = `DELETE FROM communities_users WHERE community_id = $1`
-	_, err = db.db.Exec(query, communityId)
+	_, err = db.db.ExecContext(ctx, query, communityId)
 	if err != nil {
 		return fmt.Errorf("failed to delete community: %w", err)
 	}
 
 	query = `DELETE FROM communities WHERE id = $1`
-	_, err = db.db.Exec(query, communityId)
+	_, err = db.db.ExecContext(ctx, query, communityId)
 	if err != nil {
 		return fmt.Errorf("failed to delete community: %w", err)
 	}
 
+	// Pictures moved by the blobs migrate command have a key of their own
+	keys := []string{storage.CommunityPictureKey(communityId.String())}
+	if blobKey != nil && *blobKey != keys[0] {
+		keys = append(keys, *blobKey)
+	}
+	for _, key := range keys {
+		if err := images.Delete(ctx, db.blobs, key); err != nil {
+			return fmt.Errorf("failed to delete community picture: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (db *PostgresCommunitiesRepository) CheckIfUserIsCreator(communityId uuid.UUID, userId uuid.UUID) bool {
+func (db *PostgresCommunitiesRepository) CheckIfUserIsCreator(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) bool {
 	query := `SELECT EXISTS(SELECT 1 FROM communities WHERE id=$1 AND owner_id=$2)`
 
 	var exists bool
-	err := db.db.QueryRow(query, communityId, userId).Scan(&exists)
+	err := db.db.QueryRowContext(ctx, query, communityId, userId).Scan(&exists)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if user is creator", "error", err)
+		return false
+	}
+
+	return exists
+}
+
+func (db *PostgresCommunitiesRepository) CheckIfBlockedByOwner(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) bool {
+	query := `SELECT EXISTS(SELECT 1 FROM communities c JOIN blocks bl ON bl.blocker_id = c.owner_id WHERE c.id=$1 AND bl.blocked_id=$2)`
+
+	var exists bool
+	err := db.db.QueryRowContext(ctx, query, communityId, userId).Scan(&exists)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if blocked by owner", "error", err)
 		return false
 	}
 
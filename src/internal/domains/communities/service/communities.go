@@ -1,9 +1,15 @@
 package service
 
 import (
+	"context"
+
 	"github.com/betterreads/internal/domains/communities/model"
 	"github.com/betterreads/internal/domains/communities/repository"
 	userModel "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -15,8 +21,13 @@ func NewCommunitiesServiceImpl(r repository.CommunitiesDatabase) CommunitiesServ
 	return &CommunitiesServiceImpl{r: r}
 }
 
-func (cs *CommunitiesServiceImpl) CreateCommunity(community model.NewCommunityRequest, userId uuid.UUID) (*model.CommunityResponse, error) {
-	communityResponse, err := cs.r.CreateCommunity(community, userId)
+func (cs *CommunitiesServiceImpl) CreateCommunity(ctx context.Context, community model.NewCommunityRequest, userId uuid.UUID) (*model.CommunityResponse, error) {
+	picture, err := images.Process(community.Picture)
+	if err != nil {
+		return nil, err
+	}
+
+	communityResponse, err := cs.r.CreateCommunity(ctx, community, userId, picture)
 	if err != nil {
 		return nil, err
 	}
@@ -24,21 +35,25 @@ func (cs *CommunitiesServiceImpl) CreateCommunity(community model.NewCommunityRe
 	return communityResponse, nil
 }
 
-func (cs *CommunitiesServiceImpl) GetCommunities(userId uuid.UUID) ([]*model.CommunityResponse, error) {
-	communities, err := cs.r.GetCommunities(userId)
+func (cs *CommunitiesServiceImpl) GetCommunities(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityResponse], error) {
+	communities, err := cs.r.GetCommunities(ctx, userId, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*model.CommunityResponse]{}, err
 	}
 
 	return communities, nil
 }
 
-func (cs *CommunitiesServiceImpl) JoinCommunity(communityId uuid.UUID, userId uuid.UUID) error {
-	if cs.r.CheckIfUserIsInCommunity(communityId, userId) {
+func (cs *CommunitiesServiceImpl) JoinCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error {
+	if cs.r.CheckIfUserIsInCommunity(ctx, communityId, userId) {
 		return ErrUserAlreadyInCommunity
 	}
 
-	err := cs.r.JoinCommunity(communityId, userId)
+	if cs.r.CheckIfBlockedByOwner(ctx, communityId, userId) {
+		return ErrBlockedByOwner
+	}
+
+	err := cs.r.JoinCommunity(ctx, communityId, userId)
 	if err != nil {
 		return err
 	}
@@ -46,24 +61,24 @@ func (cs *CommunitiesServiceImpl) JoinCommunity(communityId uuid.UUID, userId uu
 	return nil
 }
 
-func (cs *CommunitiesServiceImpl) GetCommunityUsers(communityId uuid.UUID) ([]*userModel.UserStageResponse, error) {
-	users, err := cs.r.GetCommunityUsers(communityId)
+func (cs *CommunitiesServiceImpl) GetCommunityUsers(ctx context.Context, communityId uuid.UUID, page pagination.Request) (pagination.Page[*userModel.UserStageResponse], error) {
+	users, err := cs.r.GetCommunityUsers(ctx, communityId, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*userModel.UserStageResponse]{}, err
 	}
 	return users, nil
 }
 
-func (cs *CommunitiesServiceImpl) GetCommunityPicture(communityId uuid.UUID) ([]byte, error) {
-	picture, err := cs.r.GetCommunityPicture(communityId)
+func (cs *CommunitiesServiceImpl) GetCommunityPicture(ctx context.Context, communityId uuid.UUID, size images.Size) (*storage.Blob, error) {
+	picture, err := cs.r.GetCommunityPicture(ctx, communityId, size)
 	if err != nil {
 		return nil, err
 	}
 	return picture, nil
 }
 
-func (cs *CommunitiesServiceImpl) SearchComunnity(search string, currId uuid.UUID) ([]*model.CommunityResponse, error) {
-	communities, err := cs.r.SearchCommunities(search, currId)
+func (cs *CommunitiesServiceImpl) SearchComunnity(ctx context.Context, search string, currId uuid.UUID) ([]*model.CommunityResponse, error) {
+	communities, err := cs.r.SearchCommunities(ctx, search, currId)
 
 	if err != nil {
 		return nil, err
@@ -76,8 +91,8 @@ func (cs *CommunitiesServiceImpl) SearchComunnity(search string, currId uuid.UUI
 	return communities, nil
 }
 
-func (cs *CommunitiesServiceImpl) GetCommunityById(id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error) {
-	community, err := cs.r.GetCommunityById(id, userId)
+func (cs *CommunitiesServiceImpl) GetCommunityById(ctx context.Context, id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error) {
+	community, err := cs.r.GetCommunityById(ctx, id, userId)
 	if err != nil {
 		if err == repository.ErrCommunityNotFound {
 			return nil, ErrCommunityNotFound
@@ -87,61 +102,107 @@ func (cs *CommunitiesServiceImpl) GetCommunityById(id uuid.UUID, userId uuid.UUI
 	return community, nil
 }
 
-func (cs *CommunitiesServiceImpl) GetCommunityPosts(communityId uuid.UUID) ([]*model.CommunityPostResponse, error) {
-	exists := cs.r.CheckIFCommunityExists(communityId)
+func (cs *CommunitiesServiceImpl) GetCommunityPosts(ctx context.Context, communityId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityPostResponse], error) {
+	exists := cs.r.CheckIFCommunityExists(ctx, communityId)
 	if !exists {
-		return nil, ErrCommunityNotFound
+		return pagination.Page[*model.CommunityPostResponse]{}, ErrCommunityNotFound
 	}
 
-	posts, err := cs.r.GetCommunityPosts(communityId)
+	posts, err := cs.r.GetCommunityPosts(ctx, communityId, viewerId, page)
 	if err != nil {
-		return nil, err
-	}
-
-	if len(posts) == 0 {
-		return []*model.CommunityPostResponse{}, nil
+		return pagination.Page[*model.CommunityPostResponse]{}, err
 	}
 
 	return posts, nil
 }
 
-func (cs *CommunitiesServiceImpl) CreateCommunityPost(communityId uuid.UUID, userId uuid.UUID, content string, title string) error {
-	userInCommunity := cs.r.CheckIfUserIsInCommunity(communityId, userId)
+func (cs *CommunitiesServiceImpl) CreateCommunityPost(ctx context.Context, communityId uuid.UUID, userId uuid.UUID, content string, title string) error {
+	userInCommunity := cs.r.CheckIfUserIsInCommunity(ctx, communityId, userId)
 	if !userInCommunity {
 		return ErrUserNotInCommunity
 	}
 
-	err := cs.r.CreateCommunityPost(communityId, userId, content, title)
+	// Members blocked after joining can't post either
+	if cs.r.CheckIfBlockedByOwner(ctx, communityId, userId) {
+		return ErrBlockedByOwner
+	}
+
+	err := cs.r.CreateCommunityPost(ctx, communityId, userId, content, title)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// EditCommunityPost changes the post, only its author or a moderator can do it.
+func (cs *CommunitiesServiceImpl) EditCommunityPost(ctx context.Context, communityId uuid.UUID, postId uuid.UUID, userId uuid.UUID, role auth.Role, content string, title string) error {
+	if err := cs.checkPostAuthor(ctx, communityId, postId, userId, role); err != nil {
+		return err
+	}
+
+	err := cs.r.UpdateCommunityPost(ctx, postId, content, title)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteCommunityPost deletes the post, only its author or a moderator can do it.
+func (cs *CommunitiesServiceImpl) DeleteCommunityPost(ctx context.Context, communityId uuid.UUID, postId uuid.UUID, userId uuid.UUID, role auth.Role) error {
+	if err := cs.checkPostAuthor(ctx, communityId, postId, userId, role); err != nil {
+		return err
+	}
+
+	err := cs.r.DeleteCommunityPost(ctx, postId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cs *CommunitiesServiceImpl) checkPostAuthor(ctx context.Context, communityId uuid.UUID, postId uuid.UUID, userId uuid.UUID, role auth.Role) error {
+	if !cs.r.CheckIFCommunityExists(ctx, communityId) {
+		return ErrCommunityNotFound
+	}
+
+	author, err := cs.r.GetCommunityPostAuthor(ctx, communityId, postId)
 	if err != nil {
+		if err == repository.ErrPostNotFound {
+			return ErrPostNotFound
+		}
 		return err
 	}
+
+	if author != userId && !role.CanModerate() {
+		return ErrUserNotPostAuthor
+	}
 	return nil
 }
 
-func (cs *CommunitiesServiceImpl) LeaveCommunity(communityId uuid.UUID, userId uuid.UUID) error {
-	userInCommunity := cs.r.CheckIfUserIsInCommunity(communityId, userId)
+func (cs *CommunitiesServiceImpl) LeaveCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error {
+	userInCommunity := cs.r.CheckIfUserIsInCommunity(ctx, communityId, userId)
 	if !userInCommunity {
 		return ErrUserNotInCommunity
 	}
 
-	err := cs.r.LeaveCommunity(communityId, userId)
+	err := cs.r.LeaveCommunity(ctx, communityId, userId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (cs *CommunitiesServiceImpl) DeleteCommunity(communityId uuid.UUID, userId uuid.UUID) error {
-	exists := cs.r.CheckIFCommunityExists(communityId)
+func (cs *CommunitiesServiceImpl) DeleteCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error {
+	exists := cs.r.CheckIFCommunityExists(ctx, communityId)
 	if !exists {
 		return ErrCommunityNotFound
 	}
 
-	if !cs.r.CheckIfUserIsCreator(communityId, userId) {
+	if !cs.r.CheckIfUserIsCreator(ctx, communityId, userId) {
 		return ErrUserNotCreator
 	}
 
-	err := cs.r.DeleteCommunity(communityId)
+	err := cs.r.DeleteCommunity(ctx, communityId)
 	if err != nil {
 		return err
 	}
@@ -1,10 +1,16 @@
 package service
 
 import (
+	"context"
+
 	"errors"
 
 	"github.com/betterreads/internal/domains/communities/model"
 	userModel "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -13,18 +19,23 @@ var (
 	ErrUserNotInCommunity     = errors.New("user is not in community")
 	ErrCommunityNotFound      = errors.New("community not found")
 	ErrUserNotCreator         = errors.New("user is not the creator")
+	ErrBlockedByOwner         = errors.New("user blocked by the creator of the community")
+	ErrPostNotFound           = errors.New("post not found")
+	ErrUserNotPostAuthor      = errors.New("user is not the author of the post")
 )
 
 type CommunitiesService interface {
-	CreateCommunity(community model.NewCommunityRequest, userId uuid.UUID) (*model.CommunityResponse, error)
-	GetCommunities(userId uuid.UUID) ([]*model.CommunityResponse, error)
-	JoinCommunity(communityId uuid.UUID, userId uuid.UUID) error
-	GetCommunityUsers(communityId uuid.UUID) ([]*userModel.UserStageResponse, error)
-	GetCommunityPicture(communityId uuid.UUID) ([]byte, error)
-	SearchComunnity(search string, currId uuid.UUID) ([]*model.CommunityResponse, error)
-	GetCommunityById(id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error)
-	GetCommunityPosts(communityId uuid.UUID) ([]*model.CommunityPostResponse, error)
-	CreateCommunityPost(communityId uuid.UUID, userId uuid.UUID, content string, title string) error
-	LeaveCommunity(communityId uuid.UUID, userId uuid.UUID) error
-	DeleteCommunity(communityId uuid.UUID, userId uuid.UUID) error
+	CreateCommunity(ctx context.Context, community model.NewCommunityRequest, userId uuid.UUID) (*model.CommunityResponse, error)
+	GetCommunities(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityResponse], error)
+	JoinCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error
+	GetCommunityUsers(ctx context.Context, communityId uuid.UUID, page pagination.Request) (pagination.Page[*userModel.UserStageResponse], error)
+	GetCommunityPicture(ctx context.Context, communityId uuid.UUID, size images.Size) (*storage.Blob, error)
+	SearchComunnity(ctx context.Context, search string, currId uuid.UUID) ([]*model.CommunityResponse, error)
+	GetCommunityById(ctx context.Context, id uuid.UUID, userId uuid.UUID) (*model.CommunityResponse, error)
+	GetCommunityPosts(ctx context.Context, communityId uuid.UUID, viewerId uuid.UUID, page pagination.Request) (pagination.Page[*model.CommunityPostResponse], error)
+	CreateCommunityPost(ctx context.Context, communityId uuid.UUID, userId uuid.UUID, content string, title string) error
+	EditCommunityPost(ctx context.Context, communityId uuid.UUID, postId uuid.UUID, userId uuid.UUID, role auth.Role, content string, title string) error
+	DeleteCommunityPost(ctx context.Context, communityId uuid.UUID, postId uuid.UUID, userId uuid.UUID, role auth.Role) error
+	LeaveCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error
+	DeleteCommunity(ctx context.Context, communityId uuid.UUID, userId uuid.UUID) error
 }
@@ -9,6 +9,7 @@ import (
 	us "github.com/betterreads/internal/domains/users/service"
 	aux "github.com/betterreads/internal/pkg/controller"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,17 +26,27 @@ func NewFeedController(fs service.FeedService) *FeedController {
 // @Description Get feed. The type of posts can be : ["post", "rating"]
 // @Tags feed
 // @Produce json
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Error 400 {object} ErrorResponse
 // @Error 404 {object} ErrorResponse
 // @Error 500 {object} ErrorResponse
-// @Success 200 {object} []models.PostDTO
+// @Success 200 {object} pagination.Page[models.PostDTO]
 // @Router /feed [get]
 func (fc *FeedController) GetFeed(c *gin.Context) {
 	userId, errId := aux.GetLoggedUserId(c)
 	if errId != nil {
 		c.AbortWithError(errId.Status, errId)
+		return
 	}
 
-	posts, err := fc.fs.GetFeed(userId)
+	page, errPage := aux.GetPageRequest(c)
+	if errPage != nil {
+		c.AbortWithError(errPage.Status, errPage)
+		return
+	}
+
+	posts, err := fc.fs.GetFeed(c.Request.Context(), userId, page)
 
 	if err != nil {
 		if errors.Is(err, us.ErrUserNotFound) {
@@ -47,7 +58,7 @@ func (fc *FeedController) GetFeed(c *gin.Context) {
 		}
 	}
 
-	res := parsePosts(posts)
+	res := pagination.Page[models.PostDTO]{Data: parsePosts(posts.Data), NextCursor: posts.NextCursor}
 
 	c.JSON(http.StatusOK, res)
 }
@@ -55,19 +66,10 @@ func (fc *FeedController) GetFeed(c *gin.Context) {
 func parsePosts(posts []models.Post) []models.PostDTO {
 	res := make([]models.PostDTO, 0)
 	for _, post := range posts {
-		var dto models.PostDTO
-		if post.Rating != nil {
-			dto = models.PostDTO{
-				Type: "rating",
-				Post: post,
-			}
-		} else {
-			dto = models.PostDTO{
-				Type: "post",
-				Post: post,
-			}
-		}
-		res = append(res, dto)
+		res = append(res, models.PostDTO{
+			Type: post.Kind,
+			Post: post,
+		})
 	}
 	return res
 }
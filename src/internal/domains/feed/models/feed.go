@@ -12,12 +12,26 @@ type Post struct {
 	BookTitle       string    `json:"book_title" db:"title"`
 	BookDescription string    `json:"book_description" db:"description"`
 	PublicationDate string    `json:"publication_date" db:"publication_date"`
-	// Ratings can be null to have the two posts.
+	// Ratings can be null to have the three posts.
 	// - The publication of a book
 	// - The rating of a book
-	Rating *int `json:"rating,omitempty" db:"rating"`
+	// - The reading progress of a book, with its page, percent and note
+	Rating  *int     `json:"rating,omitempty" db:"rating"`
+	Page    *int     `json:"page,omitempty" db:"page"`
+	Percent *float64 `json:"percent,omitempty" db:"percent"`
+	Note    *string  `json:"note,omitempty" db:"note"`
+	// One of PostType*
+	Kind string `json:"-" db:"kind"`
+	// Identifies a post among the ones with the same publication date
+	Key string `json:"-" db:"post_key"`
 }
 
+const (
+	PostTypePublication = "post"
+	PostTypeRating      = "rating"
+	PostTypeProgress    = "progress"
+)
+
 type PostDTO struct {
 	Type string `json:"type"`
 	Post Post   `json:"post"`
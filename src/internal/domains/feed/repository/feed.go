@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/betterreads/internal/domains/feed/models"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
 type FeedRepository interface {
-	GetFeed(userId uuid.UUID) ([]models.Post, error)
+	GetFeed(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[models.Post], error)
 }
\ No newline at end of file
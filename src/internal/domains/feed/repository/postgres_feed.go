@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/betterreads/internal/domains/feed/models"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -16,10 +19,30 @@ func NewPostgresFeedRepository(db *sqlx.DB) FeedRepository {
 	return &PostgresFeedRepository{db: db}
 }
 
-func (pfr *PostgresFeedRepository) GetFeed(userId uuid.UUID) ([]models.Post, error) {
+func (pfr *PostgresFeedRepository) GetFeed(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[models.Post], error) {
 	posts := make([]models.Post, 0)
 
+	// Books and reviews come from friends and followed users, reading progress only from friends.
+	// Muted users and the ones blocked either way are hidden
 	mega_query := `
+    with friends_of as (
+        select case when fr.user_a_id = $1 then fr.user_b_id else fr.user_a_id end as user_id
+        from friends fr
+        where fr.user_a_id = $1 or fr.user_b_id = $1
+    ),
+    sources as (
+        select user_id from friends_of
+        union
+        select fl.followee_id as user_id from follows fl where fl.follower_id = $1
+    ),
+    hidden as (
+        select mu.muted_id as user_id from mutes mu where mu.muter_id = $1
+        union
+        select bl.blocked_id as user_id from blocks bl where bl.blocker_id = $1
+        union
+        select bl.blocker_id as user_id from blocks bl where bl.blocked_id = $1
+    )
+    select * from (
     select us.id as user_id, 
         us.username,  
         bk.id as book_id, 
@@ -27,13 +50,18 @@ func (pfr *PostgresFeedRepository) GetFeed(userId uuid.UUID) ([]models.Post, err
         bk.title, 
         bk.description, 
         bk.publication_date, 
-        null AS rating
+        null AS rating,
+        null::INTEGER AS page,
+        null::NUMERIC AS percent,
+        null AS note,
+        'post' AS kind,
+        bk.id::TEXT || us.id::TEXT || 'p' AS post_key
     from users us
-    join friends fr on us.id = fr.user_a_id or us.id = fr.user_b_id 
+    join sources s on s.user_id = us.id
     join books bk  on us.id = bk.author 
     where us.is_author = true 
-        and ( fr.user_a_id = $1 or fr.user_b_id  = $1) 
-        and us.id != $1
+        and bk.deleted_at is null
+        and us.id not in (select user_id from hidden)
     union 
     select 
         us.id as user_id,
@@ -43,20 +71,59 @@ func (pfr *PostgresFeedRepository) GetFeed(userId uuid.UUID) ([]models.Post, err
         bk.title,
         bk.description,
         r.publication_date,
-        r.rating 
+        r.rating,
+        null::INTEGER AS page,
+        null::NUMERIC AS percent,
+        null AS note,
+        'rating' AS kind,
+        bk.id::TEXT || us.id::TEXT || 'r' AS post_key
     from users us
-    join friends fr on us.id = fr.user_a_id or us.id = fr.user_b_id 
+    join sources s on s.user_id = us.id
     join reviews r on r.user_id = us.id
     join books bk on r.book_id = bk.id 
-    where ( fr.user_a_id = $1 or fr.user_b_id  = $1) 
-        and us.id != $1
-    ORDER BY publication_date DESC;
+    where bk.deleted_at is null
+        and us.id not in (select user_id from hidden)
+    union 
+    select 
+        us.id as user_id,
+        us.username,
+        bk.id as book_id,
+        (select us.username from users us where us.id = bk.author) as author_name,
+        bk.title,
+        bk.description,
+        to_char(p.created_at, 'YYYY-MM-DD') as publication_date,
+        null AS rating,
+        p.page,
+        p.percent,
+        NULLIF(p.note, '') AS note,
+        'progress' AS kind,
+        to_char(p.created_at, 'HH24:MI:SS.US') || p.id::TEXT AS post_key
+    from users us
+    join friends_of f on f.user_id = us.id
+    join reading_progress p on p.user_id = us.id
+    join books bk on p.book_id = bk.id 
+    where bk.deleted_at is null
+        and us.id not in (select user_id from hidden)
+    ) feed
     `
-	err := pfr.db.Select(&posts, mega_query, userId)
+	args := []interface{}{userId}
+	if page.Cursor != nil {
+		mega_query += `where (publication_date, post_key) < ($2, $3)
+    `
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	mega_query += fmt.Sprintf(`ORDER BY publication_date DESC, post_key DESC
+    LIMIT $%d;
+    `, len(args)+1)
+	args = append(args, page.Fetch())
+
+	err := pfr.db.SelectContext(ctx, &posts, mega_query, args...)
 	if err != nil {
 		if err != sql.ErrNoRows {
-			return nil, err
+			return pagination.Page[models.Post]{}, err
 		}
 	}
-	return posts, nil
+	return pagination.NewPage(posts, page, func(post models.Post) pagination.Cursor {
+		return pagination.Cursor{Key: post.PublicationDate, Id: post.Key}
+	}), nil
 }
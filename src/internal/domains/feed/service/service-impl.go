@@ -1,9 +1,12 @@
 package service
 
 import (
+	"context"
+
 	"github.com/betterreads/internal/domains/feed/models"
 	"github.com/betterreads/internal/domains/feed/repository"
 	us "github.com/betterreads/internal/domains/users/service"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
@@ -16,14 +19,14 @@ func NewFeedServiceImpl(fr repository.FeedRepository, us us.UsersService) FeedSe
 	return &FeedServiceImpl{fr: fr, us: us}
 }
 
-func (fs *FeedServiceImpl) GetFeed(userId uuid.UUID) ([]models.Post, error) {
-	if !fs.us.CheckUserExists(userId) {
-		return nil, us.ErrUserNotFound
+func (fs *FeedServiceImpl) GetFeed(ctx context.Context, userId uuid.UUID, page pagination.Request) (pagination.Page[models.Post], error) {
+	if !fs.us.CheckUserExists(ctx, userId) {
+		return pagination.Page[models.Post]{}, us.ErrUserNotFound
 	}
 
-	posts, err := fs.fr.GetFeed(userId)
+	posts, err := fs.fr.GetFeed(ctx, userId, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[models.Post]{}, err
 	}
 	return posts, nil
 }
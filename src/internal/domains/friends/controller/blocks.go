@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/friends/service"
+	"github.com/betterreads/internal/domains/users/models"
+	usersService "github.com/betterreads/internal/domains/users/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Block godoc
+// @Summary Block a user
+// @Description Block a user. Removes the friendship, friend requests and follows between the users, and hides each other's reviews, posts and feed items
+// @Tags Friends
+// @Produce json
+// @Param Id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/blocks [post]
+func (fc FriendsController) Block(ctx *gin.Context) {
+	blockerId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	blockedId, err := uuid.Parse(ctx.Query("Id"))
+	if err != nil {
+		errorDetails := er.NewErrorDetails("Error When blocking user", err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	if err := fc.FriendsService.Block(ctx.Request.Context(), blockerId, blockedId); err != nil {
+		if errors.Is(err, usersService.ErrUserNotFound) {
+			errorDetails := er.NewErrorDetails("Error When blocking user", err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else if errors.Is(err, service.ErrBlockSelf) {
+			errorDetails := er.NewErrorDetails("Error When blocking user", err, http.StatusBadRequest)
+			ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		} else if errors.Is(err, service.ErrAlreadyBlocked) {
+			errorDetails := er.NewErrorDetails("Error When blocking user", err, http.StatusConflict)
+			ctx.AbortWithError(http.StatusConflict, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails("Error When blocking user", err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+// Unblock godoc
+// @Summary Unblock a user
+// @Description Unblock a user, the friendship and follows removed by the block aren't restored
+// @Tags Friends
+// @Produce json
+// @Param Id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/blocks [delete]
+func (fc FriendsController) Unblock(ctx *gin.Context) {
+	blockerId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	blockedId, err := uuid.Parse(ctx.Query("Id"))
+	if err != nil {
+		errorDetails := er.NewErrorDetails("Error When unblocking user", err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	if err := fc.FriendsService.Unblock(ctx.Request.Context(), blockerId, blockedId); err != nil {
+		if errors.Is(err, service.ErrBlockNotFound) {
+			errorDetails := er.NewErrorDetails("Error When unblocking user", err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails("Error When unblocking user", err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+// GetBlocked godoc
+// @Summary Get Blocked users
+// @Description Get the users blocked by the user logged in
+// @Tags Friends
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Produce json
+// @Success 200 {object} pagination.Page[models.UserResponse]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/blocks [get]
+func (fc FriendsController) GetBlocked(ctx *gin.Context) {
+	fc.getOwnList(ctx, "Error When getting Blocked users", fc.FriendsService.GetBlocked)
+}
+
+// Mute godoc
+// @Summary Mute a user
+// @Description Mute a user, their books, reviews and reading progress are hidden from the feed. They aren't told about it
+// @Tags Friends
+// @Produce json
+// @Param Id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/mutes [post]
+func (fc FriendsController) Mute(ctx *gin.Context) {
+	muterId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	mutedId, err := uuid.Parse(ctx.Query("Id"))
+	if err != nil {
+		errorDetails := er.NewErrorDetails("Error When muting user", err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	if err := fc.FriendsService.Mute(ctx.Request.Context(), muterId, mutedId); err != nil {
+		if errors.Is(err, usersService.ErrUserNotFound) {
+			errorDetails := er.NewErrorDetails("Error When muting user", err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else if errors.Is(err, service.ErrMuteSelf) {
+			errorDetails := er.NewErrorDetails("Error When muting user", err, http.StatusBadRequest)
+			ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		} else if errors.Is(err, service.ErrAlreadyMuted) {
+			errorDetails := er.NewErrorDetails("Error When muting user", err, http.StatusConflict)
+			ctx.AbortWithError(http.StatusConflict, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails("Error When muting user", err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User muted"})
+}
+
+// Unmute godoc
+// @Summary Unmute a user
+// @Description Unmute a user
+// @Tags Friends
+// @Produce json
+// @Param Id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/mutes [delete]
+func (fc FriendsController) Unmute(ctx *gin.Context) {
+	muterId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	mutedId, err := uuid.Parse(ctx.Query("Id"))
+	if err != nil {
+		errorDetails := er.NewErrorDetails("Error When unmuting user", err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	if err := fc.FriendsService.Unmute(ctx.Request.Context(), muterId, mutedId); err != nil {
+		if errors.Is(err, service.ErrMuteNotFound) {
+			errorDetails := er.NewErrorDetails("Error When unmuting user", err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails("Error When unmuting user", err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User unmuted"})
+}
+
+// GetMuted godoc
+// @Summary Get Muted users
+// @Description Get the users muted by the user logged in
+// @Tags Friends
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Produce json
+// @Success 200 {object} pagination.Page[models.UserResponse]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/mutes [get]
+func (fc FriendsController) GetMuted(ctx *gin.Context) {
+	fc.getOwnList(ctx, "Error When getting Muted users", fc.FriendsService.GetMuted)
+}
+
+// getOwnList answers with a page of a list of the user logged in
+func (fc FriendsController) getOwnList(ctx *gin.Context, title string, get func(context.Context, uuid.UUID, pagination.Request) (pagination.Page[models.UserResponse], error)) {
+	id, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	page, errDetails := aux.GetPageRequest(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	users, err := get(ctx.Request.Context(), id, page)
+	if err != nil {
+		errorDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		return
+	}
+	ctx.JSON(http.StatusOK, users)
+}
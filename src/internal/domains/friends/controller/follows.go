@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/friends/service"
+	"github.com/betterreads/internal/domains/users/models"
+	usersService "github.com/betterreads/internal/domains/users/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Follow godoc
+// @Summary Follow a user
+// @Description Follow a user without their approval, their books and reviews show up in the feed
+// @Tags Friends
+// @Produce json
+// @Param Id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/follows [post]
+func (fc FriendsController) Follow(ctx *gin.Context) {
+	followerId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	followeeId, err := uuid.Parse(ctx.Query("Id"))
+	if err != nil {
+		errorDetails := er.NewErrorDetails("Error When following user", err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	if err := fc.FriendsService.Follow(ctx.Request.Context(), followerId, followeeId); err != nil {
+		if errors.Is(err, usersService.ErrUserNotFound) {
+			errorDetails := er.NewErrorDetails("Error When following user", err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else if errors.Is(err, service.ErrFollowSelf) {
+			errorDetails := er.NewErrorDetails("Error When following user", err, http.StatusBadRequest)
+			ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		} else if errors.Is(err, service.ErrUserBlocked) {
+			errorDetails := er.NewErrorDetails("Error When following user", err, http.StatusForbidden)
+			ctx.AbortWithError(http.StatusForbidden, errorDetails)
+		} else if errors.Is(err, service.ErrAlreadyFollowing) {
+			errorDetails := er.NewErrorDetails("Error When following user", err, http.StatusConflict)
+			ctx.AbortWithError(http.StatusConflict, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails("Error When following user", err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User followed"})
+}
+
+// Unfollow godoc
+// @Summary Unfollow a user
+// @Description Stop following a user
+// @Tags Friends
+// @Produce json
+// @Param Id query string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/follows [delete]
+func (fc FriendsController) Unfollow(ctx *gin.Context) {
+	followerId, errId := aux.GetLoggedUserId(ctx)
+	if errId != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, errId)
+		return
+	}
+
+	followeeId, err := uuid.Parse(ctx.Query("Id"))
+	if err != nil {
+		errorDetails := er.NewErrorDetails("Error When unfollowing user", err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	if err := fc.FriendsService.Unfollow(ctx.Request.Context(), followerId, followeeId); err != nil {
+		if errors.Is(err, service.ErrFollowNotFound) {
+			errorDetails := er.NewErrorDetails("Error When unfollowing user", err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails("Error When unfollowing user", err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User unfollowed"})
+}
+
+// GetFollowers godoc
+// @Summary Get Followers
+// @Description Get the users following a user
+// @Tags Friends
+// @Param id path string true "User ID"
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Produce json
+// @Success 200 {object} pagination.Page[models.UserResponse]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/followers [get]
+func (fc FriendsController) GetFollowers(ctx *gin.Context) {
+	fc.getFollows(ctx, "Error When getting Followers", fc.FriendsService.GetFollowers)
+}
+
+// GetFollowing godoc
+// @Summary Get Following
+// @Description Get the users a user follows
+// @Tags Friends
+// @Param id path string true "User ID"
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
+// @Produce json
+// @Success 200 {object} pagination.Page[models.UserResponse]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/{id}/following [get]
+func (fc FriendsController) GetFollowing(ctx *gin.Context) {
+	fc.getFollows(ctx, "Error When getting Following", fc.FriendsService.GetFollowing)
+}
+
+func (fc FriendsController) getFollows(ctx *gin.Context, title string, get func(context.Context, uuid.UUID, pagination.Request) (pagination.Page[models.UserResponse], error)) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		err := fmt.Errorf("Invalid User ID")
+		errorDetails := er.NewErrorDetails(title, err, http.StatusBadRequest)
+		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
+		return
+	}
+
+	page, errDetails := aux.GetPageRequest(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	users, err := get(ctx.Request.Context(), id, page)
+	if err != nil {
+		if errors.Is(err, usersService.ErrUserNotFound) {
+			errorDetails := er.NewErrorDetails(title, err, http.StatusNotFound)
+			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else {
+			errorDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+			ctx.AbortWithError(http.StatusInternalServerError, errorDetails)
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, users)
+}
@@ -27,8 +27,11 @@ func NewFriendsController(fs service.FriendsService) FriendsController {
 // @Description Get Friends of user logged in
 // @Tags Friends
 // @Param id path string true "User ID"
+// @Param limit query int false "Page size, between 1 and 100"
+// @Param cursor query string false "Cursor of the next page"
 // @Produce json
-// @Success 200 {array} []models.UserResponse
+// @Success 200 {object} pagination.Page[models.UserResponse]
+// @Failure 400 {object} errors.ErrorDetailsWithParams
 // @Failure 404 {object} errors.ErrorDetails
 // @Failure 500 {object} errors.ErrorDetails
 // @Router /users/{id}/friends [get]
@@ -41,7 +44,13 @@ func (fc *FriendsController) GetFriends(ctx *gin.Context) {
 		return
 	}
 
-	friends, err := fc.FriendsService.GetFriends(id)
+	page, errDetails := aux.GetPageRequest(ctx)
+	if errDetails != nil {
+		ctx.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	friends, err := fc.FriendsService.GetFriends(ctx.Request.Context(), id, page)
 	if err != nil {
 		if errors.Is(err, usersService.ErrUserNotFound) {
 			errorDetails := er.NewErrorDetails("Error When getting Friends", err, http.StatusNotFound)
@@ -62,6 +71,7 @@ func (fc *FriendsController) GetFriends(ctx *gin.Context) {
 // @Produce json
 // @Param Id query string true "Friend ID"
 // @Success 200 {object} map[string]string
+// @Failure 403 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetails
 // @Failure 409 {object} errors.ErrorDetails
 // @Failure 500 {object} errors.ErrorDetails
@@ -80,7 +90,7 @@ func (fc *FriendsController) AddFriend(ctx *gin.Context) {
 		return
 	}
 
-	err = fc.FriendsService.AddFriend(senderId, recipientId)
+	err = fc.FriendsService.AddFriend(ctx.Request.Context(), senderId, recipientId)
 	if err != nil {
 		if errors.Is(err, usersService.ErrUserNotFound) {
 			errorDetails := er.NewErrorDetails("Error When adding friend", err, http.StatusNotFound)
@@ -91,6 +101,9 @@ func (fc *FriendsController) AddFriend(ctx *gin.Context) {
 		} else if errors.Is(err, service.ErrUserFriendNotFound) {
 			errorDetails := er.NewErrorDetails("Error When adding friend", err, http.StatusNotFound)
 			ctx.AbortWithError(http.StatusNotFound, errorDetails)
+		} else if errors.Is(err, service.ErrUserBlocked) {
+			errorDetails := er.NewErrorDetails("Error When adding friend", err, http.StatusForbidden)
+			ctx.AbortWithError(http.StatusForbidden, errorDetails)
 		} else if errors.Is(err, service.ErrFriendRequestExists) {
 			errorDetails := er.NewErrorDetails("Error When adding friend", err, http.StatusConflict)
 			ctx.AbortWithError(http.StatusConflict, errorDetails)
@@ -129,7 +142,7 @@ func (fc *FriendsController) AcceptFriendRequest(ctx *gin.Context) {
 		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
 		return
 	}
-	err = fc.FriendsService.AcceptFriendRequest(recipientId, senderId)
+	err = fc.FriendsService.AcceptFriendRequest(ctx.Request.Context(), recipientId, senderId)
 	if err != nil {
 		if errors.Is(err, service.ErrRequestNotFound) {
 			errorDetails := er.NewErrorDetails("Error When accepting friend", err, http.StatusNotFound)
@@ -165,8 +178,7 @@ func (fc FriendsController) RejectFriendRequest(ctx *gin.Context) {
 		ctx.AbortWithError(http.StatusBadRequest, errorDetails)
 		return
 	}
-	fmt.Printf("senderId: %v, recipientId: %v \n", senderId, recipientId)
-	err = fc.FriendsService.RejectFriendRequest(recipientId, senderId)
+	err = fc.FriendsService.RejectFriendRequest(ctx.Request.Context(), recipientId, senderId)
 	if err != nil {
 		if errors.Is(err, service.ErrRequestNotFound) {
 			errorDetails := er.NewErrorDetails("Error When declining friend", err, http.StatusNotFound)
@@ -195,7 +207,7 @@ func (fc FriendsController) GetFriendsRequestSent(ctx *gin.Context) {
 		ctx.AbortWithError(http.StatusUnauthorized, errId)
 		return
 	}
-	friends, err := fc.FriendsService.GetFriendRequestsSent(id)
+	friends, err := fc.FriendsService.GetFriendRequestsSent(ctx.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, usersService.ErrUserNotFound) {
 			errorDetails := er.NewErrorDetails("Error When getting Friends Request Sent", err, http.StatusNotFound)
@@ -224,7 +236,7 @@ func (fc FriendsController) GetFriendRequestsReceived(ctx *gin.Context) {
 		ctx.AbortWithError(http.StatusUnauthorized, errId)
 		return
 	}
-	friends, err := fc.FriendsService.GetFriendRequestsReceived(id)
+	friends, err := fc.FriendsService.GetFriendRequestsReceived(ctx.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, usersService.ErrUserNotFound) {
 			errorDetails := er.NewErrorDetails("Error When getting Friends Request Received", err, http.StatusNotFound)
@@ -262,7 +274,7 @@ func (fc FriendsController) DeleteFriend(ctx *gin.Context) {
 		return
 	}
 
-	if err := fc.FriendsService.DeleteFriend(id, friendId); err != nil {
+	if err := fc.FriendsService.DeleteFriend(ctx.Request.Context(), id, friendId); err != nil {
 		if errors.Is(err, service.ErrFriendShipNotFound) {
 			errorDetails := er.NewErrorDetails("Error When deleting friend", err, http.StatusNotFound)
 			ctx.AbortWithError(http.StatusNotFound, errorDetails)
@@ -1,18 +1,35 @@
 package repository
 
 import (
+	"context"
+
 	um "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
 type FriendsRepository interface {
-	GetFriends(userID uuid.UUID) ([]um.UserResponse, error)
-	AddFriend(sender uuid.UUID, recipient uuid.UUID) error
-	AcceptFriendRequest(recipient uuid.UUID, sender uuid.UUID) error
-	RejectFriendRequest(recipient uuid.UUID, sender uuid.UUID) error
-	GetFriendRequestsSent(sender uuid.UUID) ([]um.UserResponse, error)
-	GetFriendRequestsReceived(recipient uuid.UUID) ([]um.UserResponse, error)
-	CheckIfFriendRequestExists(sender uuid.UUID, recipient uuid.UUID) bool
-	CheckIfFriendShipExists(userA uuid.UUID, userB uuid.UUID) bool
-    DeleteFriendship(userA uuid.UUID, userB uuid.UUID) error
+	GetFriends(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error)
+	AddFriend(ctx context.Context, sender uuid.UUID, recipient uuid.UUID) error
+	AcceptFriendRequest(ctx context.Context, recipient uuid.UUID, sender uuid.UUID) error
+	RejectFriendRequest(ctx context.Context, recipient uuid.UUID, sender uuid.UUID) error
+	GetFriendRequestsSent(ctx context.Context, sender uuid.UUID) ([]um.UserResponse, error)
+	GetFriendRequestsReceived(ctx context.Context, recipient uuid.UUID) ([]um.UserResponse, error)
+	CheckIfFriendRequestExists(ctx context.Context, sender uuid.UUID, recipient uuid.UUID) bool
+	CheckIfFriendShipExists(ctx context.Context, userA uuid.UUID, userB uuid.UUID) bool
+    DeleteFriendship(ctx context.Context, userA uuid.UUID, userB uuid.UUID) error
+	Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	CheckIfFollowExists(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) bool
+	GetFollowers(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error)
+	GetFollowing(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error)
+	Block(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error
+	Unblock(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error
+	CheckIfBlockExists(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) bool
+	CheckIfBlocked(ctx context.Context, userA uuid.UUID, userB uuid.UUID) bool
+	GetBlocked(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error)
+	Mute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error
+	Unmute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error
+	CheckIfMuteExists(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) bool
+	GetMuted(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error)
 }
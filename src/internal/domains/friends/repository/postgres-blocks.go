@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	um "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+// Block saves the block and removes the friendship, friend requests and follows between the users.
+func (c PostgresFriendsRepository) Block(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error {
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`INSERT INTO blocks (blocker_id, blocked_id) VALUES ($1, $2)`,
+		`DELETE FROM friends WHERE (user_a_id = $1 AND user_b_id = $2) OR (user_a_id = $2 AND user_b_id = $1)`,
+		`DELETE FROM friends_requests WHERE (sender_id = $1 AND recipient_id = $2) OR (sender_id = $2 AND recipient_id = $1)`,
+		`DELETE FROM follows WHERE (follower_id = $1 AND followee_id = $2) OR (follower_id = $2 AND followee_id = $1)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+			return fmt.Errorf("failed to block user: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+	return nil
+}
+
+func (c PostgresFriendsRepository) Unblock(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error {
+	query := `DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2`
+	_, err := c.db.ExecContext(ctx, query, blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return nil
+}
+
+func (c PostgresFriendsRepository) CheckIfBlockExists(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) bool {
+	query := `SELECT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = $1 AND blocked_id = $2)`
+	var exists bool
+	err := c.db.GetContext(ctx, &exists, query, blockerId, blockedId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if block exists", "error", err)
+		return false
+	}
+	return exists
+}
+
+// CheckIfBlocked is true when any of the users blocked the other
+func (c PostgresFriendsRepository) CheckIfBlocked(ctx context.Context, userA uuid.UUID, userB uuid.UUID) bool {
+	query := `SELECT EXISTS (SELECT 1 FROM blocks WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1))`
+	var exists bool
+	err := c.db.GetContext(ctx, &exists, query, userA, userB)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if blocked", "error", err)
+		return false
+	}
+	return exists
+}
+
+func (c PostgresFriendsRepository) GetBlocked(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error) {
+	return c.getUserList(ctx, `SELECT us.* FROM blocks bl JOIN users us ON us.id = bl.blocked_id WHERE bl.blocker_id = $1`, userID, page)
+}
+
+func (c PostgresFriendsRepository) Mute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error {
+	query := `INSERT INTO mutes (muter_id, muted_id) VALUES ($1, $2)`
+	_, err := c.db.ExecContext(ctx, query, muterId, mutedId)
+	if err != nil {
+		return fmt.Errorf("failed to mute user: %w", err)
+	}
+	return nil
+}
+
+func (c PostgresFriendsRepository) Unmute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error {
+	query := `DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2`
+	_, err := c.db.ExecContext(ctx, query, muterId, mutedId)
+	if err != nil {
+		return fmt.Errorf("failed to unmute user: %w", err)
+	}
+	return nil
+}
+
+func (c PostgresFriendsRepository) CheckIfMuteExists(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) bool {
+	query := `SELECT EXISTS (SELECT 1 FROM mutes WHERE muter_id = $1 AND muted_id = $2)`
+	var exists bool
+	err := c.db.GetContext(ctx, &exists, query, muterId, mutedId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if mute exists", "error", err)
+		return false
+	}
+	return exists
+}
+
+func (c PostgresFriendsRepository) GetMuted(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error) {
+	return c.getUserList(ctx, `SELECT us.* FROM mutes mu JOIN users us ON us.id = mu.muted_id WHERE mu.muter_id = $1`, userID, page)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	um "github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/domains/users/utils"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+func (c PostgresFriendsRepository) Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	query := `INSERT INTO follows (follower_id, followee_id) VALUES ($1, $2)`
+	_, err := c.db.ExecContext(ctx, query, followerId, followeeId)
+	if err != nil {
+		return fmt.Errorf("failed to follow user: %w", err)
+	}
+	return nil
+}
+
+func (c PostgresFriendsRepository) Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	query := `DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`
+	_, err := c.db.ExecContext(ctx, query, followerId, followeeId)
+	if err != nil {
+		return fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	return nil
+}
+
+func (c PostgresFriendsRepository) CheckIfFollowExists(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) bool {
+	query := `SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2)`
+	var exists bool
+	err := c.db.GetContext(ctx, &exists, query, followerId, followeeId)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if follow exists", "error", err)
+		return false
+	}
+	return exists
+}
+
+// GetFollowers returns the users following the user
+func (c PostgresFriendsRepository) GetFollowers(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error) {
+	return c.getUserList(ctx, `SELECT us.* FROM follows fl JOIN users us ON us.id = fl.follower_id WHERE fl.followee_id = $1`, userID, page)
+}
+
+// GetFollowing returns the users the user follows
+func (c PostgresFriendsRepository) GetFollowing(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error) {
+	return c.getUserList(ctx, `SELECT us.* FROM follows fl JOIN users us ON us.id = fl.followee_id WHERE fl.follower_id = $1`, userID, page)
+}
+
+// getUserList pages the users of a query that takes the user id as $1, by username
+func (c PostgresFriendsRepository) getUserList(ctx context.Context, query string, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error) {
+	users := []um.UserRecord{}
+	args := []interface{}{userID}
+	if page.Cursor != nil {
+		query += ` AND (us.username, us.id::TEXT) > ($2, $3)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(` ORDER BY us.username, us.id::TEXT LIMIT $%d`, len(args)+1)
+	args = append(args, page.Fetch())
+
+	err := c.db.SelectContext(ctx, &users, query, args...)
+	if err != nil && err != sql.ErrNoRows {
+		return pagination.Page[um.UserResponse]{}, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	res := []um.UserResponse{}
+	for _, user := range users {
+		res = append(res, *utils.MapUserRecordToUserResponse(&user))
+	}
+
+	return pagination.NewPage(res, page, func(user um.UserResponse) pagination.Cursor {
+		return pagination.Cursor{Key: user.Username, Id: user.Id.String()}
+	}), nil
+}
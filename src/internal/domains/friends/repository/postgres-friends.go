@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -8,6 +9,8 @@ import (
 
 	um "github.com/betterreads/internal/domains/users/models"
 	"github.com/betterreads/internal/domains/users/utils"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
@@ -15,38 +18,11 @@ type PostgresFriendsRepository struct {
 	db *sqlx.DB
 }
 
-func NewPostgresFriendsRepository(db *sqlx.DB) (FriendsRepository, error) {
-	schema := `
-        CREATE TABLE IF NOT EXISTS friends (
-            user_a_id UUID NOT NULL,
-            user_b_id UUID NOT NULL,
-            PRIMARY KEY (user_a_id, user_b_id),
-            FOREIGN KEY (user_a_id) REFERENCES users(id),
-            FOREIGN KEY (user_b_id) REFERENCES users(id)
-        );
-    `
-
-	if _, err := db.Exec(schema); err != nil {
-		return nil, fmt.Errorf("failed to create friends table: %w", err)
-	}
-
-	schema = `
-        CREATE TABLE IF NOT EXISTS friends_requests (
-            recipient_id UUID NOT NULL,
-            sender_id UUID NOT NULL,
-            PRIMARY KEY (recipient_id, sender_id),   
-            FOREIGN KEY (recipient_id) REFERENCES users(id),
-            FOREIGN KEY (sender_id) REFERENCES users(id)
-        );
-    `
-	if _, err := db.Exec(schema); err != nil {
-		return nil, fmt.Errorf("failed to create friend_requests table: %w", err)
-	}
-
-	return &PostgresFriendsRepository{db: db}, nil
+func NewPostgresFriendsRepository(db *sqlx.DB) FriendsRepository {
+	return &PostgresFriendsRepository{db: db}
 }
 
-func (c PostgresFriendsRepository) GetFriends(userID uuid.UUID) ([]um.UserResponse, error) {
+func (c PostgresFriendsRepository) GetFriends(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[um.UserResponse], error) {
 	friends := []um.UserRecord{}
 	query := `
         SELECT 
@@ -57,12 +33,19 @@ func (c PostgresFriendsRepository) GetFriends(userID uuid.UUID) ([]um.UserRespon
                 WHEN fr.user_a_id = $1 THEN fr.user_b_id
                 ELSE fr.user_a_id 
             END
-        WHERE fr.user_a_id = $1 OR fr.user_b_id = $1
+        WHERE (fr.user_a_id = $1 OR fr.user_b_id = $1)
     `
+	args := []interface{}{userID}
+	if page.Cursor != nil {
+		query += ` AND (us.username, us.id::TEXT) > ($2, $3)`
+		args = append(args, page.Cursor.Key, page.Cursor.Id)
+	}
+	query += fmt.Sprintf(` ORDER BY us.username, us.id::TEXT LIMIT $%d`, len(args)+1)
+	args = append(args, page.Fetch())
 
-	err := c.db.Select(&friends, query, userID)
+	err := c.db.SelectContext(ctx, &friends, query, args...)
 	if err != nil && err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to get friends: %w", err)
+		return pagination.Page[um.UserResponse]{}, fmt.Errorf("failed to get friends: %w", err)
 	}
 
 	res := []um.UserResponse{}
@@ -70,59 +53,61 @@ func (c PostgresFriendsRepository) GetFriends(userID uuid.UUID) ([]um.UserRespon
 		res = append(res, *utils.MapUserRecordToUserResponse(&friend))
 	}
 
-	return res, nil
+	return pagination.NewPage(res, page, func(friend um.UserResponse) pagination.Cursor {
+		return pagination.Cursor{Key: friend.Username, Id: friend.Id.String()}
+	}), nil
 }
 
-func (c PostgresFriendsRepository) AddFriend(senderId uuid.UUID, recipientId uuid.UUID) error {
+func (c PostgresFriendsRepository) AddFriend(ctx context.Context, senderId uuid.UUID, recipientId uuid.UUID) error {
 	query := `INSERT INTO friends_requests (sender_id, recipient_id) VALUES ($1, $2)`
-	_, err := c.db.Exec(query, senderId, recipientId)
+	_, err := c.db.ExecContext(ctx, query, senderId, recipientId)
 	if err != nil {
 		return fmt.Errorf("failed to add friend: %w", err)
 	}
 	return nil
 }
 
-func (c PostgresFriendsRepository) AcceptFriendRequest(recipientId uuid.UUID, senderId uuid.UUID) error {
-	err := c.DeleteRequest(senderId, recipientId)
+func (c PostgresFriendsRepository) AcceptFriendRequest(ctx context.Context, recipientId uuid.UUID, senderId uuid.UUID) error {
+	err := c.DeleteRequest(ctx, senderId, recipientId)
 	if err != nil {
 		return fmt.Errorf("failed to accept friend request: %w", err)
 	}
 
 	// checks if the other user has also sent a friend request from the other side
-	exits := c.CheckIfFriendRequestExists(recipientId, senderId)
+	exits := c.CheckIfFriendRequestExists(ctx, recipientId, senderId)
 	if exits {
-		err = c.DeleteRequest(recipientId, senderId)
+		err = c.DeleteRequest(ctx, recipientId, senderId)
 		if err != nil {
 			return fmt.Errorf("failed to accept friend request: %w", err)
 		}
 	}
 
 	query := `INSERT INTO friends (user_a_id, user_b_id) VALUES ($1, $2)`
-	_, err = c.db.Exec(query, recipientId, senderId)
+	_, err = c.db.ExecContext(ctx, query, recipientId, senderId)
 	if err != nil {
 		return fmt.Errorf("failed to accept friend request: %w", err)
 	}
 	return nil
 }
 
-func (c *PostgresFriendsRepository) DeleteRequest(senderId uuid.UUID, recipientId uuid.UUID) error {
+func (c *PostgresFriendsRepository) DeleteRequest(ctx context.Context, senderId uuid.UUID, recipientId uuid.UUID) error {
 	query := `DELETE FROM friends_requests WHERE recipient_id= $1 AND sender_id= $2`
-	_, err := c.db.Exec(query, recipientId, senderId)
+	_, err := c.db.ExecContext(ctx, query, recipientId, senderId)
 	if err != nil {
 		return fmt.Errorf("failed to delete friend request: %w", err)
 	}
 	return nil
 }
 
-func (c PostgresFriendsRepository) RejectFriendRequest(recipientId uuid.UUID, senderId uuid.UUID) error {
-	err := c.DeleteRequest(senderId, recipientId)
+func (c PostgresFriendsRepository) RejectFriendRequest(ctx context.Context, recipientId uuid.UUID, senderId uuid.UUID) error {
+	err := c.DeleteRequest(ctx, senderId, recipientId)
 	if err != nil {
 		return fmt.Errorf("failed to reject friend request: %w", err)
 	}
 	return nil
 }
 
-func (c PostgresFriendsRepository) GetFriendRequestsSent(senderId uuid.UUID) ([]um.UserResponse, error) {
+func (c PostgresFriendsRepository) GetFriendRequestsSent(ctx context.Context, senderId uuid.UUID) ([]um.UserResponse, error) {
 	friends := []um.UserRecord{}
 	query := `
         SELECT 
@@ -131,7 +116,7 @@ func (c PostgresFriendsRepository) GetFriendRequestsSent(senderId uuid.UUID) ([]
         JOIN users us ON us.id = fr.recipient_id
         WHERE fr.sender_id = $1
     `
-	err := c.db.Select(&friends, query, senderId)
+	err := c.db.SelectContext(ctx, &friends, query, senderId)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get friend requests sent: %w", err)
 	}
@@ -143,7 +128,7 @@ func (c PostgresFriendsRepository) GetFriendRequestsSent(senderId uuid.UUID) ([]
 	return res, nil
 }
 
-func (c PostgresFriendsRepository) GetFriendRequestsReceived(receiverId uuid.UUID) ([]um.UserResponse, error) {
+func (c PostgresFriendsRepository) GetFriendRequestsReceived(ctx context.Context, receiverId uuid.UUID) ([]um.UserResponse, error) {
 	friends := []um.UserRecord{}
 	query := `
         SELECT 
@@ -152,7 +137,7 @@ func (c PostgresFriendsRepository) GetFriendRequestsReceived(receiverId uuid.UUI
         JOIN users us ON us.id = fr.sender_id
         WHERE fr.recipient_id= $1
     `
-	err := c.db.Select(&friends, query, receiverId)
+	err := c.db.SelectContext(ctx, &friends, query, receiverId)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get friend requests sent: %w", err)
 	}
@@ -164,31 +149,32 @@ func (c PostgresFriendsRepository) GetFriendRequestsReceived(receiverId uuid.UUI
 	return res, nil
 }
 
-func (c PostgresFriendsRepository) CheckIfFriendRequestExists(senderId uuid.UUID, recipientId uuid.UUID) bool {
-	fmt.Printf("senderId: %v, recipientId: %v \n", senderId, recipientId)
+func (c PostgresFriendsRepository) CheckIfFriendRequestExists(ctx context.Context, senderId uuid.UUID, recipientId uuid.UUID) bool {
 	query := `SELECT EXISTS (SELECT 1 FROM friends_requests WHERE recipient_id= $1 AND sender_id= $2)`
 	var exists1 bool
-	err := c.db.Get(&exists1, query, recipientId, senderId)
+	err := c.db.GetContext(ctx, &exists1, query, recipientId, senderId)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if friend request exists", "error", err)
 		return false
 	}
 
 	return exists1
 }
 
-func (c PostgresFriendsRepository) CheckIfFriendShipExists(userA uuid.UUID, userB uuid.UUID) bool {
+func (c PostgresFriendsRepository) CheckIfFriendShipExists(ctx context.Context, userA uuid.UUID, userB uuid.UUID) bool {
 	query := `SELECT EXISTS (SELECT 1 FROM friends WHERE (user_a_id = $1 AND user_b_id= $2) OR (user_a_id = $2 AND user_b_id= $1))`
 	var exists1 bool
-	err := c.db.Get(&exists1, query, userA, userB)
+	err := c.db.GetContext(ctx, &exists1, query, userA, userB)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if friendship exists", "error", err)
 		return false
 	}
 	return exists1
 }
 
-func (c PostgresFriendsRepository) DeleteFriendship(userA uuid.UUID, userB uuid.UUID) error {
+func (c PostgresFriendsRepository) DeleteFriendship(ctx context.Context, userA uuid.UUID, userB uuid.UUID) error {
 	query := `DELETE FROM friends WHERE (user_a_id = $1 AND user_b_id= $2) OR (user_a_id = $2 AND user_b_id= $1)`
-	_, err := c.db.Exec(query, userA, userB)
+	_, err := c.db.ExecContext(ctx, query, userA, userB)
 	if err != nil {
 		return fmt.Errorf("failed to delete friendship: %w", err)
 	}
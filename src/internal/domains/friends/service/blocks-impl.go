@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+
+	"github.com/betterreads/internal/domains/users/models"
+	users "github.com/betterreads/internal/domains/users/service"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+// Block removes the friendship, friend requests and follows between the users, and they can't
+// send new ones until they are unblocked
+func (fs *FriendsServiceImpl) Block(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error {
+	if blockerId == blockedId {
+		return ErrBlockSelf
+	}
+	if !fs.us.CheckUserExists(ctx, blockedId) {
+		return users.ErrUserNotFound
+	}
+
+	if fs.fr.CheckIfBlockExists(ctx, blockerId, blockedId) {
+		return ErrAlreadyBlocked
+	}
+
+	if err := fs.fr.Block(ctx, blockerId, blockedId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fs *FriendsServiceImpl) Unblock(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error {
+	if !fs.fr.CheckIfBlockExists(ctx, blockerId, blockedId) {
+		return ErrBlockNotFound
+	}
+
+	if err := fs.fr.Unblock(ctx, blockerId, blockedId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fs *FriendsServiceImpl) GetBlocked(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error) {
+	blocked, err := fs.fr.GetBlocked(ctx, userID, page)
+	if err != nil {
+		return pagination.Page[models.UserResponse]{}, err
+	}
+	return blocked, nil
+}
+
+// Mute hides the user from the feed, the muted user can still interact with the user
+func (fs *FriendsServiceImpl) Mute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error {
+	if muterId == mutedId {
+		return ErrMuteSelf
+	}
+	if !fs.us.CheckUserExists(ctx, mutedId) {
+		return users.ErrUserNotFound
+	}
+
+	if fs.fr.CheckIfMuteExists(ctx, muterId, mutedId) {
+		return ErrAlreadyMuted
+	}
+
+	if err := fs.fr.Mute(ctx, muterId, mutedId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fs *FriendsServiceImpl) Unmute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error {
+	if !fs.fr.CheckIfMuteExists(ctx, muterId, mutedId) {
+		return ErrMuteNotFound
+	}
+
+	if err := fs.fr.Unmute(ctx, muterId, mutedId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fs *FriendsServiceImpl) GetMuted(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error) {
+	muted, err := fs.fr.GetMuted(ctx, userID, page)
+	if err != nil {
+		return pagination.Page[models.UserResponse]{}, err
+	}
+	return muted, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+
+	"github.com/betterreads/internal/domains/users/models"
+	users "github.com/betterreads/internal/domains/users/service"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/betterreads/internal/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+// Follow doesn't need the approval of the followed user, unlike AddFriend
+func (fs *FriendsServiceImpl) Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	if followerId == followeeId {
+		return ErrFollowSelf
+	}
+	if !fs.us.CheckUserExists(ctx, followeeId) {
+		return users.ErrUserNotFound
+	}
+
+	if fs.fr.CheckIfBlocked(ctx, followerId, followeeId) {
+		return ErrUserBlocked
+	}
+
+	if fs.fr.CheckIfFollowExists(ctx, followerId, followeeId) {
+		return ErrAlreadyFollowing
+	}
+
+	if err := fs.fr.Follow(ctx, followerId, followeeId); err != nil {
+		return err
+	}
+	metrics.UsersFollowed.Inc()
+	return nil
+}
+
+func (fs *FriendsServiceImpl) Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	if !fs.fr.CheckIfFollowExists(ctx, followerId, followeeId) {
+		return ErrFollowNotFound
+	}
+
+	if err := fs.fr.Unfollow(ctx, followerId, followeeId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fs *FriendsServiceImpl) GetFollowers(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error) {
+	if !fs.us.CheckUserExists(ctx, userID) {
+		return pagination.Page[models.UserResponse]{}, users.ErrUserNotFound
+	}
+
+	followers, err := fs.fr.GetFollowers(ctx, userID, page)
+	if err != nil {
+		return pagination.Page[models.UserResponse]{}, err
+	}
+	return followers, nil
+}
+
+func (fs *FriendsServiceImpl) GetFollowing(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error) {
+	if !fs.us.CheckUserExists(ctx, userID) {
+		return pagination.Page[models.UserResponse]{}, users.ErrUserNotFound
+	}
+
+	following, err := fs.fr.GetFollowing(ctx, userID, page)
+	if err != nil {
+		return pagination.Page[models.UserResponse]{}, err
+	}
+	return following, nil
+}
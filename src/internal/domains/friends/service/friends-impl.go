@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
+
 	"github.com/betterreads/internal/domains/friends/repository"
 	"github.com/betterreads/internal/domains/users/models"
 	users "github.com/betterreads/internal/domains/users/service"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
@@ -16,110 +20,115 @@ func NewFriendsServiceImpl(fr repository.FriendsRepository, us users.UsersServic
 	return &FriendsServiceImpl{fr: fr, us: us}
 }
 
-func (fs *FriendsServiceImpl) GetFriends(userID uuid.UUID) ([]models.UserResponse, error) {
-	if !fs.us.CheckUserExists(userID) {
-		return nil, users.ErrUserNotFound
+func (fs *FriendsServiceImpl) GetFriends(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error) {
+	if !fs.us.CheckUserExists(ctx, userID) {
+		return pagination.Page[models.UserResponse]{}, users.ErrUserNotFound
 	}
 
-	friends, err := fs.fr.GetFriends(userID)
+	friends, err := fs.fr.GetFriends(ctx, userID, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[models.UserResponse]{}, err
 	}
 	return friends, nil
 }
 
-func (fs *FriendsServiceImpl) AddFriend(senderId uuid.UUID, recipientId uuid.UUID) error {
+func (fs *FriendsServiceImpl) AddFriend(ctx context.Context, senderId uuid.UUID, recipientId uuid.UUID) error {
 	if senderId == recipientId {
 		return ErrSameUser
 	}
-	if !fs.us.CheckUserExists(senderId) {
+	if !fs.us.CheckUserExists(ctx, senderId) {
 		return ErrUserFriendNotFound
 	}
 
-	if !fs.us.CheckUserExists(recipientId) {
+	if !fs.us.CheckUserExists(ctx, recipientId) {
 		return users.ErrUserNotFound
 	}
 
-	if fs.fr.CheckIfFriendRequestExists(senderId, recipientId) {
+	if fs.fr.CheckIfBlocked(ctx, senderId, recipientId) {
+		return ErrUserBlocked
+	}
+
+	if fs.fr.CheckIfFriendRequestExists(ctx, senderId, recipientId) {
 		return ErrFriendRequestExists
 	}
 
-	if fs.fr.CheckIfFriendShipExists(senderId, recipientId) {
+	if fs.fr.CheckIfFriendShipExists(ctx, senderId, recipientId) {
 		return ErrAlreadyFriends
 	}
 
-	err := fs.fr.AddFriend(senderId, recipientId)
+	err := fs.fr.AddFriend(ctx, senderId, recipientId)
 	if err != nil {
 		return err
 	}
+	metrics.FriendRequestsSent.Inc()
 	return nil
 }
 
-func (fs *FriendsServiceImpl) AcceptFriendRequest(recipientId uuid.UUID, senderId uuid.UUID) error {
-	if !fs.fr.CheckIfFriendRequestExists(senderId, recipientId) {
+func (fs *FriendsServiceImpl) AcceptFriendRequest(ctx context.Context, recipientId uuid.UUID, senderId uuid.UUID) error {
+	if !fs.fr.CheckIfFriendRequestExists(ctx, senderId, recipientId) {
 		return ErrRequestNotFound
 	}
 
-	err := fs.fr.AcceptFriendRequest(recipientId, senderId)
+	err := fs.fr.AcceptFriendRequest(ctx, recipientId, senderId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (fs *FriendsServiceImpl) RejectFriendRequest(recipientId uuid.UUID, senderId uuid.UUID) error {
-	if !fs.fr.CheckIfFriendRequestExists(senderId, recipientId) {
+func (fs *FriendsServiceImpl) RejectFriendRequest(ctx context.Context, recipientId uuid.UUID, senderId uuid.UUID) error {
+	if !fs.fr.CheckIfFriendRequestExists(ctx, senderId, recipientId) {
 		return ErrRequestNotFound
 	}
 
-	err := fs.fr.RejectFriendRequest(recipientId, senderId)
+	err := fs.fr.RejectFriendRequest(ctx, recipientId, senderId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (fs *FriendsServiceImpl) GetFriendRequestsSent(userID uuid.UUID) ([]models.UserResponse, error) {
-	if !fs.us.CheckUserExists(userID) {
+func (fs *FriendsServiceImpl) GetFriendRequestsSent(ctx context.Context, userID uuid.UUID) ([]models.UserResponse, error) {
+	if !fs.us.CheckUserExists(ctx, userID) {
 		return nil, users.ErrUserNotFound
 	}
 
-	friendRequestsSent, err := fs.fr.GetFriendRequestsSent(userID)
+	friendRequestsSent, err := fs.fr.GetFriendRequestsSent(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	return friendRequestsSent, nil
 }
 
-func (fs *FriendsServiceImpl) GetFriendRequestsReceived(userID uuid.UUID) ([]models.UserResponse, error) {
-	if !fs.us.CheckUserExists(userID) {
+func (fs *FriendsServiceImpl) GetFriendRequestsReceived(ctx context.Context, userID uuid.UUID) ([]models.UserResponse, error) {
+	if !fs.us.CheckUserExists(ctx, userID) {
 		return nil, users.ErrUserNotFound
 	}
 
-	friendRequestsReceived, err := fs.fr.GetFriendRequestsReceived(userID)
+	friendRequestsReceived, err := fs.fr.GetFriendRequestsReceived(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	return friendRequestsReceived, nil
 }
 
-func (fs *FriendsServiceImpl) DeleteFriend(userID uuid.UUID, friendID uuid.UUID) error {
+func (fs *FriendsServiceImpl) DeleteFriend(ctx context.Context, userID uuid.UUID, friendID uuid.UUID) error {
 	if userID == friendID {
 		return ErrSameUser
 	}
-	if !fs.us.CheckUserExists(friendID) {
+	if !fs.us.CheckUserExists(ctx, friendID) {
 		return ErrUserFriendNotFound
 	}
 
-	if !fs.us.CheckUserExists(userID) {
+	if !fs.us.CheckUserExists(ctx, userID) {
 		return users.ErrUserNotFound
 	}
 
-	if !fs.fr.CheckIfFriendShipExists(friendID, userID) {
+	if !fs.fr.CheckIfFriendShipExists(ctx, friendID, userID) {
 		return ErrFriendShipNotFound
 	}
 
-	if err := fs.fr.DeleteFriendship(userID, friendID); err != nil {
+	if err := fs.fr.DeleteFriendship(ctx, userID, friendID); err != nil {
 		return err
 	}
 
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
+
 	"errors"
 	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/google/uuid"
 )
 
@@ -13,14 +16,34 @@ var (
 	ErrAlreadyFriends      = errors.New("users are already friends")
 	ErrRequestNotFound     = errors.New("friend request not found")
 	ErrSameUser            = errors.New("cannot add yourself as a friend")
+	ErrFollowSelf          = errors.New("cannot follow yourself")
+	ErrAlreadyFollowing    = errors.New("user already followed")
+	ErrFollowNotFound      = errors.New("user not followed")
+	ErrUserBlocked         = errors.New("user blocked")
+	ErrBlockSelf           = errors.New("cannot block yourself")
+	ErrAlreadyBlocked      = errors.New("user already blocked")
+	ErrBlockNotFound       = errors.New("user not blocked")
+	ErrMuteSelf            = errors.New("cannot mute yourself")
+	ErrAlreadyMuted        = errors.New("user already muted")
+	ErrMuteNotFound        = errors.New("user not muted")
 )
 
 type FriendsService interface {
-	GetFriends(userID uuid.UUID) ([]models.UserResponse, error)
-	AddFriend(senderId uuid.UUID, recipientId uuid.UUID) error
-	AcceptFriendRequest(recipientId uuid.UUID, senderId uuid.UUID) error
-	RejectFriendRequest(recipientId uuid.UUID, senderId uuid.UUID) error
-	GetFriendRequestsSent(userID uuid.UUID) ([]models.UserResponse, error)
-	GetFriendRequestsReceived(userID uuid.UUID) ([]models.UserResponse, error)
-    DeleteFriend(userA uuid.UUID, userB uuid.UUID) error
+	GetFriends(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error)
+	AddFriend(ctx context.Context, senderId uuid.UUID, recipientId uuid.UUID) error
+	AcceptFriendRequest(ctx context.Context, recipientId uuid.UUID, senderId uuid.UUID) error
+	RejectFriendRequest(ctx context.Context, recipientId uuid.UUID, senderId uuid.UUID) error
+	GetFriendRequestsSent(ctx context.Context, userID uuid.UUID) ([]models.UserResponse, error)
+	GetFriendRequestsReceived(ctx context.Context, userID uuid.UUID) ([]models.UserResponse, error)
+    DeleteFriend(ctx context.Context, userA uuid.UUID, userB uuid.UUID) error
+	Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	GetFollowers(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error)
+	GetFollowing(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error)
+	Block(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error
+	Unblock(ctx context.Context, blockerId uuid.UUID, blockedId uuid.UUID) error
+	GetBlocked(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error)
+	Mute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error
+	Unmute(ctx context.Context, muterId uuid.UUID, mutedId uuid.UUID) error
+	GetMuted(ctx context.Context, userID uuid.UUID, page pagination.Request) (pagination.Page[models.UserResponse], error)
 }
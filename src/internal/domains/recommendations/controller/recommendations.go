@@ -40,7 +40,7 @@ func (rc *RecommenationsController) GetRecommendations(c *gin.Context) {
 		return
 	}
 
-	booksByTop3Genres, err := rc.rs.GetRecommendations(userId)
+	booksByTop3Genres, err := rc.rs.GetRecommendations(c.Request.Context(), userId)
 	if err != nil {
 		if errors.Is(err, bookService.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting recommendations", err, http.StatusNotFound)
@@ -88,7 +88,7 @@ func (rc *RecommenationsController) GetMoreRecommendations(c *gin.Context) {
 		return
 	}
 
-	books, err := rc.rs.GetMoreRecommendations(userId, genre)
+	books, err := rc.rs.GetMoreRecommendations(c.Request.Context(), userId, genre)
 	if err != nil {
 		if errors.Is(err, bookService.ErrGenreNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting recommendations", err, http.StatusNotFound)
@@ -126,7 +126,7 @@ func (rc *RecommenationsController) GetFriendsRecommendations(c *gin.Context) {
 		c.AbortWithError(errId.Status, errId)
 		return
 	}
-	books, err := rc.rs.GetFriendsRecommendations(userId)
+	books, err := rc.rs.GetFriendsRecommendations(c.Request.Context(), userId)
 	if err != nil {
 		if errors.Is(err, bookService.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when getting recommendations", err, http.StatusNotFound)
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sort"
@@ -9,6 +10,7 @@ import (
 	"github.com/betterreads/internal/domains/books/repository"
 	bsm "github.com/betterreads/internal/domains/bookshelf/models"
 	"github.com/betterreads/internal/domains/recommendations/model"
+	"github.com/betterreads/internal/pkg/logger"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -23,16 +25,16 @@ func NewPostgresRecommendationsRepository(c *sqlx.DB, br repository.BooksDatabas
 	return &PostgresRecommendationsRepository{c: c, br: br}
 }
 
-func (r *PostgresRecommendationsRepository) GetRecommendations(userId uuid.UUID) (map[string][]*bm.Book, error) {
-	preferedGenres, err := r.getPreferedGenres(userId)
+func (r *PostgresRecommendationsRepository) GetRecommendations(ctx context.Context, userId uuid.UUID) (map[string][]*bm.Book, error) {
+	preferedGenres, err := r.getPreferedGenres(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
 	booksByGenre := make(map[string][]*bm.Book)
 	for _, genre := range preferedGenres {
-		fmt.Printf("Getting books for genre: %v \n", genre)
-		books, err := r.GetPreferedBooks(genre, 5, userId)
+		logger.FromContext(ctx).Debug("getting recommended books", "genre", genre)
+		books, err := r.GetPreferedBooks(ctx, genre, 5, userId)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get books by genre: %w", err)
 		}
@@ -41,15 +43,15 @@ func (r *PostgresRecommendationsRepository) GetRecommendations(userId uuid.UUID)
 	return booksByGenre, nil
 }
 
-func (r *PostgresRecommendationsRepository) GetMoreRecommendations(userId uuid.UUID, genre string) ([]*bm.Book, error) {
-	books, err := r.GetPreferedBooks(genre, 20, userId)
+func (r *PostgresRecommendationsRepository) GetMoreRecommendations(ctx context.Context, userId uuid.UUID, genre string) ([]*bm.Book, error) {
+	books, err := r.GetPreferedBooks(ctx, genre, 20, userId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get books by genre: %w", err)
 	}
 	return books, nil
 }
 
-func (r *PostgresRecommendationsRepository) GetPreferedBooks(genre string, limit int, userId uuid.UUID) ([]*bm.Book, error) {
+func (r *PostgresRecommendationsRepository) GetPreferedBooks(ctx context.Context, genre string, limit int, userId uuid.UUID) ([]*bm.Book, error) {
 	genre_id, err := getGenreId(genre)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get genre id: %w", err)
@@ -88,11 +90,12 @@ func (r *PostgresRecommendationsRepository) GetPreferedBooks(genre string, limit
     WHERE 
         gb.genre_id = $1 
         AND bk.id NOT IN (SELECT book_id FROM bookshelf WHERE user_id = $2)
+        AND bk.deleted_at IS NULL
     ORDER BY avg_ratings DESC
     LIMIT $3;
    `
 
-	err = r.c.Select(&partRes, query, genre_id, userId, limit)
+	err = r.c.SelectContext(ctx, &partRes, query, genre_id, userId, limit)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get preferedBooks: %w", err)
 	}
@@ -111,7 +114,7 @@ func (r *PostgresRecommendationsRepository) GetPreferedBooks(genre string, limit
 			AverageRating:   book.AverageRating,
 		}
 
-		genres, err := r.br.GetGenresForBook(book.Id) //Need to fetch the genres unluckily
+		genres, err := r.br.GetGenresForBook(ctx, book.Id) //Need to fetch the genres unluckily
 		if err != nil {
 			return nil, fmt.Errorf("failed to get genres for book: %w", err)
 		}
@@ -122,7 +125,7 @@ func (r *PostgresRecommendationsRepository) GetPreferedBooks(genre string, limit
 	return res, nil
 }
 
-func (r *PostgresRecommendationsRepository) getPreferedGenres(userId uuid.UUID) ([]string, error) {
+func (r *PostgresRecommendationsRepository) getPreferedGenres(ctx context.Context, userId uuid.UUID) ([]string, error) {
 	// First gets the books that user has read from bookshelf
 	query := `SELECT bk.title, bk.author, bk.description, bk.amount_of_pages, bk.
               publication_date, bk.language, bk.id 
@@ -130,7 +133,7 @@ func (r *PostgresRecommendationsRepository) getPreferedGenres(userId uuid.UUID)
               JOIN books bk ON bs.book_id = bk.id
               WHERE bs.user_id = $1 AND bs.status='read';`
 	userBooks := []bm.BookDb{}
-	err := r.c.Select(&userBooks, query, userId)
+	err := r.c.SelectContext(ctx, &userBooks, query, userId)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get user books: %w", err)
 	}
@@ -138,7 +141,7 @@ func (r *PostgresRecommendationsRepository) getPreferedGenres(userId uuid.UUID)
 	genresMap := make(map[string]int)
 	// Then gets the genres of the books
 	for _, book := range userBooks {
-		genres, err := r.br.GetGenresForBook(book.Id)
+		genres, err := r.br.GetGenresForBook(ctx, book.Id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get genres for book: %w", err)
 		}
@@ -185,17 +188,18 @@ func getGenreId(genre string) (int, error) {
 	return -1, repository.ErrGenreNotFound
 }
 
-func (r *PostgresRecommendationsRepository) CheckIfUserHasValidShelf(userId uuid.UUID) bool {
+func (r *PostgresRecommendationsRepository) CheckIfUserHasValidShelf(ctx context.Context, userId uuid.UUID) bool {
 	bookshelf := []bsm.BookShelfBasicData{}
 	query := `SELECT * FROM bookshelf WHERE user_id=$1 AND status='read';`
-	err := r.c.Select(&bookshelf, query, userId)
+	err := r.c.SelectContext(ctx, &bookshelf, query, userId)
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to check if user has valid shelf", "error", err)
 		return false
 	}
 	return len(bookshelf) >= 5
 }
 
-func (r *PostgresRecommendationsRepository) GetFriendsRecommendations(userId uuid.UUID) ([]*bm.Book, error) {
+func (r *PostgresRecommendationsRepository) GetFriendsRecommendations(ctx context.Context, userId uuid.UUID) ([]*bm.Book, error) {
 	books := []*bm.Book{}
 	query := `
      WITH ratings AS (
@@ -231,16 +235,17 @@ func (r *PostgresRecommendationsRepository) GetFriendsRecommendations(userId uui
         (fr.friend_id = $1 OR fr.user_id = $1) 
         AND bs.status='read'
         AND bk.id NOT IN (SELECT book_id FROM bookshelf WHERE user_id = $1)
+        AND bk.deleted_at IS NULL
     ORDER BY avg_rating DESC
     `
 
-	err := r.c.Select(&books, query, userId)
+	err := r.c.SelectContext(ctx, &books, query, userId)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get friends recommendations: %w", err)
 	}
 
 	for _, book := range books {
-		genres, err := r.br.GetGenresForBook(book.Id)
+		genres, err := r.br.GetGenresForBook(ctx, book.Id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get genres for book: %w", err)
 		}
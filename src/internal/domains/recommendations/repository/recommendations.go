@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"github.com/betterreads/internal/domains/books/models"
 	"github.com/google/uuid"
@@ -11,8 +12,8 @@ var (
 )
 
 type RecommendationsDatabase interface {
-	GetMoreRecommendations(userId uuid.UUID, genre string) ([]*models.Book, error)
-	GetRecommendations(userId uuid.UUID) (map[string][]*models.Book, error)
-	CheckIfUserHasValidShelf(userId uuid.UUID) bool
-	GetFriendsRecommendations(userId uuid.UUID) ([]*models.Book, error)
+	GetMoreRecommendations(ctx context.Context, userId uuid.UUID, genre string) ([]*models.Book, error)
+	GetRecommendations(ctx context.Context, userId uuid.UUID) (map[string][]*models.Book, error)
+	CheckIfUserHasValidShelf(ctx context.Context, userId uuid.UUID) bool
+	GetFriendsRecommendations(ctx context.Context, userId uuid.UUID) ([]*models.Book, error)
 }
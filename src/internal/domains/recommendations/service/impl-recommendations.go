@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/betterreads/internal/domains/books/models"
 	bs "github.com/betterreads/internal/domains/books/service"
 	"github.com/betterreads/internal/domains/recommendations/repository"
@@ -17,49 +19,49 @@ func NewRecommendationsServiceImpl(recommendationsRepository repository.Recommen
 	return &RecommendationsServiceImpl{recommendationsRepository: recommendationsRepository, booksService: booksService}
 }
 
-func (rs *RecommendationsServiceImpl) GetRecommendations(userId uuid.UUID) (map[string][]*models.Book, error) {
-	userExists := rs.booksService.CheckIfUserExists(userId)
+func (rs *RecommendationsServiceImpl) GetRecommendations(ctx context.Context, userId uuid.UUID) (map[string][]*models.Book, error) {
+	userExists := rs.booksService.CheckIfUserExists(ctx, userId)
 	if !userExists {
 		return nil, bs.ErrUserNotFound
 	}
 
-	valid := rs.recommendationsRepository.CheckIfUserHasValidShelf(userId)
+	valid := rs.recommendationsRepository.CheckIfUserHasValidShelf(ctx, userId)
 	if !valid {
 		return nil, ErrNeedMoreBooksInShelf
 	}
 
-	books, err := rs.recommendationsRepository.GetRecommendations(userId)
+	books, err := rs.recommendationsRepository.GetRecommendations(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 	return books, nil
 }
 
-func (rs *RecommendationsServiceImpl) GetMoreRecommendations(userId uuid.UUID, genre string) ([]*models.Book, error) {
-	userExists := rs.booksService.CheckIfUserExists(userId)
+func (rs *RecommendationsServiceImpl) GetMoreRecommendations(ctx context.Context, userId uuid.UUID, genre string) ([]*models.Book, error) {
+	userExists := rs.booksService.CheckIfUserExists(ctx, userId)
 	if !userExists {
 		return nil, bs.ErrUserNotFound
 	}
 
-	valid := rs.recommendationsRepository.CheckIfUserHasValidShelf(userId)
+	valid := rs.recommendationsRepository.CheckIfUserHasValidShelf(ctx, userId)
 	if !valid {
 		return nil, ErrNeedMoreBooksInShelf
 	}
 
-	books, err := rs.recommendationsRepository.GetMoreRecommendations(userId, genre)
+	books, err := rs.recommendationsRepository.GetMoreRecommendations(ctx, userId, genre)
 	if err != nil {
 		return nil, err
 	}
 	return books, nil
 }
 
-func (rs *RecommendationsServiceImpl) GetFriendsRecommendations(userId uuid.UUID) ([]*models.Book, error) {
-	userExists := rs.booksService.CheckIfUserExists(userId)
+func (rs *RecommendationsServiceImpl) GetFriendsRecommendations(ctx context.Context, userId uuid.UUID) ([]*models.Book, error) {
+	userExists := rs.booksService.CheckIfUserExists(ctx, userId)
 	if !userExists {
 		return nil, bs.ErrUserNotFound
 	}
 
-	books, err := rs.recommendationsRepository.GetFriendsRecommendations(userId)
+	books, err := rs.recommendationsRepository.GetFriendsRecommendations(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
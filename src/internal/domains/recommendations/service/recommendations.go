@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	"github.com/betterreads/internal/domains/books/models"
@@ -12,7 +13,7 @@ var (
 )
 
 type RecommendationsService interface {
-	GetRecommendations(userId uuid.UUID) (map[string][]*models.Book, error)
-	GetMoreRecommendations(userId uuid.UUID, genre string) ([]*models.Book, error)
-	GetFriendsRecommendations(userId uuid.UUID) ([]*models.Book, error)
+	GetRecommendations(ctx context.Context, userId uuid.UUID) (map[string][]*models.Book, error)
+	GetMoreRecommendations(ctx context.Context, userId uuid.UUID, genre string) ([]*models.Book, error)
+	GetFriendsRecommendations(ctx context.Context, userId uuid.UUID) ([]*models.Book, error)
 }
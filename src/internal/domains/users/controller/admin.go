@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/domains/users/service"
+	"github.com/betterreads/internal/pkg/auth"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsersByRole godoc
+// @Summary Get users by role
+// @Description Get the users with a role, only for admins
+// @Tags admin
+// @Produce  json
+// @Param role query string true "Role" Enums(user, author, moderator, admin)
+// @Success 200 {object} []models.UserResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /admin/users [get]
+func (u *UsersController) GetUsersByRole(c *gin.Context) {
+	users, err := u.us.GetUsersByRole(c.Request.Context(), auth.Role(c.Query("role")))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			errDetails := er.NewErrorDetailsWithParams("Error when getting users", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when getting users", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// ChangeUserRole godoc
+// @Summary Change the role of a user
+// @Description Changes the role of a user and logs out their sessions, only for admins. Admins can't change their own role
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User id"
+// @Param request body models.RoleRequest true "Role request"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 403 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /admin/users/{id}/role [put]
+func (u *UsersController) ChangeUserRole(c *gin.Context) {
+	adminId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	userId, err := parseUserId(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	user, err := u.us.ChangeUserRole(c.Request.Context(), adminId, userId, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			errDetails := er.NewErrorDetailsWithParams("Error when changing role", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrChangeOwnRole) {
+			errDetails := er.NewErrorDetails("Error when changing role", err, http.StatusConflict)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when changing role", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when changing role", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
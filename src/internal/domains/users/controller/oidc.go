@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/domains/users/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+// Cookie with the hash of the state, it's only sent to the OIDC endpoints
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/users/oidc"
+)
+
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcStateCookiePath,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCLogIn godoc
+// @Summary Start the log in with the identity provider
+// @Description Returns the URL of the provider to send the user to. The provider sends them back to the app with a code and a state to send to /users/oidc/callback. The state is bound to the browser with the oidc_state cookie
+// @Tags users
+// @Produce  json
+// @Success 200 {object} models.OIDCAuthorizationResponse
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 502 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/oidc/login [get]
+func (u *UsersController) OIDCLogIn(c *gin.Context) {
+	response, err := u.us.OIDCLogInURL(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, oidc.ErrProviderUnavailable) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusBadGateway)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	setOIDCStateCookie(c, response.StateHash, int(service.OIDCStateDuration.Seconds()))
+	c.JSON(http.StatusOK, response)
+}
+
+// OIDCCallback godoc
+// @Summary Finish the log in with the identity provider
+// @Description Exchanges the code the provider sent to the app for the tokens, only with the oidc_state cookie of /users/oidc/login. Users without an account get the registration started with 201, it's finished with /users/register/{id}/additional-info. Users with two-factor authentication get a challenge token like in /users/login
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.OIDCCallbackRequest true "Code and state of the provider"
+// @Success 200 {object} models.UserResponse
+// @Success 200 {object} models.SecondFactorChallengeResponse
+// @Success 201 {object} models.UserStageResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 429 {object} errors.ErrorDetails
+// @Failure 502 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/oidc/callback [post]
+func (u *UsersController) OIDCCallback(c *gin.Context) {
+	var req models.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+	// The state is used once, so is its cookie
+	req.StateHash, _ = c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+
+	result, err := u.us.LogInOIDC(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrInvalidOIDCState) || errors.Is(err, service.ErrOIDCEmailRequired) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusBadRequest)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrUsernameTaken) || errors.Is(err, service.ErrEmailTaken) {
+			errDetails := er.NewErrorDetailsWithParams("Error when logging in", http.StatusBadRequest, err)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, oidc.ErrExchangeFailed) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusUnauthorized)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrOIDCAccountExists) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusConflict)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, oidc.ErrProviderUnavailable) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusBadGateway)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrAccountLocked) {
+			var lockErr *service.AccountLockedError
+			if errors.As(err, &lockErr) {
+				aux.SetRetryAfter(c, lockErr.RetryAfter)
+			}
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusTooManyRequests)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrSecondFactorRequired) {
+			var challengeErr *service.SecondFactorRequiredError
+			errors.As(err, &challengeErr)
+			c.JSON(http.StatusOK, challengeErr.Challenge)
+		} else {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	if result.Registration != nil {
+		c.JSON(http.StatusCreated, gin.H{"user": result.Registration})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":          result.User,
+		"token":         result.Tokens.Token,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+	})
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/domains/users/service"
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// LogInSecondFactor godoc
+// @Summary Finish the log in with the second factor
+// @Description Exchanges the challenge token of the log in and a code of the authenticator app, or a recovery code, for the tokens. Wrong codes count as failed logins
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.SecondFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} models.TokensResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 429 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/login/2fa [post]
+func (u *UsersController) LogInSecondFactor(c *gin.Context) {
+	var req models.SecondFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	userResponse, tokens, err := u.us.LogInSecondFactor(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidChallengeToken) || errors.Is(err, service.ErrInvalidCode) {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusUnauthorized)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrAccountLocked) {
+			var lockErr *service.AccountLockedError
+			if errors.As(err, &lockErr) {
+				aux.SetRetryAfter(c, lockErr.RetryAfter)
+			}
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusTooManyRequests)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":          userResponse,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// EnrollTOTP godoc
+// @Summary Start enrolling an authenticator app
+// @Description Creates the secret of the app, as text and as an otpauth URI to show as a QR code. Two-factor authentication is enabled after confirming it with a code
+// @Tags users
+// @Produce  json
+// @Success 200 {object} models.TOTPEnrollmentResponse
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/2fa/enroll [post]
+func (u *UsersController) EnrollTOTP(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	enrollment, err := u.us.EnrollTOTP(c.Request.Context(), userId)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when enrolling two-factor authentication", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrTOTPAlreadyEnabled) {
+			errDetails := er.NewErrorDetails("Error when enrolling two-factor authentication", err, http.StatusConflict)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when enrolling two-factor authentication", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary Enable two-factor authentication
+// @Description Confirms the enrollment with a code of the app and returns the recovery codes, they are only shown once
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.TOTPCodeRequest true "Code of the app"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/2fa/confirm [post]
+func (u *UsersController) ConfirmTOTP(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	recoveryCodes, err := u.us.ConfirmTOTP(c.Request.Context(), userId, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCode) {
+			errDetails := er.NewErrorDetails("Error when enabling two-factor authentication", err, http.StatusBadRequest)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrTOTPNotEnrolled) || errors.Is(err, service.ErrTOTPAlreadyEnabled) {
+			errDetails := er.NewErrorDetails("Error when enabling two-factor authentication", err, http.StatusConflict)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when enabling two-factor authentication", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodes)
+}
+
+// DisableTOTP godoc
+// @Summary Disable two-factor authentication
+// @Description Removes the authenticator app and the recovery codes, it needs the password and a code of the app or a recovery code. Wrong ones count as failed logins
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.DisableTOTPRequest true "Password and code of the app or recovery code"
+// @Success 204
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 429 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/2fa/disable [post]
+func (u *UsersController) DisableTOTP(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	var req models.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	if err := u.us.DisableTOTP(c.Request.Context(), userId, &req); err != nil {
+		if errors.Is(err, service.ErrInvalidCode) {
+			errDetails := er.NewErrorDetails("Error when disabling two-factor authentication", err, http.StatusBadRequest)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrWrongPassword) {
+			errDetails := er.NewErrorDetails("Error when disabling two-factor authentication", err, http.StatusUnauthorized)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrAccountLocked) {
+			var lockErr *service.AccountLockedError
+			if errors.As(err, &lockErr) {
+				aux.SetRetryAfter(c, lockErr.RetryAfter)
+			}
+			errDetails := er.NewErrorDetails("Error when disabling two-factor authentication", err, http.StatusTooManyRequests)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrTOTPNotEnabled) {
+			errDetails := er.NewErrorDetails("Error when disabling two-factor authentication", err, http.StatusConflict)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when disabling two-factor authentication", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -3,13 +3,13 @@ package controller
 import (
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/betterreads/internal/domains/users/models"
 	"github.com/betterreads/internal/domains/users/service"
 	aux "github.com/betterreads/internal/pkg/controller"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -33,7 +33,7 @@ func NewUsersController(us service.UsersService) *UsersController {
 // @Success 200 {object} models.UserResponse
 // @Router /users [get]
 func (u *UsersController) GetUsers(c *gin.Context) {
-	Users, err := u.us.GetUsers()
+	Users, err := u.us.GetUsers(c.Request.Context())
 	if err != nil {
 		err := er.NewErrorDetails("Error when getting users", err, http.StatusInternalServerError)
 		c.AbortWithError(err.Status, err)
@@ -59,7 +59,7 @@ func (u *UsersController) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := u.us.GetUser(uuid)
+	user, err := u.us.GetUser(c.Request.Context(), uuid)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
@@ -77,15 +77,17 @@ func (u *UsersController) GetUser(c *gin.Context) {
 
 // LogIn godoc
 // @Summary Log in a user
-// @Description Log in a user and return a JWT
+// @Description Log in a user and return a short lived JWT with a refresh token to renew it. Users with two-factor authentication get a challenge token to send with a code to /users/login/2fa instead
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param user body models.UserLoginRequest true "User login request"
 // @Success 200 {object} models.UserResponse
+// @Success 200 {object} models.SecondFactorChallengeResponse
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 400 {object} errors.ErrorDetailsWithParams
 // @Failure 404 {object} errors.ErrorDetails
+// @Failure 429 {object} errors.ErrorDetails
 // @Router /users/login [post]
 func (u *UsersController) LogIn(c *gin.Context) {
 	var user *models.UserLoginRequest
@@ -95,7 +97,7 @@ func (u *UsersController) LogIn(c *gin.Context) {
 		return
 	}
 
-	userResponse, token, err := u.us.LogInUser(user)
+	userResponse, tokens, err := u.us.LogInUser(c.Request.Context(), user)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUsernameNotFound) {
@@ -104,6 +106,18 @@ func (u *UsersController) LogIn(c *gin.Context) {
 		} else if errors.Is(err, service.ErrWrongPassword) {
 			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusUnauthorized)
 			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrAccountLocked) {
+			var lockErr *service.AccountLockedError
+			if errors.As(err, &lockErr) {
+				aux.SetRetryAfter(c, lockErr.RetryAfter)
+			}
+			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusTooManyRequests)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrSecondFactorRequired) {
+			// The password was right, the tokens are given after the second factor
+			var challengeErr *service.SecondFactorRequiredError
+			errors.As(err, &challengeErr)
+			c.JSON(http.StatusOK, challengeErr.Challenge)
 		} else {
 			errDetails := er.NewErrorDetails("Error when logging in", err, http.StatusInternalServerError)
 			c.AbortWithError(errDetails.Status, errDetails)
@@ -112,11 +126,220 @@ func (u *UsersController) LogIn(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user":  userResponse,
-		"token": token,
+		"user":          userResponse,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
+// Refresh godoc
+// @Summary Refresh the tokens of a session
+// @Description Uses a refresh token to get a new JWT and refresh token, each refresh token can be used once. Using one again logs out the session
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param refresh body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokensResponse
+// @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/refresh [post]
+func (u *UsersController) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	tokens, err := u.us.RefreshTokens(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			errDetails := er.NewErrorDetails("Error when refreshing tokens", err, http.StatusUnauthorized)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when refreshing tokens", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// LogOut godoc
+// @Summary Log out
+// @Description Logs out the session of the JWT, its JWT and refresh tokens stop working
+// @Tags users
+// @Produce  json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/logout [post]
+func (u *UsersController) LogOut(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	if err := u.us.LogOut(c.Request.Context(), userId, aux.GetSessionId(c)); err != nil {
+		errDetails := er.NewErrorDetails("Error when logging out", err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogOutAll godoc
+// @Summary Log out of every session
+// @Description Logs out every session of the logged user, including the current one
+// @Tags users
+// @Produce  json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/logout/all [post]
+func (u *UsersController) LogOutAll(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	if err := u.us.LogOutAll(c.Request.Context(), userId); err != nil {
+		errDetails := er.NewErrorDetails("Error when logging out", err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of every session"})
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Verifies the email of the user with the token sent by email
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.VerifyEmailRequest true "Verify email request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/verify-email [post]
+func (u *UsersController) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	if err := u.us.VerifyEmail(c.Request.Context(), &req); err != nil {
+		abortWithTokenError(c, "Error when verifying email", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend verification email
+// @Description Sends a new verification email to the logged user, the links sent before stop working
+// @Tags users
+// @Produce  json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} errors.ErrorDetails
+// @Failure 404 {object} errors.ErrorDetails
+// @Failure 409 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/verify-email/resend [post]
+func (u *UsersController) ResendVerificationEmail(c *gin.Context) {
+	userId, errId := aux.GetLoggedUserId(c)
+	if errId != nil {
+		c.AbortWithError(errId.Status, errId)
+		return
+	}
+
+	if err := u.us.ResendVerificationEmail(c.Request.Context(), userId); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			errDetails := er.NewErrorDetails("Error when sending verification email", err, http.StatusConflict)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			errDetails := er.NewErrorDetails("Error when sending verification email", err, http.StatusNotFound)
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else {
+			errDetails := er.NewErrorDetails("Error when sending verification email", err, http.StatusInternalServerError)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword godoc
+// @Summary Forgot password
+// @Description Sends a password reset email. The response is the same whether the email is registered or not
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.ForgotPasswordRequest true "Forgot password request"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Router /users/password/forgot [post]
+func (u *UsersController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	if err := u.us.ForgotPassword(c.Request.Context(), &req); err != nil {
+		errDetails := er.NewErrorDetails("Error when requesting password reset", err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a reset link was sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Sets a new password with the token sent by email and logs out every session
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param request body models.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorDetails
+// @Failure 500 {object} errors.ErrorDetails
+// @Router /users/password/reset [post]
+func (u *UsersController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		er.AbortWithJsonErorr(c, err)
+		return
+	}
+
+	if err := u.us.ResetPassword(c.Request.Context(), &req); err != nil {
+		abortWithTokenError(c, "Error when resetting password", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+}
+
+func abortWithTokenError(c *gin.Context, title string, err error) {
+	if errors.Is(err, service.ErrInvalidToken) {
+		errDetails := er.NewErrorDetails(title, err, http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+	} else {
+		errDetails := er.NewErrorDetails(title, err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
+	}
+}
+
 // RegisterBasic godoc
 // @Summary Register first step
 // @Description Register first step
@@ -135,7 +358,7 @@ func (u *UsersController) RegisterFirstStep(c *gin.Context) {
 		return
 	}
 
-	userStageResponse, err := u.us.RegisterFirstStep(user)
+	userStageResponse, err := u.us.RegisterFirstStep(c.Request.Context(), user)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUsernameTaken) || errors.Is(err, service.ErrEmailTaken) {
@@ -177,7 +400,7 @@ func (u *UsersController) RegisterSecondStep(c *gin.Context) {
 		return
 	}
 
-	userResponse, err := u.us.RegisterSecondStep(user, uuid)
+	userResponse, err := u.us.RegisterSecondStep(c.Request.Context(), user, uuid)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Error when registering user", err, http.StatusNotFound)
@@ -197,9 +420,11 @@ func (u *UsersController) RegisterSecondStep(c *gin.Context) {
 // @Tags users
 // @Accept  mpfd
 // @Produce  json
-// @Param file formData file true "User picture"
+// @Param file formData file true "User picture, a JPEG, PNG or WebP image up to 5 MB"
 // @Success 201
 // @Failure 400 {object} errors.ErrorDetailsWithParams
+// @Failure 413 {object} errors.ErrorDetailsWithParams
+// @Failure 415 {object} errors.ErrorDetailsWithParams
 // @Failure 500 {object} errors.ErrorDetails
 // @Router /users/picture [post]
 func (u *UsersController) PostPicture(c *gin.Context) {
@@ -209,6 +434,16 @@ func (u *UsersController) PostPicture(c *gin.Context) {
 		return
 	}
 
+	if err := aux.ParsePictureForm(c); err != nil {
+		if errDetails := aux.GetPictureError("Failed to post picture", err); errDetails != nil {
+			c.AbortWithError(errDetails.Status, errDetails)
+			return
+		}
+		errDetails := er.NewErrorDetails("Failed to post picture", fmt.Errorf("Error when parsing picture: %w", err), http.StatusBadRequest)
+		c.AbortWithError(errDetails.Status, errDetails)
+		return
+	}
+
 	file, _, err := c.Request.FormFile("file")
 	if err != nil {
 		errDetail := fmt.Errorf("Error when parsing picture: %w", err)
@@ -219,8 +454,12 @@ func (u *UsersController) PostPicture(c *gin.Context) {
 
 	defer file.Close()
 
-	picture, err := io.ReadAll(file)
+	picture, err := images.ReadUpload(file)
 	if err != nil {
+		if errDetails := aux.GetPictureError("Failed to post picture", err); errDetails != nil {
+			c.AbortWithError(errDetails.Status, errDetails)
+			return
+		}
 		errDetail := fmt.Errorf("Error when parsing picture: %w", err)
 		errDetails := er.NewErrorDetails("Failed to post picture", errDetail, http.StatusInternalServerError)
 		c.AbortWithError(errDetails.Status, errDetails)
@@ -228,9 +467,11 @@ func (u *UsersController) PostPicture(c *gin.Context) {
 	}
 
 	request := models.UserPictureRequest{Picture: picture}
-	err = u.us.PostUserPicture(user_id, request)
+	err = u.us.PostUserPicture(c.Request.Context(), user_id, request)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
+		if errDetails := aux.GetPictureError("Failed to post picture", err); errDetails != nil {
+			c.AbortWithError(errDetails.Status, errDetails)
+		} else if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Failed to post picture", err, http.StatusNotFound)
 			c.AbortWithError(errDetails.Status, errDetails)
 		} else {
@@ -248,8 +489,12 @@ func (u *UsersController) PostPicture(c *gin.Context) {
 // @Description Get user picture
 // @Tags users
 // @Param id path string true "User id"
-// @Produce jpeg
+// @Param size query string false "thumb, medium or large (default)"
+// @Param If-None-Match header string false "ETag of the cached picture"
+// @Produce jpeg,png
 // @Success 200 {file} []byte
+// @Success 204
+// @Success 304
 // @Failure 400 {object} errors.ErrorDetails
 // @Failure 404 {object} errors.ErrorDetails
 // @Router /users/{id}/picture [get]
@@ -260,7 +505,13 @@ func (u *UsersController) GetPicture(c *gin.Context) {
 		return
 	}
 
-	base64Bytes, err := u.us.GetUserPicture(user_id)
+	size, errSize := aux.GetPictureSize(c)
+	if errSize != nil {
+		c.AbortWithError(errSize.Status, errSize)
+		return
+	}
+
+	picture, err := u.us.GetUserPicture(c.Request.Context(), user_id, size)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			errDetails := er.NewErrorDetails("Failed to get user picture", err, http.StatusNotFound)
@@ -272,16 +523,15 @@ func (u *UsersController) GetPicture(c *gin.Context) {
 		return
 	}
 
-	if base64Bytes == nil {
-		c.JSON(http.StatusNoContent, gin.H{})
+	if err := aux.SendPicture(c, picture); err != nil {
+		errDetails := er.NewErrorDetails("Failed to get user picture", err, http.StatusInternalServerError)
+		c.AbortWithError(errDetails.Status, errDetails)
 	}
-
-	c.Data(http.StatusOK, "image/jpeg", base64Bytes)
 }
 
 // SearchUsers godoc
 // @Summary Search users
-// @Description Search users
+// @Description Search users. The users blocked by the user logged in, or that blocked them, aren't in the results
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -294,14 +544,15 @@ func (u *UsersController) GetPicture(c *gin.Context) {
 func (u *UsersController) SearchUsers(c *gin.Context) {
 	name := c.Query("name")
 	isAuthor := c.Query("author")
+	viewerId := aux.GetUserIdIfLogged(c)
 
 	var users []*models.UserResponse
 
 	var err error
 	if isAuthor == "true" {
-		users, err = u.us.SearchUsers(name, true)
+		users, err = u.us.SearchUsers(c.Request.Context(), name, true, viewerId)
 	} else {
-		users, err = u.us.SearchUsers(name, false)
+		users, err = u.us.SearchUsers(c.Request.Context(), name, false, viewerId)
 	}
 
 	if err != nil {
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/betterreads/internal/pkg/auth"
 	"github.com/google/uuid"
 )
 
@@ -16,9 +17,11 @@ type UserRecord struct {
 	Gender         string    `json:"gender" db:"gender"`
 	AboutMe        string    `json:"about_me" db:"about_me"`
 	IsAuthor       bool      `json:"is_author" db:"is_author"`
+	Role           auth.Role `json:"role" db:"role"`
 	Id             uuid.UUID `json:"id" db:"id"`
 	Age            int       `json:"age" db:"age"`
 	ProfilePicture []byte    `json:"profile_picture" db:"profile_picture"`
+	EmailVerified  bool      `json:"email_verified" db:"email_verified"`
 }
 
 type UserStageRecord struct {
@@ -31,6 +34,26 @@ type UserStageRecord struct {
 	Id        uuid.UUID `json:"id" db:"id"`
 }
 
+type SessionRecord struct {
+	Id        uuid.UUID `db:"id"`
+	UserId    uuid.UUID `db:"user_id"`
+	CreatedAt string    `db:"created_at"`
+	RevokedAt *string   `db:"revoked_at"`
+}
+
+type TOTPRecord struct {
+	UserId      uuid.UUID `db:"user_id"`
+	Secret      string    `db:"secret"`
+	CreatedAt   string    `db:"created_at"`
+	EnabledAt   *string   `db:"enabled_at"`
+	LastCounter *int64    `db:"last_counter"`
+}
+
+type OIDCStateRecord struct {
+	Nonce        string `db:"nonce"`
+	CodeVerifier string `db:"code_verifier"`
+}
+
 // RESPONSE
 
 type UserStageResponse struct {
@@ -43,16 +66,59 @@ type UserStageResponse struct {
 }
 
 type UserResponse struct {
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Username  string    `json:"username"`
-	Location  string    `json:"location"`
-	Gender    string    `json:"gender"`
-	AboutMe   string    `json:"about_me"`
-	IsAuthor  bool      `json:"is_author"`
-	Id        uuid.UUID `json:"id" db:"id"`
-	Age       int       `json:"age"`
+	Email         string    `json:"email"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Username      string    `json:"username"`
+	Location      string    `json:"location"`
+	Gender        string    `json:"gender"`
+	AboutMe       string    `json:"about_me"`
+	IsAuthor      bool      `json:"is_author"`
+	Role          auth.Role `json:"role"`
+	Id            uuid.UUID `json:"id" db:"id"`
+	Age           int       `json:"age"`
+	EmailVerified bool      `json:"email_verified"`
+}
+
+// The access token is sent as a bearer token, the refresh token is used once to get new tokens.
+type TokensResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Answer of the log in of a user with two-factor authentication, the challenge token is sent
+// with a code to /users/login/2fa.
+type SecondFactorChallengeResponse struct {
+	SecondFactorRequired bool   `json:"second_factor_required"`
+	ChallengeToken       string `json:"challenge_token"`
+	ExpiresIn            int    `json:"expires_in"`
+}
+
+// The secret is added to the authenticator app by hand or as a QR code of the provisioning URI.
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// The recovery codes are only shown once, each one logs in once without the app.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// The app sends the user to the authorization URL to log in with the provider.
+type OIDCAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	// Kept in a cookie, so the callback is only accepted from the browser that started the log in
+	StateHash string `json:"-"`
+}
+
+// Result of a log in with the provider. Linked identities get the user and the tokens, new ones
+// get the stage user of the registration, to finish with /users/register/{id}/additional-info.
+type OIDCLogInResult struct {
+	User         *UserResponse
+	Tokens       *TokensResponse
+	Registration *UserStageResponse
 }
 
 type UserPictureResponse struct {
@@ -66,6 +132,57 @@ type UserLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// The code is a code of the authenticator app or a recovery code.
+type SecondFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// The code and state the provider sent to the redirect URL
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+	// Hash of the state in the cookie of the browser
+	StateHash string `json:"-"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type RoleRequest struct {
+	Role auth.Role `json:"role" binding:"required"`
+}
+
+type UserTokenPurpose string
+
+const (
+	UserTokenVerifyEmail   UserTokenPurpose = "verify_email"
+	UserTokenResetPassword UserTokenPurpose = "reset_password"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 type UserStageRequest struct {
 	Email     string `json:"email" binding:"required,email" db:"email"`
 	Username  string `json:"username" binding:"required" db:"username"`
@@ -1,9 +1,15 @@
 package repository
 
 import (
+	"context"
+
 	"errors"
+	"time"
 
 	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -12,19 +18,50 @@ var (
 	ErrUserStageNotFound    = errors.New("user stage not found")
 	ErrUsernameAlreadyTaken = errors.New("username already taken")
 	ErrEmailAlreadyTaken    = errors.New("email already taken")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token already used")
+	ErrUserTokenNotFound    = errors.New("token not found")
+	ErrTOTPNotFound         = errors.New("totp not found")
+	ErrTOTPAlreadyEnabled   = errors.New("totp already enabled")
+	ErrOIDCStateNotFound    = errors.New("oidc state not found")
 )
 
 type UsersDatabase interface {
-	CreateStageUser(user *models.UserStageRequest) (*models.UserStageRecord, error)
-	JoinAndCreateUser(userAddtional *models.UserAdditionalRequest, id uuid.UUID) (*models.UserRecord, error)
-	GetUser(id uuid.UUID) (*models.UserRecord, error)
-	GetUsers() ([]*models.UserRecord, error)
-	GetStageUser(id uuid.UUID) (*models.UserStageRecord, error)
-	GetUserByUsername(username string) (*models.UserRecord, error)
-	GetUserByEmail(email string) (*models.UserRecord, error)
-	GetUserPicture(id uuid.UUID) ([]byte, error)
-	CheckUserExistsForRegister(user *models.UserStageRequest) error
-	CheckUserExists(id uuid.UUID) bool
-	SaveUserPicture(id uuid.UUID, picture []byte) error
-	SearchUsers(username string, isAuthor bool) ([]*models.UserRecord, error)
+	CreateStageUser(ctx context.Context, user *models.UserStageRequest) (*models.UserStageRecord, error)
+	JoinAndCreateUser(ctx context.Context, userAddtional *models.UserAdditionalRequest, id uuid.UUID) (*models.UserRecord, error)
+	GetUser(ctx context.Context, id uuid.UUID) (*models.UserRecord, error)
+	GetUsers(ctx context.Context) ([]*models.UserRecord, error)
+	GetStageUser(ctx context.Context, id uuid.UUID) (*models.UserStageRecord, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.UserRecord, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.UserRecord, error)
+	GetUserPicture(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error)
+	CheckUserExistsForRegister(ctx context.Context, user *models.UserStageRequest) error
+	CheckUserExists(ctx context.Context, id uuid.UUID) bool
+	SaveUserPicture(ctx context.Context, id uuid.UUID, picture *images.Picture) error
+	SearchUsers(ctx context.Context, username string, isAuthor bool, viewerId uuid.UUID) ([]*models.UserRecord, error)
+	CreateSession(ctx context.Context, userId uuid.UUID, refreshHash string, expiresAt time.Time) (uuid.UUID, error)
+	RotateRefreshToken(ctx context.Context, refreshHash string, newRefreshHash string, expiresAt time.Time) (*models.SessionRecord, error)
+	RevokeSession(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userId uuid.UUID) error
+	IsSessionRevoked(ctx context.Context, sessionId uuid.UUID) (bool, error)
+	CreateUserToken(ctx context.Context, userId uuid.UUID, purpose models.UserTokenPurpose, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string) error
+	ResetPassword(ctx context.Context, tokenHash string, hashedPassword string) error
+	GetUsersByRole(ctx context.Context, role auth.Role) ([]*models.UserRecord, error)
+	UpdateUserRole(ctx context.Context, id uuid.UUID, role auth.Role) (*models.UserRecord, error)
+	GetAccountLock(ctx context.Context, userId uuid.UUID) (time.Duration, error)
+	RecordFailedLogin(ctx context.Context, userId uuid.UUID, maxFailures int, lockout time.Duration) (time.Duration, error)
+	ResetFailedLogins(ctx context.Context, userId uuid.UUID) error
+	SaveTOTPSecret(ctx context.Context, userId uuid.UUID, secret string) error
+	GetTOTP(ctx context.Context, userId uuid.UUID) (*models.TOTPRecord, error)
+	EnableTOTP(ctx context.Context, userId uuid.UUID, counter int64, recoveryHashes []string) error
+	UseTOTPCounter(ctx context.Context, userId uuid.UUID, counter int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (bool, error)
+	DisableTOTP(ctx context.Context, userId uuid.UUID) error
+	CreateOIDCState(ctx context.Context, stateHash string, nonce string, codeVerifier string, expiresAt time.Time) error
+	UseOIDCState(ctx context.Context, stateHash string) (*models.OIDCStateRecord, error)
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.UserRecord, error)
+	LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string) error
+	GetPendingIdentityStage(ctx context.Context, provider string, subject string) (*models.UserStageRecord, error)
+	CreatePendingIdentity(ctx context.Context, stageId uuid.UUID, provider string, subject string, emailVerified bool) error
 }
@@ -1,84 +1,30 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
 type PostgresUserRepository struct {
-	c *sqlx.DB
+	c     *sqlx.DB
+	blobs storage.BlobStore
 }
 
-func NewPostgresUserRepository(c *sqlx.DB) (UsersDatabase, error) {
-	enableUUIDExtension := `CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`
-	if _, err := c.Exec(enableUUIDExtension); err != nil {
-		return nil, fmt.Errorf("failed to enable uuid extension: %w", err)
-	}
-
-	schemaUsers := `
-		CREATE TABLE IF NOT EXISTS users (
-			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-			username VARCHAR(255) NOT NULL UNIQUE,
-			first_name VARCHAR(255) NOT NULL,
-			last_name VARCHAR(255) NOT NULL,
-			email VARCHAR(255) NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			location VARCHAR(255) NULL,
-			age INTEGER,
-			gender VARCHAR(255),
-			about_me TEXT,
-            is_author BOOLEAN DEFAULT FALSE
-		);
-		
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
-	`
-
-	schemaRegistry := `
-		CREATE TABLE IF NOT EXISTS registry (
-			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-			email VARCHAR(255) NOT NULL UNIQUE,
-			username VARCHAR(255) NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			first_name VARCHAR(255) NOT NULL,
-			last_name VARCHAR(255) NOT NULL,
-            is_author BOOLEAN DEFAULT FALSE
-		);
-		
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
-	`
-
-	schemaPictures := `
-		CREATE TABLE IF NOT EXISTS pictures_users (
-			user_id UUID,
-			picture BYTEA,
-			FOREIGN KEY (user_id) REFERENCES users(id),
-			PRIMARY KEY (user_id)
-		);
-		`
-
-	if _, err := c.Exec(schemaUsers); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	if _, err := c.Exec(schemaRegistry); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	if _, err := c.Exec(schemaPictures); err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	return &PostgresUserRepository{c}, nil
+func NewPostgresUserRepository(c *sqlx.DB, blobs storage.BlobStore) UsersDatabase {
+	return &PostgresUserRepository{c, blobs}
 }
 
-func (r *PostgresUserRepository) CreateStageUser(user *models.UserStageRequest) (*models.UserStageRecord, error) {
+func (r *PostgresUserRepository) CreateStageUser(ctx context.Context, user *models.UserStageRequest) (*models.UserStageRecord, error) {
 	userRecord := &models.UserStageRecord{}
 	query := `INSERT INTO registry (email, username, password, first_name, last_name, 
             is_author)
@@ -87,7 +33,7 @@ func (r *PostgresUserRepository) CreateStageUser(user *models.UserStageRequest)
 
 	args := []interface{}{user.Email, user.Username, user.Password, user.FirstName, user.LastName, user.IsAuthor}
 
-	err := r.c.Get(userRecord, query, args...)
+	err := r.c.GetContext(ctx, userRecord, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,34 +41,54 @@ func (r *PostgresUserRepository) CreateStageUser(user *models.UserStageRequest)
 	return userRecord, nil
 }
 
-func (r *PostgresUserRepository) JoinAndCreateUser(userAdditional *models.UserAdditionalRequest, id uuid.UUID) (*models.UserRecord, error) {
-	user, err := r.GetStageUser(id)
+func (r *PostgresUserRepository) JoinAndCreateUser(ctx context.Context, userAdditional *models.UserAdditionalRequest, id uuid.UUID) (*models.UserRecord, error) {
+	user, err := r.GetStageUser(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	userRecord, err := r.createUser(user, userAdditional)
+
+	// The user is created only with its identity and without its registration, so a failed step can be retried
+	tx, err := r.c.BeginTxx(ctx, nil)
 	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	defer tx.Rollback()
+
+	userRecord, err := createUser(ctx, tx, user, userAdditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := linkPendingIdentity(ctx, tx, id, userRecord); err != nil {
 		return nil, err
 	}
 
-	if err := r.deleteStageUser(id); err != nil {
+	if err := deleteStageUser(ctx, tx, id); err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
 	return userRecord, nil
 }
 
-func (r *PostgresUserRepository) createUser(user *models.UserStageRecord, userAdditional *models.UserAdditionalRequest) (*models.UserRecord, error) {
+func createUser(ctx context.Context, tx *sqlx.Tx, user *models.UserStageRecord, userAdditional *models.UserAdditionalRequest) (*models.UserRecord, error) {
 	userRecord := &models.UserRecord{}
 	query := `INSERT INTO users (email, password, first_name, last_name, username, 
-                    location, gender, about_me, age, is_author)
+                    location, gender, about_me, age, role)
                     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-                    RETURNING id, email, password, first_name, last_name, username, location, gender,about_me, age, is_author;`
+                    RETURNING id, email, password, first_name, last_name, username, location, gender,about_me, age, is_author, role;`
+
+	role := auth.RoleUser
+	if user.IsAuthor {
+		role = auth.RoleAuthor
+	}
 
 	args := []interface{}{user.Email, user.Password, user.FirstName, user.LastName, user.Username, userAdditional.Location,
-		userAdditional.Gender, userAdditional.AboutMe, userAdditional.Age, user.IsAuthor}
+		userAdditional.Gender, userAdditional.AboutMe, userAdditional.Age, role}
 
-	err := r.c.Get(userRecord, query, args...)
+	err := tx.GetContext(ctx, userRecord, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -130,18 +96,18 @@ func (r *PostgresUserRepository) createUser(user *models.UserStageRecord, userAd
 	return userRecord, nil
 }
 
-func (r *PostgresUserRepository) deleteStageUser(id uuid.UUID) error {
+func deleteStageUser(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
 	query := `DELETE FROM registry WHERE id = $1;`
-	if _, err := r.c.Exec(query, id); err != nil {
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgresUserRepository) GetUser(id uuid.UUID) (*models.UserRecord, error) {
+func (r *PostgresUserRepository) GetUser(ctx context.Context, id uuid.UUID) (*models.UserRecord, error) {
 	user := &models.UserRecord{}
 	query := `SELECT * FROM users WHERE id = $1;`
-	if err := r.c.Get(user, query, id); err != nil {
+	if err := r.c.GetContext(ctx, user, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
 		}
@@ -150,10 +116,10 @@ func (r *PostgresUserRepository) GetUser(id uuid.UUID) (*models.UserRecord, erro
 	return user, nil
 }
 
-func (r *PostgresUserRepository) GetStageUser(uuid uuid.UUID) (*models.UserStageRecord, error) {
+func (r *PostgresUserRepository) GetStageUser(ctx context.Context, uuid uuid.UUID) (*models.UserStageRecord, error) {
 	user := &models.UserStageRecord{}
 	query := `SELECT * FROM registry WHERE id = $1;`
-	if err := r.c.Get(user, query, uuid); err != nil {
+	if err := r.c.GetContext(ctx, user, query, uuid); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserStageNotFound
 		}
@@ -162,10 +128,10 @@ func (r *PostgresUserRepository) GetStageUser(uuid uuid.UUID) (*models.UserStage
 	return user, nil
 }
 
-func (r *PostgresUserRepository) GetUsers() ([]*models.UserRecord, error) {
+func (r *PostgresUserRepository) GetUsers(ctx context.Context) ([]*models.UserRecord, error) {
 	users := []*models.UserRecord{}
 	query := `SELECT * FROM users;`
-	if err := r.c.Select(&users, query); err != nil {
+	if err := r.c.SelectContext(ctx, &users, query); err != nil {
 		if err != sql.ErrNoRows {
 			return nil, fmt.Errorf("failed to get users: %w", err)
 		}
@@ -173,10 +139,10 @@ func (r *PostgresUserRepository) GetUsers() ([]*models.UserRecord, error) {
 	return users, nil
 }
 
-func (r *PostgresUserRepository) GetUserByUsername(username string) (*models.UserRecord, error) {
+func (r *PostgresUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.UserRecord, error) {
 	user := &models.UserRecord{}
 	query := `SELECT * FROM users WHERE username = $1;`
-	if err := r.c.Get(user, query, username); err != nil {
+	if err := r.c.GetContext(ctx, user, query, username); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
 		}
@@ -185,10 +151,10 @@ func (r *PostgresUserRepository) GetUserByUsername(username string) (*models.Use
 	return user, nil
 }
 
-func (r *PostgresUserRepository) GetUserByEmail(email string) (*models.UserRecord, error) {
+func (r *PostgresUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.UserRecord, error) {
 	user := &models.UserRecord{}
 	query := `SELECT * FROM users WHERE email = $1;`
-	if err := r.c.Get(user, query, email); err != nil {
+	if err := r.c.GetContext(ctx, user, query, email); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
 		}
@@ -197,47 +163,54 @@ func (r *PostgresUserRepository) GetUserByEmail(email string) (*models.UserRecor
 	return user, nil
 }
 
-func (r *PostgresUserRepository) GetUserPicture(id uuid.UUID) ([]byte, error) {
-	var picture []byte
-	query := `SELECT picture FROM pictures_users WHERE user_id= $1;`
-	if err := r.c.Get(&picture, query, id); err != nil {
+func (r *PostgresUserRepository) GetUserPicture(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error) {
+	record := &storage.PictureRecord{}
+	query := `SELECT picture, blob_key, content_type, variants, etag, updated_at FROM pictures_users WHERE user_id= $1;`
+	if err := r.c.GetContext(ctx, record, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
-	return picture, nil
+	return storage.LoadPicture(ctx, r.blobs, record, string(size))
 }
 
-func (r *PostgresUserRepository) SaveUserPicture(id uuid.UUID, picture []byte) error {
+func (r *PostgresUserRepository) SaveUserPicture(ctx context.Context, id uuid.UUID, picture *images.Picture) error {
 	exists := false
 	query := `SELECT EXISTS(SELECT 1 FROM pictures_users WHERE user_id = $1);`
-	if err := r.c.Get(&exists, query, id); err != nil {
+	if err := r.c.GetContext(ctx, &exists, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return ErrUserNotFound
 		}
 		return fmt.Errorf("failed to check if user picture exists: %w", err)
 	}
 
+	key := storage.UserPictureKey(id.String())
+	if err := picture.Save(ctx, r.blobs, key); err != nil {
+		return fmt.Errorf("failed to save user picture: %w", err)
+	}
+
 	if exists {
-		query = `UPDATE pictures_users SET picture = $2 WHERE user_id = $1;`
+		query = `UPDATE pictures_users SET picture = NULL, blob_key = $2, content_type = $3, variants = true,
+				etag = $4, updated_at = now() WHERE user_id = $1;`
 
 	} else {
-		query = `INSERT INTO pictures_users (user_id, picture) VALUES ($1, $2);`
+		query = `INSERT INTO pictures_users (user_id, blob_key, content_type, variants, etag)
+				VALUES ($1, $2, $3, true, $4);`
 	}
 
-	if _, err := r.c.Exec(query, id, picture); err != nil {
+	if _, err := r.c.ExecContext(ctx, query, id, key, picture.ContentType, picture.ETag); err != nil {
 		return fmt.Errorf("failed to save user picture: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgresUserRepository) CheckUserExistsForRegister(user *models.UserStageRequest) error {
-	result_registry_email, result_registry_username, err := r.checkUserExistsInTable(user, "registry")
+func (r *PostgresUserRepository) CheckUserExistsForRegister(ctx context.Context, user *models.UserStageRequest) error {
+	result_registry_email, result_registry_username, err := r.checkUserExistsInTable(ctx, user, "registry")
 	if err != nil {
 		return fmt.Errorf("failed to check user exists in user table: %w", err)
 	}
-	result_users_email, result_users_username, err := r.checkUserExistsInTable(user, "users")
+	result_users_email, result_users_username, err := r.checkUserExistsInTable(ctx, user, "users")
 	if err != nil {
 		return fmt.Errorf("failed to check user exists in user table: %w", err)
 	}
@@ -253,20 +226,20 @@ func (r *PostgresUserRepository) CheckUserExistsForRegister(user *models.UserSta
 	return nil
 }
 
-func (r *PostgresUserRepository) checkUserExistsInTable(user *models.UserStageRequest, table string) (bool, bool, error) {
+func (r *PostgresUserRepository) checkUserExistsInTable(ctx context.Context, user *models.UserStageRequest, table string) (bool, bool, error) {
 	result_email := false
 	result_username := false
 
 	// Check if the email exists
 	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE email = $1);`, table)
-	err := r.c.Get(&result_email, query, user.Email)
+	err := r.c.GetContext(ctx, &result_email, query, user.Email)
 	if err != nil {
 		return false, false, fmt.Errorf("failed to check user exists for email: %w", err)
 	}
 
 	// Check if the username exists
 	query = fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE username = $1);`, table)
-	err = r.c.Get(&result_username, query, user.Username)
+	err = r.c.GetContext(ctx, &result_username, query, user.Username)
 	if err != nil {
 		return false, false, fmt.Errorf("failed to check user exists for username: %w", err)
 	}
@@ -274,19 +247,22 @@ func (r *PostgresUserRepository) checkUserExistsInTable(user *models.UserStageRe
 	return result_email, result_username, nil
 }
 
-func (r *PostgresUserRepository) CheckUserExists(id uuid.UUID) bool {
+func (r *PostgresUserRepository) CheckUserExists(ctx context.Context, id uuid.UUID) bool {
 	exists := false
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1);`
-	if err := r.c.Get(&exists, query, id); err != nil {
+	if err := r.c.GetContext(ctx, &exists, query, id); err != nil {
+		logger.FromContext(ctx).Error("failed to check if user exists", "error", err)
 		return false
 	}
 	return exists
 }
 
-func (r *PostgresUserRepository) SearchUsers(username string, isAuthor bool) ([]*models.UserRecord, error) {
+func (r *PostgresUserRepository) SearchUsers(ctx context.Context, username string, isAuthor bool, viewerId uuid.UUID) ([]*models.UserRecord, error) {
 	users := []*models.UserRecord{}
-	query := `SELECT * FROM users WHERE LOWER(username) LIKE LOWER('%'||$1||'%') AND is_author = $2;`
-	if err := r.c.Select(&users, query, username, isAuthor); err != nil {
+	query := `SELECT * FROM users us WHERE LOWER(us.username) LIKE LOWER('%'||$1||'%') AND us.is_author = $2
+		AND NOT EXISTS (SELECT 1 FROM blocks bl
+			WHERE (bl.blocker_id = $3 AND bl.blocked_id = us.id) OR (bl.blocker_id = us.id AND bl.blocked_id = $3));`
+	if err := r.c.SelectContext(ctx, &users, query, username, isAuthor, viewerId); err != nil {
 		if err != sql.ErrNoRows {
 			return nil, fmt.Errorf("failed to search users: %w", err)
 		}
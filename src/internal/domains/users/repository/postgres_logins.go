@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetAccountLock returns how long the account stays locked, zero when it isn't.
+func (r *PostgresUserRepository) GetAccountLock(ctx context.Context, userId uuid.UUID) (time.Duration, error) {
+	var seconds float64
+	query := `SELECT EXTRACT(EPOCH FROM locked_until - now())::DOUBLE PRECISION FROM login_failures
+		WHERE user_id = $1 AND locked_until > now();`
+	if err := r.c.GetContext(ctx, &seconds, query, userId); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get account lock: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// RecordFailedLogin counts a failed login. When it's the maxFailures one the account is locked
+// for lockout and the count starts again, then it returns lockout.
+func (r *PostgresUserRepository) RecordFailedLogin(ctx context.Context, userId uuid.UUID, maxFailures int, lockout time.Duration) (time.Duration, error) {
+	var locked bool
+	query := `INSERT INTO login_failures AS lf (user_id, failures) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET
+			failures = CASE WHEN lf.failures + 1 >= $2 THEN 0 ELSE lf.failures + 1 END,
+			locked_until = CASE WHEN lf.failures + 1 >= $2 THEN now() + make_interval(secs => $3) ELSE lf.locked_until END
+		RETURNING COALESCE(locked_until > now(), false);`
+	if err := r.c.GetContext(ctx, &locked, query, userId, maxFailures, lockout.Seconds()); err != nil {
+		return 0, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	return lockout, nil
+}
+
+// ResetFailedLogins forgets the failed logins after a successful one.
+func (r *PostgresUserRepository) ResetFailedLogins(ctx context.Context, userId uuid.UUID) error {
+	query := `DELETE FROM login_failures WHERE user_id = $1;`
+	if _, err := r.c.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+	return nil
+}
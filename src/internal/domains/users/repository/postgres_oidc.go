@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateOIDCState saves a log in started with the provider, and deletes the expired ones.
+func (r *PostgresUserRepository) CreateOIDCState(ctx context.Context, stateHash string, nonce string, codeVerifier string, expiresAt time.Time) error {
+	if _, err := r.c.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at < now();`); err != nil {
+		return fmt.Errorf("failed to delete expired oidc states: %w", err)
+	}
+
+	query := `INSERT INTO oidc_states (state_hash, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4);`
+	if _, err := r.c.ExecContext(ctx, query, stateHash, nonce, codeVerifier, expiresAt); err != nil {
+		return fmt.Errorf("failed to create oidc state: %w", err)
+	}
+	return nil
+}
+
+// UseOIDCState deletes the state and returns it, so each one is used once.
+func (r *PostgresUserRepository) UseOIDCState(ctx context.Context, stateHash string) (*models.OIDCStateRecord, error) {
+	state := &models.OIDCStateRecord{}
+	query := `DELETE FROM oidc_states WHERE state_hash = $1 AND expires_at > now() RETURNING nonce, code_verifier;`
+	if err := r.c.GetContext(ctx, state, query, stateHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOIDCStateNotFound
+		}
+		return nil, fmt.Errorf("failed to use oidc state: %w", err)
+	}
+	return state, nil
+}
+
+func (r *PostgresUserRepository) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.UserRecord, error) {
+	user := &models.UserRecord{}
+	query := `SELECT us.* FROM users us
+		JOIN user_identities ui ON ui.user_id = us.id
+		WHERE ui.provider = $1 AND ui.subject = $2;`
+	if err := r.c.GetContext(ctx, user, query, provider, subject); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+	return user, nil
+}
+
+func (r *PostgresUserRepository) LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string) error {
+	return linkIdentity(ctx, r.c, userId, provider, subject)
+}
+
+func linkIdentity(ctx context.Context, exec sqlx.ExecerContext, userId uuid.UUID, provider string, subject string) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id) VALUES ($1, $2, $3);`
+	if _, err := exec.ExecContext(ctx, query, provider, subject, userId); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// GetPendingIdentityStage returns the stage user of a registration started with the identity.
+func (r *PostgresUserRepository) GetPendingIdentityStage(ctx context.Context, provider string, subject string) (*models.UserStageRecord, error) {
+	user := &models.UserStageRecord{}
+	query := `SELECT rg.id, rg.email, rg.username, rg.password, rg.first_name, rg.last_name, rg.is_author
+		FROM registry rg
+		JOIN pending_identities pi ON pi.stage_id = rg.id
+		WHERE pi.provider = $1 AND pi.subject = $2;`
+	if err := r.c.GetContext(ctx, user, query, provider, subject); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserStageNotFound
+		}
+		return nil, fmt.Errorf("failed to get pending identity: %w", err)
+	}
+	return user, nil
+}
+
+func (r *PostgresUserRepository) CreatePendingIdentity(ctx context.Context, stageId uuid.UUID, provider string, subject string, emailVerified bool) error {
+	query := `INSERT INTO pending_identities (stage_id, provider, subject, email_verified) VALUES ($1, $2, $3, $4);`
+	if _, err := r.c.ExecContext(ctx, query, stageId, provider, subject, emailVerified); err != nil {
+		return fmt.Errorf("failed to create pending identity: %w", err)
+	}
+	return nil
+}
+
+// linkPendingIdentity links the identity the registration was started with to the new user. The
+// email is verified when the provider verified it.
+func linkPendingIdentity(ctx context.Context, tx *sqlx.Tx, stageId uuid.UUID, user *models.UserRecord) error {
+	var identity struct {
+		Provider      string `db:"provider"`
+		Subject       string `db:"subject"`
+		EmailVerified bool   `db:"email_verified"`
+	}
+	query := `SELECT provider, subject, email_verified FROM pending_identities WHERE stage_id = $1;`
+	if err := tx.GetContext(ctx, &identity, query, stageId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("failed to get pending identity: %w", err)
+	}
+
+	if err := linkIdentity(ctx, tx, user.Id, identity.Provider, identity.Subject); err != nil {
+		return err
+	}
+
+	if identity.EmailVerified {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET email_verified = TRUE WHERE id = $1;`, user.Id); err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		user.EmailVerified = true
+	}
+	return nil
+}
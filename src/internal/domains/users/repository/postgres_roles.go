@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/google/uuid"
+)
+
+func (r *PostgresUserRepository) GetUsersByRole(ctx context.Context, role auth.Role) ([]*models.UserRecord, error) {
+	users := []*models.UserRecord{}
+	query := `SELECT * FROM users WHERE role = $1 ORDER BY username;`
+	if err := r.c.SelectContext(ctx, &users, query, role); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return users, nil
+}
+
+// UpdateUserRole changes the role and revokes the sessions of the user, so the tokens with the old
+// role stop working.
+func (r *PostgresUserRepository) UpdateUserRole(ctx context.Context, id uuid.UUID, role auth.Role) (*models.UserRecord, error) {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	defer tx.Rollback()
+
+	user := &models.UserRecord{}
+	query := `UPDATE users SET role = $1 WHERE id = $2 RETURNING *;`
+	if err := tx.GetContext(ctx, user, query, role, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	query = `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/google/uuid"
+)
+
+// CreateSession starts a session of the user with its first refresh token.
+func (r *PostgresUserRepository) CreateSession(ctx context.Context, userId uuid.UUID, refreshHash string, expiresAt time.Time) (uuid.UUID, error) {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sessionId uuid.UUID
+	query := `INSERT INTO sessions (user_id) VALUES ($1) RETURNING id;`
+	if err := tx.GetContext(ctx, &sessionId, query, userId); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	query = `INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, $3);`
+	if _, err := tx.ExecContext(ctx, query, refreshHash, sessionId, expiresAt); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return sessionId, nil
+}
+
+// RotateRefreshToken uses a refresh token and saves the next one of its session. A token that
+// was already used means it was stolen, so the whole session is revoked.
+func (r *PostgresUserRepository) RotateRefreshToken(ctx context.Context, refreshHash string, newRefreshHash string, expiresAt time.Time) (*models.SessionRecord, error) {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	defer tx.Rollback()
+
+	token := struct {
+		models.SessionRecord
+		Used    bool `db:"used"`
+		Expired bool `db:"expired"`
+	}{}
+	query := `SELECT s.id, s.user_id, s.created_at, s.revoked_at,
+				rt.used_at IS NOT NULL AS used,
+				rt.expires_at < now() AS expired
+			  FROM refresh_tokens rt
+			  JOIN sessions s ON s.id = rt.session_id
+			  WHERE rt.token_hash = $1
+			  FOR UPDATE;`
+	if err := tx.GetContext(ctx, &token, query, refreshHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if token.RevokedAt != nil || token.Expired {
+		return nil, ErrRefreshTokenNotFound
+	}
+
+	if token.Used {
+		query = `UPDATE sessions SET revoked_at = now() WHERE id = $1;`
+		if _, err := tx.ExecContext(ctx, query, token.Id); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	query = `UPDATE refresh_tokens SET used_at = now() WHERE token_hash = $1;`
+	if _, err := tx.ExecContext(ctx, query, refreshHash); err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	query = `INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, $3);`
+	if _, err := tx.ExecContext(ctx, query, newRefreshHash, token.Id, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return &token.SessionRecord, nil
+}
+
+// RevokeSession only revokes the session if it belongs to the user.
+func (r *PostgresUserRepository) RevokeSession(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error {
+	query := `UPDATE sessions SET revoked_at = now()
+			  WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;`
+	if _, err := r.c.ExecContext(ctx, query, sessionId, userId); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) RevokeUserSessions(ctx context.Context, userId uuid.UUID) error {
+	query := `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`
+	if _, err := r.c.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// IsSessionRevoked is used to check every access token, unknown sessions are revoked.
+func (r *PostgresUserRepository) IsSessionRevoked(ctx context.Context, sessionId uuid.UUID) (bool, error) {
+	revoked := true
+	query := `SELECT revoked_at IS NOT NULL FROM sessions WHERE id = $1;`
+	if err := r.c.GetContext(ctx, &revoked, query, sessionId); err != nil {
+		if err == sql.ErrNoRows {
+			return true, nil
+		}
+		return true, fmt.Errorf("failed to check session: %w", err)
+	}
+	return revoked, nil
+}
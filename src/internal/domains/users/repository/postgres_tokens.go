@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateUserToken saves a token sent by email, the tokens sent before for the same purpose stop working.
+func (r *PostgresUserRepository) CreateUserToken(ctx context.Context, userId uuid.UUID, purpose models.UserTokenPurpose, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE user_tokens SET used_at = now()
+			  WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;`
+	if _, err := tx.ExecContext(ctx, query, userId, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+
+	query = `INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at) VALUES ($1, $2, $3, $4);`
+	if _, err := tx.ExecContext(ctx, query, tokenHash, userId, purpose, expiresAt); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) VerifyEmail(ctx context.Context, tokenHash string) error {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	defer tx.Rollback()
+
+	userId, err := useUserToken(ctx, tx, tokenHash, models.UserTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET email_verified = TRUE WHERE id = $1;`
+	if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword changes the password and logs out every session of the user.
+func (r *PostgresUserRepository) ResetPassword(ctx context.Context, tokenHash string, hashedPassword string) error {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	defer tx.Rollback()
+
+	userId, err := useUserToken(ctx, tx, tokenHash, models.UserTokenResetPassword)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET password = $1 WHERE id = $2;`
+	if _, err := tx.ExecContext(ctx, query, hashedPassword, userId); err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	query = `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`
+	if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	return nil
+}
+
+// useUserToken marks the token as used and returns its user, used and expired tokens are not found.
+func useUserToken(ctx context.Context, tx *sqlx.Tx, tokenHash string, purpose models.UserTokenPurpose) (uuid.UUID, error) {
+	var userId uuid.UUID
+	query := `UPDATE user_tokens SET used_at = now()
+			  WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+			  RETURNING user_id;`
+	if err := tx.GetContext(ctx, &userId, query, tokenHash, purpose); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, ErrUserTokenNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to use token: %w", err)
+	}
+	return userId, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// SaveTOTPSecret starts the enrollment of the user, it replaces the secret of an enrollment that
+// wasn't confirmed.
+func (r *PostgresUserRepository) SaveTOTPSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = now(), last_counter = NULL
+		WHERE user_totp.enabled_at IS NULL;`
+	res, err := r.c.ExecContext(ctx, query, userId, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) GetTOTP(ctx context.Context, userId uuid.UUID) (*models.TOTPRecord, error) {
+	totp := &models.TOTPRecord{}
+	query := `SELECT * FROM user_totp WHERE user_id = $1;`
+	if err := r.c.GetContext(ctx, totp, query, userId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTOTPNotFound
+		}
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	return totp, nil
+}
+
+// EnableTOTP confirms the enrollment with the counter of the code used and replaces the recovery codes.
+func (r *PostgresUserRepository) EnableTOTP(ctx context.Context, userId uuid.UUID, counter int64, recoveryHashes []string) error {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE user_totp SET enabled_at = now(), last_counter = $2 WHERE user_id = $1 AND enabled_at IS NULL;`
+	res, err := tx.ExecContext(ctx, query, userId, counter)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, recoveryHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userId uuid.UUID, hashes []string) error {
+	query := `DELETE FROM recovery_codes WHERE user_id = $1;`
+	if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	query = `INSERT INTO recovery_codes (code_hash, user_id) VALUES ($1, $2);`
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, query, hash, userId); err != nil {
+			return fmt.Errorf("failed to save recovery codes: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseTOTPCounter saves the counter of a valid code. It is false when a code of the same or a later
+// period was used already, so the code was replayed.
+func (r *PostgresUserRepository) UseTOTPCounter(ctx context.Context, userId uuid.UUID, counter int64) (bool, error) {
+	query := `UPDATE user_totp SET last_counter = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND (last_counter IS NULL OR last_counter < $2);`
+	res, err := r.c.ExecContext(ctx, query, userId, counter)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows > 0, nil
+}
+
+// UseRecoveryCode marks the recovery code as used, it is false when the user has no such unused code.
+func (r *PostgresUserRepository) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;`
+	res, err := r.c.ExecContext(ctx, query, userId, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows > 0, nil
+}
+
+// DisableTOTP removes the secret and the recovery codes of the user.
+func (r *PostgresUserRepository) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	tx, err := r.c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1;`, userId); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1;`, userId); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	VerifyEmailTokenDuration   = 24 * time.Hour
+	ResetPasswordTokenDuration = time.Hour
+)
+
+// ResendVerificationEmail sends a new verification email, the links sent before stop working.
+func (u *UsersServiceImpl) ResendVerificationEmail(ctx context.Context, userId uuid.UUID) error {
+	user, err := u.rp.GetUser(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return u.sendVerificationEmail(ctx, user)
+}
+
+func (u *UsersServiceImpl) VerifyEmail(ctx context.Context, req *models.VerifyEmailRequest) error {
+	if err := u.rp.VerifyEmail(ctx, auth.HashOpaqueToken(req.Token)); err != nil {
+		if errors.Is(err, rs.ErrUserTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	return nil
+}
+
+// ForgotPassword sends a password reset email. It doesn't fail when there is no user with the
+// email, and the email is looked up and sent after answering, so neither the response nor its time
+// tell which emails are registered.
+func (u *UsersServiceImpl) ForgotPassword(ctx context.Context, req *models.ForgotPasswordRequest) error {
+	go u.sendPasswordReset(context.WithoutCancel(ctx), req.Email)
+	return nil
+}
+
+func (u *UsersServiceImpl) sendPasswordReset(ctx context.Context, email string) {
+	log := logger.FromContext(ctx)
+	user, err := u.rp.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, rs.ErrUserNotFound) {
+			log.Error("failed to get user for password reset", "error", err)
+		}
+		return
+	}
+
+	link, err := u.createTokenLink(ctx, user.Id, models.UserTokenResetPassword, ResetPasswordTokenDuration, "/reset-password")
+	if err != nil {
+		log.Error("failed to create password reset token", "user_id", user.Id, "error", err)
+		return
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Betterreads password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to choose a new password, it expires in %s:\n\n%s\n\n"+
+			"If you didn't ask for it you can ignore this email.\n", user.FirstName, formatDuration(ResetPasswordTokenDuration), link),
+	}
+	if err := u.mailer.Send(msg); err != nil {
+		log.Error("failed to send password reset email", "user_id", user.Id, "error", err)
+	}
+}
+
+// ResetPassword sets the new password and logs out every session of the user.
+func (u *UsersServiceImpl) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := u.rp.ResetPassword(ctx, auth.HashOpaqueToken(req.Token), hashedPassword); err != nil {
+		if errors.Is(err, rs.ErrUserTokenNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	return nil
+}
+
+func (u *UsersServiceImpl) sendVerificationEmail(ctx context.Context, user *models.UserRecord) error {
+	link, err := u.createTokenLink(ctx, user.Id, models.UserTokenVerifyEmail, VerifyEmailTokenDuration, "/verify-email")
+	if err != nil {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Betterreads email",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to verify your email, it expires in %s:\n\n%s\n",
+			user.FirstName, formatDuration(VerifyEmailTokenDuration), link),
+	}
+	// The request doesn't wait for the mail server, failures are only logged
+	go func() {
+		if err := u.mailer.Send(msg); err != nil {
+			logger.FromContext(ctx).Error("failed to send verification email", "user_id", user.Id, "error", err)
+		}
+	}()
+	return nil
+}
+
+// createTokenLink saves a new token and returns the link of the app with it.
+func (u *UsersServiceImpl) createTokenLink(ctx context.Context, userId uuid.UUID, purpose models.UserTokenPurpose, duration time.Duration, path string) (string, error) {
+	token, tokenHash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.rp.CreateUserToken(ctx, userId, purpose, tokenHash, time.Now().Add(duration)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%s?token=%s", u.appURL, path, url.QueryEscape(token)), nil
+}
+
+func formatDuration(d time.Duration) string {
+	if hours := int(d.Hours()); hours > 1 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return "1 hour"
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/mail"
+	"github.com/google/uuid"
+)
+
+// blockingMailer holds every email until the test releases it
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mail.Message
+}
+
+func newBlockingMailer() *blockingMailer {
+	return &blockingMailer{release: make(chan struct{}), sent: make(chan mail.Message, 1)}
+}
+
+func (m *blockingMailer) Send(msg mail.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+func (f *fakeUsersDatabase) CreateUserToken(ctx context.Context, userId uuid.UUID, purpose models.UserTokenPurpose, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+
+func TestForgotPasswordDoesntWaitForTheEmail(t *testing.T) {
+	user := &models.UserRecord{Id: uuid.New(), Email: "reader@betterreads.test"}
+	mailer := newBlockingMailer()
+	u := &UsersServiceImpl{rp: newFakeUsersDatabase(user), mailer: mailer, appURL: "http://app.test"}
+
+	for _, email := range []string{user.Email, "unknown@betterreads.test"} {
+		done := make(chan error, 1)
+		go func() {
+			done <- u.ForgotPassword(context.Background(), &models.ForgotPasswordRequest{Email: email})
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: %v", email, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: the response waited for the email", email)
+		}
+	}
+
+	close(mailer.release)
+	select {
+	case msg := <-mailer.sent:
+		if msg.To != user.Email {
+			t.Errorf("email sent to %s", msg.To)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the email wasn't sent")
+	}
+}
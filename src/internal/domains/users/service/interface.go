@@ -1,12 +1,16 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	"github.com/google/uuid"
 
 	"github.com/betterreads/internal/domains/users/models"
+	"github.com/betterreads/internal/pkg/auth"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/storage"
 )
 
 var (
@@ -24,17 +28,69 @@ var (
 
 	ErrWrongPassword = errors.New("wrong password")
 
+	ErrAccountLocked = errors.New("account locked after too many failed logins")
+
 	ErrUserNotFound = errors.New("user not found")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	ErrRefreshTokenReused = errors.New("refresh token already used, the session was closed")
+
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+
+	ErrInvalidToken = errors.New("invalid or expired token")
+
+	ErrInvalidRole = er.ErrorParam{
+		Name:   "role",
+		Reason: "role must be one of user, author, moderator or admin",
+	}
+
+	ErrChangeOwnRole = errors.New("admins can't change their own role")
+
+	ErrSecondFactorRequired = errors.New("second factor required")
+
+	ErrInvalidChallengeToken = errors.New("invalid or expired challenge token")
+
+	ErrInvalidCode = errors.New("invalid code")
+
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	ErrTOTPNotEnrolled = errors.New("two-factor authentication wasn't enrolled")
+
+	ErrTOTPNotEnabled = errors.New("two-factor authentication isn't enabled")
+
+	ErrOIDCDisabled = errors.New("log in with an identity provider isn't enabled")
+
+	ErrInvalidOIDCState = errors.New("invalid or expired state")
+
+	ErrOIDCEmailRequired = errors.New("the identity provider didn't share an email")
+
+	ErrOIDCAccountExists = errors.New("a user with this email already exists, log in with the password to link the identity provider")
 )
 
 type UsersService interface {
-	RegisterFirstStep(user *models.UserStageRequest) (*models.UserStageResponse, error)
-	RegisterSecondStep(user *models.UserAdditionalRequest, id uuid.UUID) (*models.UserResponse, error)
-	LogInUser(user *models.UserLoginRequest) (*models.UserResponse, string, error)
-	GetUsers() ([]*models.UserResponse, error)
-	GetUser(id uuid.UUID) (*models.UserResponse, error)
-	PostUserPicture(id uuid.UUID, picture models.UserPictureRequest) error
-	GetUserPicture(id uuid.UUID) ([]byte, error)
-	SearchUsers(username string, isAuthor bool) ([]*models.UserResponse, error)
-	CheckUserExists(id uuid.UUID) bool
+	RegisterFirstStep(ctx context.Context, user *models.UserStageRequest) (*models.UserStageResponse, error)
+	RegisterSecondStep(ctx context.Context, user *models.UserAdditionalRequest, id uuid.UUID) (*models.UserResponse, error)
+	LogInUser(ctx context.Context, user *models.UserLoginRequest) (*models.UserResponse, *models.TokensResponse, error)
+	LogInSecondFactor(ctx context.Context, req *models.SecondFactorLoginRequest) (*models.UserResponse, *models.TokensResponse, error)
+	EnrollTOTP(ctx context.Context, userId uuid.UUID) (*models.TOTPEnrollmentResponse, error)
+	ConfirmTOTP(ctx context.Context, userId uuid.UUID, req *models.TOTPCodeRequest) (*models.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, userId uuid.UUID, req *models.DisableTOTPRequest) error
+	OIDCLogInURL(ctx context.Context) (*models.OIDCAuthorizationResponse, error)
+	LogInOIDC(ctx context.Context, req *models.OIDCCallbackRequest) (*models.OIDCLogInResult, error)
+	RefreshTokens(ctx context.Context, req *models.RefreshRequest) (*models.TokensResponse, error)
+	LogOut(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error
+	LogOutAll(ctx context.Context, userId uuid.UUID) error
+	ResendVerificationEmail(ctx context.Context, userId uuid.UUID) error
+	VerifyEmail(ctx context.Context, req *models.VerifyEmailRequest) error
+	ForgotPassword(ctx context.Context, req *models.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error
+	GetUsers(ctx context.Context) ([]*models.UserResponse, error)
+	GetUser(ctx context.Context, id uuid.UUID) (*models.UserResponse, error)
+	PostUserPicture(ctx context.Context, id uuid.UUID, picture models.UserPictureRequest) error
+	GetUserPicture(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error)
+	SearchUsers(ctx context.Context, username string, isAuthor bool, viewerId uuid.UUID) ([]*models.UserResponse, error)
+	CheckUserExists(ctx context.Context, id uuid.UUID) bool
+	GetUsersByRole(ctx context.Context, role auth.Role) ([]*models.UserResponse, error)
+	ChangeUserRole(ctx context.Context, adminId uuid.UUID, userId uuid.UUID, req *models.RoleRequest) (*models.UserResponse, error)
 }
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Failed logins in a row that lock the account
+	MaxFailedLogins      = 5
+	LoginLockoutDuration = 15 * time.Minute
+)
+
+// AccountLockedError is ErrAccountLocked with how long the lock lasts, so the client knows when
+// to try again.
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("%s, try again in %s", ErrAccountLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/domains/users/utils"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/oidc"
+)
+
+const (
+	// Time the user has to log in with the provider
+	OIDCStateDuration = 10 * time.Minute
+	// Tries to find a free username for a new user before giving up
+	oidcUsernameAttempts = 5
+)
+
+// OIDCLogInURL starts a log in with the provider. The state, nonce and PKCE verifier are kept
+// until the provider sends the user back.
+func (u *UsersServiceImpl) OIDCLogInURL(ctx context.Context) (*models.OIDCAuthorizationResponse, error) {
+	if u.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	state, stateHash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, _, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := u.oidc.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.rp.CreateOIDCState(ctx, stateHash, nonce, verifier, time.Now().Add(OIDCStateDuration)); err != nil {
+		return nil, err
+	}
+	return &models.OIDCAuthorizationResponse{AuthorizationURL: authURL, StateHash: stateHash}, nil
+}
+
+// LogInOIDC finishes a log in with the provider. Linked identities log in, identities with the
+// verified email of a user with a verified email are linked to it, and the rest start a registration.
+func (u *UsersServiceImpl) LogInOIDC(ctx context.Context, req *models.OIDCCallbackRequest) (*models.OIDCLogInResult, error) {
+	if u.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	// Otherwise an attacker could log the user in to the attacker's account with their own code and state
+	stateHash := auth.HashOpaqueToken(req.State)
+	if subtle.ConstantTimeCompare([]byte(req.StateHash), []byte(stateHash)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	state, err := u.rp.UseOIDCState(ctx, stateHash)
+	if err != nil {
+		if errors.Is(err, rs.ErrOIDCStateNotFound) {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+
+	claims, err := u.oidc.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	provider := u.oidc.Name()
+
+	userRecord, err := u.rp.GetUserByIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		return u.logInIdentity(ctx, userRecord)
+	}
+	if !errors.Is(err, rs.ErrUserNotFound) {
+		return nil, err
+	}
+
+	// The registration was started before, the user still has to finish it
+	stage, err := u.rp.GetPendingIdentityStage(ctx, provider, claims.Subject)
+	if err == nil {
+		return &models.OIDCLogInResult{Registration: utils.MapUserStageRecordToUserStageResponse(stage)}, nil
+	}
+	if !errors.Is(err, rs.ErrUserStageNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, ErrOIDCEmailRequired
+	}
+
+	userRecord, err = u.rp.GetUserByEmail(ctx, claims.Email)
+	if err == nil {
+		// Otherwise anyone could take over an account by setting its email in the provider
+		if !bool(claims.EmailVerified) || !userRecord.EmailVerified {
+			return nil, ErrOIDCAccountExists
+		}
+		if err := u.rp.LinkIdentity(ctx, userRecord.Id, provider, claims.Subject); err != nil {
+			return nil, err
+		}
+		return u.logInIdentity(ctx, userRecord)
+	}
+	if !errors.Is(err, rs.ErrUserNotFound) {
+		return nil, err
+	}
+
+	stageResponse, err := u.registerIdentity(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OIDCLogInResult{Registration: stageResponse}, nil
+}
+
+// logInIdentity logs in a user that proved their identity with the provider, so only the lock
+// and the second factor are checked.
+func (u *UsersServiceImpl) logInIdentity(ctx context.Context, userRecord *models.UserRecord) (*models.OIDCLogInResult, error) {
+	locked, err := u.rp.GetAccountLock(ctx, userRecord.Id)
+	if err != nil {
+		return nil, err
+	}
+	if locked > 0 {
+		return nil, &AccountLockedError{RetryAfter: locked}
+	}
+
+	if err := u.secondFactorChallenge(ctx, userRecord.Id); err != nil {
+		return nil, err
+	}
+
+	userResponse := utils.MapUserRecordToUserResponse(userRecord)
+	tokens, err := u.startSession(ctx, userResponse.Id, userResponse.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OIDCLogInResult{User: userResponse, Tokens: tokens}, nil
+}
+
+// registerIdentity does the first step of the registration with the claims of the provider. The
+// user gets a random password, they can set one with the password reset.
+func (u *UsersServiceImpl) registerIdentity(ctx context.Context, provider string, claims *oidc.Claims) (*models.UserStageResponse, error) {
+	password, _, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := oidcNames(claims)
+	user := &models.UserStageRequest{
+		Email:     claims.Email,
+		Password:  hashedPassword,
+		FirstName: firstName,
+		LastName:  lastName,
+	}
+
+	base := oidcUsername(claims)
+	for attempt := 0; ; attempt++ {
+		if attempt == oidcUsernameAttempts {
+			return nil, ErrUsernameTaken
+		}
+		user.Username = base
+		if attempt > 0 {
+			suffix, _, err := auth.GenerateOpaqueToken()
+			if err != nil {
+				return nil, err
+			}
+			user.Username = fmt.Sprintf("%s_%s", base, strings.ToLower(suffix[:4]))
+		}
+
+		err := u.rp.CheckUserExistsForRegister(ctx, user)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, rs.ErrEmailAlreadyTaken) {
+			return nil, ErrEmailTaken
+		}
+		if !errors.Is(err, rs.ErrUsernameAlreadyTaken) {
+			return nil, err
+		}
+	}
+
+	stage, err := u.rp.CreateStageUser(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("Error when creating stage user: %w", err)
+	}
+	if err := u.rp.CreatePendingIdentity(ctx, stage.Id, provider, claims.Subject, bool(claims.EmailVerified)); err != nil {
+		return nil, err
+	}
+	return utils.MapUserStageRecordToUserStageResponse(stage), nil
+}
+
+// oidcUsername is the preferred username of the provider, or the local part of the email,
+// without the characters usernames don't have.
+func oidcUsername(claims *oidc.Claims) string {
+	username := claims.PreferredUsername
+	if username == "" || strings.Contains(username, "@") {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(username) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "reader"
+	}
+	return b.String()
+}
+
+func oidcNames(claims *oidc.Claims) (string, string) {
+	if claims.GivenName != "" || claims.FamilyName != "" {
+		return claims.GivenName, claims.FamilyName
+	}
+	first, last, _ := strings.Cut(strings.TrimSpace(claims.Name), " ")
+	return first, strings.TrimSpace(last)
+}
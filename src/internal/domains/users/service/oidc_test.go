@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/oidc"
+	"github.com/betterreads/internal/pkg/oidc/oidctest"
+	"github.com/google/uuid"
+)
+
+const testProvider = "test"
+
+// errSessionStarted stops the log in before signing the tokens, which needs JWT_SECRET
+var errSessionStarted = errors.New("session started")
+
+// fakeUsersDatabase keeps what the OIDC log in uses in memory, the other methods panic.
+type fakeUsersDatabase struct {
+	rs.UsersDatabase
+	states     map[string]*models.OIDCStateRecord
+	users      []*models.UserRecord
+	identities map[string]uuid.UUID
+	pending    map[string]*models.UserStageRecord
+	sessions   []uuid.UUID
+}
+
+func newFakeUsersDatabase(users ...*models.UserRecord) *fakeUsersDatabase {
+	return &fakeUsersDatabase{
+		states:     map[string]*models.OIDCStateRecord{},
+		users:      users,
+		identities: map[string]uuid.UUID{},
+		pending:    map[string]*models.UserStageRecord{},
+	}
+}
+
+func (f *fakeUsersDatabase) CreateOIDCState(ctx context.Context, stateHash string, nonce string, codeVerifier string, expiresAt time.Time) error {
+	f.states[stateHash] = &models.OIDCStateRecord{Nonce: nonce, CodeVerifier: codeVerifier}
+	return nil
+}
+
+func (f *fakeUsersDatabase) UseOIDCState(ctx context.Context, stateHash string) (*models.OIDCStateRecord, error) {
+	state, ok := f.states[stateHash]
+	if !ok {
+		return nil, rs.ErrOIDCStateNotFound
+	}
+	delete(f.states, stateHash)
+	return state, nil
+}
+
+func (f *fakeUsersDatabase) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.UserRecord, error) {
+	if id, ok := f.identities[provider+"/"+subject]; ok {
+		for _, user := range f.users {
+			if user.Id == id {
+				return user, nil
+			}
+		}
+	}
+	return nil, rs.ErrUserNotFound
+}
+
+func (f *fakeUsersDatabase) LinkIdentity(ctx context.Context, userId uuid.UUID, provider string, subject string) error {
+	f.identities[provider+"/"+subject] = userId
+	return nil
+}
+
+func (f *fakeUsersDatabase) GetPendingIdentityStage(ctx context.Context, provider string, subject string) (*models.UserStageRecord, error) {
+	if stage, ok := f.pending[provider+"/"+subject]; ok {
+		return stage, nil
+	}
+	return nil, rs.ErrUserStageNotFound
+}
+
+func (f *fakeUsersDatabase) GetUserByEmail(ctx context.Context, email string) (*models.UserRecord, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, rs.ErrUserNotFound
+}
+
+func (f *fakeUsersDatabase) CheckUserExistsForRegister(ctx context.Context, user *models.UserStageRequest) error {
+	return nil
+}
+
+func (f *fakeUsersDatabase) CreateStageUser(ctx context.Context, user *models.UserStageRequest) (*models.UserStageRecord, error) {
+	return &models.UserStageRecord{Id: uuid.New(), Email: user.Email, Username: user.Username, FirstName: user.FirstName, LastName: user.LastName}, nil
+}
+
+func (f *fakeUsersDatabase) CreatePendingIdentity(ctx context.Context, stageId uuid.UUID, provider string, subject string, emailVerified bool) error {
+	f.pending[provider+"/"+subject] = &models.UserStageRecord{Id: stageId}
+	return nil
+}
+
+func (f *fakeUsersDatabase) GetAccountLock(ctx context.Context, userId uuid.UUID) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeUsersDatabase) GetTOTP(ctx context.Context, userId uuid.UUID) (*models.TOTPRecord, error) {
+	return nil, rs.ErrTOTPNotFound
+}
+
+func (f *fakeUsersDatabase) CreateSession(ctx context.Context, userId uuid.UUID, refreshHash string, expiresAt time.Time) (uuid.UUID, error) {
+	f.sessions = append(f.sessions, userId)
+	return uuid.Nil, errSessionStarted
+}
+
+func newOIDCTestService(t *testing.T, rp *fakeUsersDatabase) (*UsersServiceImpl, *oidctest.Issuer) {
+	issuer := oidctest.NewIssuer(t, "betterreads")
+	provider := oidc.NewProvider(oidc.Config{
+		Name:        testProvider,
+		IssuerURL:   issuer.URL,
+		ClientID:    issuer.ClientID,
+		RedirectURL: "http://app.test/oidc/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+	})
+	return &UsersServiceImpl{rp: rp, appURL: "http://app.test", oidc: provider}, issuer
+}
+
+// logIn goes through the provider with the claims and sends the callback from the browser that started it.
+func logIn(t *testing.T, u *UsersServiceImpl, issuer *oidctest.Issuer, claims map[string]any) (*models.OIDCLogInResult, error) {
+	t.Helper()
+	response, err := u.OIDCLogInURL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := issuer.Authorize(response.AuthorizationURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.LogInOIDC(context.Background(), &models.OIDCCallbackRequest{Code: code, State: state, StateHash: response.StateHash})
+}
+
+func TestLogInOIDCStateFromAnotherBrowser(t *testing.T) {
+	rp := newFakeUsersDatabase()
+	u, issuer := newOIDCTestService(t, rp)
+
+	response, err := u.OIDCLogInURL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := issuer.Authorize(response.AuthorizationURL, issuer.Claims("subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stateHash := range []string{"", "another state hash"} {
+		req := &models.OIDCCallbackRequest{Code: code, State: state, StateHash: stateHash}
+		if _, err := u.LogInOIDC(context.Background(), req); !errors.Is(err, ErrInvalidOIDCState) {
+			t.Errorf("state hash %q: expected ErrInvalidOIDCState, got %v", stateHash, err)
+		}
+	}
+	if len(rp.states) != 1 {
+		t.Error("a rejected callback used the state")
+	}
+}
+
+func TestLogInOIDCUnknownState(t *testing.T) {
+	u, _ := newOIDCTestService(t, newFakeUsersDatabase())
+	req := &models.OIDCCallbackRequest{Code: "code", State: "state", StateHash: auth.HashOpaqueToken("state")}
+	if _, err := u.LogInOIDC(context.Background(), req); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("expected ErrInvalidOIDCState, got %v", err)
+	}
+}
+
+func TestLogInOIDCLinkedIdentity(t *testing.T) {
+	user := &models.UserRecord{Id: uuid.New(), Email: "reader@betterreads.test"}
+	rp := newFakeUsersDatabase(user)
+	rp.identities[testProvider+"/subject"] = user.Id
+	u, issuer := newOIDCTestService(t, rp)
+
+	if _, err := logIn(t, u, issuer, issuer.Claims("subject")); !errors.Is(err, errSessionStarted) {
+		t.Fatalf("expected the session to start, got %v", err)
+	}
+	if len(rp.sessions) != 1 || rp.sessions[0] != user.Id {
+		t.Errorf("sessions %v, expected one of %s", rp.sessions, user.Id)
+	}
+}
+
+func TestLogInOIDCLinksExistingAccount(t *testing.T) {
+	user := &models.UserRecord{Id: uuid.New(), Email: "reader@betterreads.test", EmailVerified: true}
+	rp := newFakeUsersDatabase(user)
+	u, issuer := newOIDCTestService(t, rp)
+
+	claims := issuer.Claims("subject")
+	claims["email"] = user.Email
+	claims["email_verified"] = true
+	if _, err := logIn(t, u, issuer, claims); !errors.Is(err, errSessionStarted) {
+		t.Fatalf("expected the session to start, got %v", err)
+	}
+	if rp.identities[testProvider+"/subject"] != user.Id {
+		t.Error("the identity wasn't linked to the user")
+	}
+	if len(rp.sessions) != 1 || rp.sessions[0] != user.Id {
+		t.Errorf("sessions %v, expected one of %s", rp.sessions, user.Id)
+	}
+}
+
+func TestLogInOIDCUnverifiedEmail(t *testing.T) {
+	cases := []struct {
+		name             string
+		providerVerified bool
+		userVerified     bool
+	}{
+		{"not verified by the provider", false, true},
+		{"not verified by the user", true, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			user := &models.UserRecord{Id: uuid.New(), Email: "reader@betterreads.test", EmailVerified: c.userVerified}
+			rp := newFakeUsersDatabase(user)
+			u, issuer := newOIDCTestService(t, rp)
+
+			claims := issuer.Claims("subject")
+			claims["email"] = user.Email
+			claims["email_verified"] = c.providerVerified
+			if _, err := logIn(t, u, issuer, claims); !errors.Is(err, ErrOIDCAccountExists) {
+				t.Fatalf("expected ErrOIDCAccountExists, got %v", err)
+			}
+			if len(rp.identities) != 0 || len(rp.sessions) != 0 {
+				t.Error("the account was taken over")
+			}
+		})
+	}
+}
+
+func TestLogInOIDCNewIdentity(t *testing.T) {
+	rp := newFakeUsersDatabase()
+	u, issuer := newOIDCTestService(t, rp)
+
+	claims := issuer.Claims("subject")
+	claims["email"] = "new.reader@betterreads.test"
+	claims["email_verified"] = true
+	claims["given_name"] = "New"
+	claims["family_name"] = "Reader"
+	result, err := logIn(t, u, issuer, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Registration == nil || result.Registration.Email != "new.reader@betterreads.test" {
+		t.Fatalf("expected a registration, got %+v", result)
+	}
+	if _, ok := rp.pending[testProvider+"/subject"]; !ok {
+		t.Error("the identity isn't kept for the registration")
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/domains/users/utils"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/google/uuid"
+)
+
+func (u *UsersServiceImpl) GetUsersByRole(ctx context.Context, role auth.Role) ([]*models.UserResponse, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	users, err := u.rp.GetUsersByRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MapUsersRecordToUsersResponses(users), nil
+}
+
+// ChangeUserRole is used by the admins. They can't change their own role so there is always an admin left.
+func (u *UsersServiceImpl) ChangeUserRole(ctx context.Context, adminId uuid.UUID, userId uuid.UUID, req *models.RoleRequest) (*models.UserResponse, error) {
+	if !req.Role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+	if adminId == userId {
+		return nil, ErrChangeOwnRole
+	}
+
+	user, err := u.rp.UpdateUserRole(ctx, userId, req.Role)
+	if err != nil {
+		if errors.Is(err, rs.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return utils.MapUserRecordToUserResponse(user), nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// RefreshTokens gives a new access token and replaces the refresh token with a new one.
+func (u *UsersServiceImpl) RefreshTokens(ctx context.Context, req *models.RefreshRequest) (*models.TokensResponse, error) {
+	refreshToken, refreshHash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := u.rp.RotateRefreshToken(ctx, auth.HashOpaqueToken(req.RefreshToken), refreshHash, time.Now().Add(auth.RefreshTokenDuration()))
+	if err != nil {
+		if errors.Is(err, rs.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		} else if errors.Is(err, rs.ErrRefreshTokenReused) {
+			logger.FromContext(ctx).Warn("refresh token reused, its session was revoked")
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, err
+	}
+
+	user, err := u.rp.GetUser(ctx, session.UserId)
+	if err != nil {
+		if errors.Is(err, rs.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return newTokensResponse(user.Id, user.Role, session.Id, refreshToken)
+}
+
+// LogOut revokes the session of the token, its access and refresh tokens stop working.
+func (u *UsersServiceImpl) LogOut(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error {
+	return u.rp.RevokeSession(ctx, userId, sessionId)
+}
+
+// LogOutAll revokes every session of the user.
+func (u *UsersServiceImpl) LogOutAll(ctx context.Context, userId uuid.UUID) error {
+	return u.rp.RevokeUserSessions(ctx, userId)
+}
+
+func (u *UsersServiceImpl) startSession(ctx context.Context, userId uuid.UUID, role auth.Role) (*models.TokensResponse, error) {
+	refreshToken, refreshHash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionId, err := u.rp.CreateSession(ctx, userId, refreshHash, time.Now().Add(auth.RefreshTokenDuration()))
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokensResponse(userId, role, sessionId, refreshToken)
+}
+
+func newTokensResponse(userId uuid.UUID, role auth.Role, sessionId uuid.UUID, refreshToken string) (*models.TokensResponse, error) {
+	token, err := auth.GenerateToken(userId.String(), role, sessionId.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokensResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenDuration().Seconds()),
+	}, nil
+}
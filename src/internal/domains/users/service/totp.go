@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/domains/users/utils"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// Name of the account in the authenticator apps
+	totpIssuer         = "Betterreads"
+	RecoveryCodesCount = 10
+)
+
+// SecondFactorRequiredError is returned by LogInUser instead of the tokens when the user has
+// two-factor authentication, the challenge token is exchanged with a code in LogInSecondFactor.
+type SecondFactorRequiredError struct {
+	Challenge *models.SecondFactorChallengeResponse
+}
+
+func (e *SecondFactorRequiredError) Error() string {
+	return ErrSecondFactorRequired.Error()
+}
+
+func (e *SecondFactorRequiredError) Is(target error) bool {
+	return target == ErrSecondFactorRequired
+}
+
+// secondFactorChallenge is the error LogInUser returns when the user has two-factor authentication enabled.
+func (u *UsersServiceImpl) secondFactorChallenge(ctx context.Context, userId uuid.UUID) error {
+	totp, err := u.rp.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrTOTPNotFound) {
+			return nil
+		}
+		return err
+	}
+	if totp.EnabledAt == nil {
+		return nil
+	}
+
+	token, err := auth.GenerateChallengeToken(userId.String())
+	if err != nil {
+		return err
+	}
+	return &SecondFactorRequiredError{Challenge: &models.SecondFactorChallengeResponse{
+		SecondFactorRequired: true,
+		ChallengeToken:       token,
+		ExpiresIn:            int(auth.ChallengeTokenDuration.Seconds()),
+	}}
+}
+
+// LogInSecondFactor finishes the log in of a user with two-factor authentication. Wrong codes count
+// as failed logins, so guessing them locks the account.
+func (u *UsersServiceImpl) LogInSecondFactor(ctx context.Context, req *models.SecondFactorLoginRequest) (*models.UserResponse, *models.TokensResponse, error) {
+	subject, err := auth.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, nil, ErrInvalidChallengeToken
+	}
+	userId, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, nil, ErrInvalidChallengeToken
+	}
+
+	locked, err := u.rp.GetAccountLock(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if locked > 0 {
+		return nil, nil, &AccountLockedError{RetryAfter: locked}
+	}
+
+	valid, err := u.checkSecondFactor(ctx, userId, req.Code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		locked, err := u.rp.RecordFailedLogin(ctx, userId, MaxFailedLogins, LoginLockoutDuration)
+		if err != nil {
+			return nil, nil, err
+		}
+		if locked > 0 {
+			logger.FromContext(ctx).Warn("account locked after failed second factors", "user_id", userId, "retry_after", locked)
+			return nil, nil, &AccountLockedError{RetryAfter: locked}
+		}
+		return nil, nil, ErrInvalidCode
+	}
+
+	if err := u.rp.ResetFailedLogins(ctx, userId); err != nil {
+		return nil, nil, err
+	}
+
+	userRecord, err := u.rp.GetUser(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrUserNotFound) {
+			return nil, nil, ErrInvalidChallengeToken
+		}
+		return nil, nil, err
+	}
+
+	userResponse := utils.MapUserRecordToUserResponse(userRecord)
+	tokens, err := u.startSession(ctx, userResponse.Id, userResponse.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+	return userResponse, tokens, nil
+}
+
+// checkSecondFactor uses a code of the app or a recovery code of a user with two-factor authentication enabled.
+func (u *UsersServiceImpl) checkSecondFactor(ctx context.Context, userId uuid.UUID, code string) (bool, error) {
+	totp, err := u.rp.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrTOTPNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if totp.EnabledAt == nil {
+		return false, nil
+	}
+
+	if auth.IsTOTPCode(code) {
+		counter, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return u.rp.UseTOTPCounter(ctx, userId, counter)
+	}
+	return u.rp.UseRecoveryCode(ctx, userId, auth.HashRecoveryCode(code))
+}
+
+// EnrollTOTP creates the secret to add to the authenticator app. Two-factor authentication isn't
+// enabled until a code of the app is sent to ConfirmTOTP.
+func (u *UsersServiceImpl) EnrollTOTP(ctx context.Context, userId uuid.UUID) (*models.TOTPEnrollmentResponse, error) {
+	user, err := u.rp.GetUser(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.rp.SaveTOTPSecret(ctx, userId, secret); err != nil {
+		if errors.Is(err, rs.ErrTOTPAlreadyEnabled) {
+			return nil, ErrTOTPAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &models.TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication with a code of the app and returns the recovery codes.
+func (u *UsersServiceImpl) ConfirmTOTP(ctx context.Context, userId uuid.UUID, req *models.TOTPCodeRequest) (*models.RecoveryCodesResponse, error) {
+	totp, err := u.rp.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrTOTPNotFound) {
+			return nil, ErrTOTPNotEnrolled
+		}
+		return nil, err
+	}
+	if totp.EnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	counter, ok := auth.ValidateTOTP(totp.Secret, req.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := auth.GenerateRecoveryCodes(RecoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.rp.EnableTOTP(ctx, userId, counter, hashes); err != nil {
+		if errors.Is(err, rs.ErrTOTPAlreadyEnabled) {
+			return nil, ErrTOTPAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP turns off two-factor authentication, it needs the password and a code of the app or a
+// recovery code. Wrong ones count as failed logins, so a stolen access token isn't enough to guess them.
+func (u *UsersServiceImpl) DisableTOTP(ctx context.Context, userId uuid.UUID, req *models.DisableTOTPRequest) error {
+	totp, err := u.rp.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, rs.ErrTOTPNotFound) {
+			return ErrTOTPNotEnabled
+		}
+		return err
+	}
+	if totp.EnabledAt == nil {
+		return ErrTOTPNotEnabled
+	}
+
+	locked, err := u.rp.GetAccountLock(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if locked > 0 {
+		return &AccountLockedError{RetryAfter: locked}
+	}
+
+	userRecord, err := u.rp.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	failure := ErrWrongPassword
+	if auth.VerifyPassword(userRecord.Password, req.Password) {
+		valid, err := u.checkSecondFactor(ctx, userId, req.Code)
+		if err != nil {
+			return err
+		}
+		failure = nil
+		if !valid {
+			failure = ErrInvalidCode
+		}
+	}
+	if failure != nil {
+		locked, err := u.rp.RecordFailedLogin(ctx, userId, MaxFailedLogins, LoginLockoutDuration)
+		if err != nil {
+			return err
+		}
+		if locked > 0 {
+			logger.FromContext(ctx).Warn("account locked after failed attempts to disable two-factor authentication", "user_id", userId, "retry_after", locked)
+			return &AccountLockedError{RetryAfter: locked}
+		}
+		return failure
+	}
+
+	if err := u.rp.DisableTOTP(ctx, userId); err != nil {
+		return err
+	}
+	return u.rp.ResetFailedLogins(ctx, userId)
+}
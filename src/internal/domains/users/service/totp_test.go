@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/betterreads/internal/domains/users/models"
+	rs "github.com/betterreads/internal/domains/users/repository"
+	"github.com/betterreads/internal/pkg/auth"
+	"github.com/google/uuid"
+)
+
+// fakeTOTPDatabase is a user with two-factor authentication enabled and the failed logins of the lockout
+type fakeTOTPDatabase struct {
+	*fakeUsersDatabase
+	totp     *models.TOTPRecord
+	recovery map[string]bool
+	failures int
+	lockedAt int
+}
+
+func (f *fakeTOTPDatabase) GetUser(ctx context.Context, id uuid.UUID) (*models.UserRecord, error) {
+	for _, user := range f.users {
+		if user.Id == id {
+			return user, nil
+		}
+	}
+	return nil, rs.ErrUserNotFound
+}
+
+func (f *fakeTOTPDatabase) GetTOTP(ctx context.Context, userId uuid.UUID) (*models.TOTPRecord, error) {
+	if f.totp == nil {
+		return nil, rs.ErrTOTPNotFound
+	}
+	return f.totp, nil
+}
+
+func (f *fakeTOTPDatabase) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (bool, error) {
+	if !f.recovery[codeHash] {
+		return false, nil
+	}
+	delete(f.recovery, codeHash)
+	return true, nil
+}
+
+func (f *fakeTOTPDatabase) GetAccountLock(ctx context.Context, userId uuid.UUID) (time.Duration, error) {
+	if f.lockedAt > 0 && f.failures >= f.lockedAt {
+		return LoginLockoutDuration, nil
+	}
+	return 0, nil
+}
+
+func (f *fakeTOTPDatabase) RecordFailedLogin(ctx context.Context, userId uuid.UUID, maxFailures int, lockout time.Duration) (time.Duration, error) {
+	f.failures++
+	f.lockedAt = maxFailures
+	if f.failures >= maxFailures {
+		return lockout, nil
+	}
+	return 0, nil
+}
+
+func (f *fakeTOTPDatabase) ResetFailedLogins(ctx context.Context, userId uuid.UUID) error {
+	f.failures = 0
+	return nil
+}
+
+func (f *fakeTOTPDatabase) DisableTOTP(ctx context.Context, userId uuid.UUID) error {
+	f.totp = nil
+	return nil
+}
+
+func newFakeTOTPDatabase(t *testing.T, password string, recoveryCode string) (*fakeTOTPDatabase, uuid.UUID) {
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.UserRecord{Id: uuid.New(), Password: hashedPassword}
+	enabledAt := time.Now().Format(time.RFC3339)
+	return &fakeTOTPDatabase{
+		fakeUsersDatabase: newFakeUsersDatabase(user),
+		totp:              &models.TOTPRecord{UserId: user.Id, EnabledAt: &enabledAt},
+		recovery:          map[string]bool{auth.HashRecoveryCode(recoveryCode): true},
+	}, user.Id
+}
+
+func TestDisableTOTP(t *testing.T) {
+	const password, recoveryCode = "correct horse battery staple", "abcd-efgh-ijkl-mnop"
+
+	t.Run("password and code", func(t *testing.T) {
+		rp, userId := newFakeTOTPDatabase(t, password, recoveryCode)
+		u := &UsersServiceImpl{rp: rp}
+
+		err := u.DisableTOTP(context.Background(), userId, &models.DisableTOTPRequest{Password: password, Code: recoveryCode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rp.totp != nil {
+			t.Error("two-factor authentication is still enabled")
+		}
+	})
+
+	t.Run("code without the password", func(t *testing.T) {
+		rp, userId := newFakeTOTPDatabase(t, password, recoveryCode)
+		u := &UsersServiceImpl{rp: rp}
+
+		err := u.DisableTOTP(context.Background(), userId, &models.DisableTOTPRequest{Password: "wrong", Code: recoveryCode})
+		if !errors.Is(err, ErrWrongPassword) {
+			t.Fatalf("got %v, want %v", err, ErrWrongPassword)
+		}
+		if rp.totp == nil || rp.failures != 1 {
+			t.Errorf("enabled %t, %d failures", rp.totp != nil, rp.failures)
+		}
+		if !rp.recovery[auth.HashRecoveryCode(recoveryCode)] {
+			t.Error("the recovery code was used with a wrong password")
+		}
+	})
+
+	t.Run("wrong codes lock the account", func(t *testing.T) {
+		rp, userId := newFakeTOTPDatabase(t, password, recoveryCode)
+		u := &UsersServiceImpl{rp: rp}
+
+		for i := 1; i < MaxFailedLogins; i++ {
+			err := u.DisableTOTP(context.Background(), userId, &models.DisableTOTPRequest{Password: password, Code: "wxyz-wxyz-wxyz-wxyz"})
+			if !errors.Is(err, ErrInvalidCode) {
+				t.Fatalf("attempt %d: got %v, want %v", i, err, ErrInvalidCode)
+			}
+		}
+		err := u.DisableTOTP(context.Background(), userId, &models.DisableTOTPRequest{Password: password, Code: "wxyz-wxyz-wxyz-wxyz"})
+		if !errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("got %v, want %v", err, ErrAccountLocked)
+		}
+
+		// The right password and code don't help while the account is locked
+		err = u.DisableTOTP(context.Background(), userId, &models.DisableTOTPRequest{Password: password, Code: recoveryCode})
+		if !errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("got %v, want %v", err, ErrAccountLocked)
+		}
+		if rp.totp == nil {
+			t.Error("two-factor authentication was disabled while locked")
+		}
+	})
+}
@@ -1,27 +1,43 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+
 	"github.com/betterreads/internal/domains/users/models"
 	rs "github.com/betterreads/internal/domains/users/repository"
 	"github.com/betterreads/internal/domains/users/utils"
 	"github.com/betterreads/internal/pkg/auth"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/mail"
+	"github.com/betterreads/internal/pkg/oidc"
+	"github.com/betterreads/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
 type UsersServiceImpl struct {
-	rp rs.UsersDatabase
+	rp     rs.UsersDatabase
+	mailer mail.Mailer
+	appURL string
+	oidc   *oidc.Provider
 }
 
-func NewUsersServiceImpl(rp rs.UsersDatabase) UsersService {
+// The app url is used to build the links sent by email. The provider is nil when the log in with
+// an identity provider isn't configured.
+func NewUsersServiceImpl(rp rs.UsersDatabase, mailer mail.Mailer, appURL string, provider *oidc.Provider) UsersService {
 	return &UsersServiceImpl{
-		rp: rp,
+		rp:     rp,
+		mailer: mailer,
+		appURL: strings.TrimSuffix(appURL, "/"),
+		oidc:   provider,
 	}
 }
 
-func (u *UsersServiceImpl) RegisterFirstStep(user *models.UserStageRequest) (*models.UserStageResponse, error) {
-	if err := u.rp.CheckUserExistsForRegister(user); err != nil {
+func (u *UsersServiceImpl) RegisterFirstStep(ctx context.Context, user *models.UserStageRequest) (*models.UserStageResponse, error) {
+	if err := u.rp.CheckUserExistsForRegister(ctx, user); err != nil {
 		if errors.Is(err, rs.ErrUsernameAlreadyTaken) {
 			return nil, ErrUsernameTaken
 		} else if errors.Is(err, rs.ErrEmailAlreadyTaken) {
@@ -36,7 +52,7 @@ func (u *UsersServiceImpl) RegisterFirstStep(user *models.UserStageRequest) (*mo
 
 	user.Password = hashedPassword
 
-	userRecord, err := u.rp.CreateStageUser(user)
+	userRecord, err := u.rp.CreateStageUser(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("Error when creating stage user: %w", err)
 	}
@@ -45,8 +61,8 @@ func (u *UsersServiceImpl) RegisterFirstStep(user *models.UserStageRequest) (*mo
 	return UserStageResponse, nil
 }
 
-func (u *UsersServiceImpl) RegisterSecondStep(user *models.UserAdditionalRequest, id uuid.UUID) (*models.UserResponse, error) {
-	UserRecord, err := u.rp.JoinAndCreateUser(user, id)
+func (u *UsersServiceImpl) RegisterSecondStep(ctx context.Context, user *models.UserAdditionalRequest, id uuid.UUID) (*models.UserResponse, error) {
+	UserRecord, err := u.rp.JoinAndCreateUser(ctx, user, id)
 	if err != nil {
 		if errors.Is(err, rs.ErrUserStageNotFound) {
 			return nil, ErrUserNotFound
@@ -54,33 +70,67 @@ func (u *UsersServiceImpl) RegisterSecondStep(user *models.UserAdditionalRequest
 		return nil, err
 	}
 
+	// The user is already created, they can ask for the email again. Users registered with an
+	// identity provider that verified the email don't need it.
+	if !UserRecord.EmailVerified {
+		if err := u.sendVerificationEmail(ctx, UserRecord); err != nil {
+			logger.FromContext(ctx).Error("failed to send verification email", "user_id", UserRecord.Id, "error", err)
+		}
+	}
+
 	UserResponse := utils.MapUserRecordToUserResponse(UserRecord)
 	return UserResponse, nil
 }
 
-func (u *UsersServiceImpl) LogInUser(user *models.UserLoginRequest) (*models.UserResponse, string, error) {
-	userRecord, err := u.rp.GetUserByUsername(user.Username)
+func (u *UsersServiceImpl) LogInUser(ctx context.Context, user *models.UserLoginRequest) (*models.UserResponse, *models.TokensResponse, error) {
+	userRecord, err := u.rp.GetUserByUsername(ctx, user.Username)
 	if err != nil {
 		if errors.Is(err, rs.ErrUserNotFound) {
-			return nil, "", ErrUsernameNotFound
+			return nil, nil, ErrUsernameNotFound
 		}
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// Checked before the password, so a locked account doesn't cost a bcrypt comparison
+	locked, err := u.rp.GetAccountLock(ctx, userRecord.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if locked > 0 {
+		return nil, nil, &AccountLockedError{RetryAfter: locked}
 	}
 
 	if !auth.VerifyPassword(userRecord.Password, user.Password) {
-		return nil, "", ErrWrongPassword
+		locked, err := u.rp.RecordFailedLogin(ctx, userRecord.Id, MaxFailedLogins, LoginLockoutDuration)
+		if err != nil {
+			return nil, nil, err
+		}
+		if locked > 0 {
+			logger.FromContext(ctx).Warn("account locked after failed logins", "user_id", userRecord.Id, "retry_after", locked)
+			return nil, nil, &AccountLockedError{RetryAfter: locked}
+		}
+		return nil, nil, ErrWrongPassword
+	}
+
+	// The failed logins are reset after the second factor, so its codes can't be guessed by logging in again
+	if err := u.secondFactorChallenge(ctx, userRecord.Id); err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.rp.ResetFailedLogins(ctx, userRecord.Id); err != nil {
+		return nil, nil, err
 	}
 
 	userResponse := utils.MapUserRecordToUserResponse(userRecord)
-	token, err := auth.GenerateToken(userResponse.Id.String(), userResponse.IsAuthor)
+	tokens, err := u.startSession(ctx, userResponse.Id, userResponse.Role)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
-	return userResponse, token, nil
+	return userResponse, tokens, nil
 }
 
-func (u *UsersServiceImpl) GetUsers() ([]*models.UserResponse, error) {
-	users, err := u.rp.GetUsers()
+func (u *UsersServiceImpl) GetUsers(ctx context.Context) ([]*models.UserResponse, error) {
+	users, err := u.rp.GetUsers(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -90,8 +140,8 @@ func (u *UsersServiceImpl) GetUsers() ([]*models.UserResponse, error) {
 	return UserResponses, nil
 }
 
-func (u *UsersServiceImpl) GetUser(id uuid.UUID) (*models.UserResponse, error) {
-	user, err := u.rp.GetUser(id)
+func (u *UsersServiceImpl) GetUser(ctx context.Context, id uuid.UUID) (*models.UserResponse, error) {
+	user, err := u.rp.GetUser(ctx, id)
 	if err != nil {
 		if errors.Is(err, rs.ErrUserNotFound) {
 			return nil, ErrUserNotFound
@@ -104,13 +154,18 @@ func (u *UsersServiceImpl) GetUser(id uuid.UUID) (*models.UserResponse, error) {
 	return UserResponse, nil
 }
 
-func (u *UsersServiceImpl) PostUserPicture(id uuid.UUID, picture models.UserPictureRequest) error {
-	exists := u.rp.CheckUserExists(id)
+func (u *UsersServiceImpl) PostUserPicture(ctx context.Context, id uuid.UUID, picture models.UserPictureRequest) error {
+	exists := u.rp.CheckUserExists(ctx, id)
 	if !exists {
 		return ErrUserNotFound
 	}
 
-	err := u.rp.SaveUserPicture(id, picture.Picture)
+	processed, err := images.Process(picture.Picture)
+	if err != nil {
+		return err
+	}
+
+	err = u.rp.SaveUserPicture(ctx, id, processed)
 	if err != nil {
 		if errors.Is(err, rs.ErrUserNotFound) {
 			return ErrUserNotFound // to be sure
@@ -120,21 +175,23 @@ func (u *UsersServiceImpl) PostUserPicture(id uuid.UUID, picture models.UserPict
 	return nil
 }
 
-func (u *UsersServiceImpl) GetUserPicture(id uuid.UUID) ([]byte, error) {
-	exists := u.rp.CheckUserExists(id)
+func (u *UsersServiceImpl) GetUserPicture(ctx context.Context, id uuid.UUID, size images.Size) (*storage.Blob, error) {
+	exists := u.rp.CheckUserExists(ctx, id)
 	if !exists {
 		return nil, ErrUserNotFound
 	}
 
-	picture, err := u.rp.GetUserPicture(id)
+	picture, err := u.rp.GetUserPicture(ctx, id, size)
 	if err != nil {
 		return nil, err
 	}
 	return picture, nil
 }
 
-func (u *UsersServiceImpl) SearchUsers(username string, isAuthor bool) ([]*models.UserResponse, error) {
-	users, err := u.rp.SearchUsers(username, isAuthor)
+// SearchUsers leaves out the users blocked by the viewer or that blocked them, the viewer is
+// uuid.Nil when there is no user logged in.
+func (u *UsersServiceImpl) SearchUsers(ctx context.Context, username string, isAuthor bool, viewerId uuid.UUID) ([]*models.UserResponse, error) {
+	users, err := u.rp.SearchUsers(ctx, username, isAuthor, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +201,6 @@ func (u *UsersServiceImpl) SearchUsers(username string, isAuthor bool) ([]*model
 	return UserResponses, nil
 }
 
-func (u *UsersServiceImpl) CheckUserExists(id uuid.UUID) bool {
-	return u.rp.CheckUserExists(id)
+func (u *UsersServiceImpl) CheckUserExists(ctx context.Context, id uuid.UUID) bool {
+	return u.rp.CheckUserExists(ctx, id)
 }
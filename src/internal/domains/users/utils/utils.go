@@ -15,16 +15,18 @@ func MapUsersRecordToUsersResponses(users []*models.UserRecord) []*models.UserRe
 
 func MapUserRecordToUserResponse(user *models.UserRecord) *models.UserResponse {
 	return &models.UserResponse{
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Username:  user.Username,
-		Location:  user.Location,
-		Gender:    user.Gender,
-		Id:        user.Id,
-		Age:       user.Age,
-		AboutMe:   user.AboutMe,
-		IsAuthor:  user.IsAuthor,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Username:      user.Username,
+		Location:      user.Location,
+		Gender:        user.Gender,
+		Id:            user.Id,
+		Age:           user.Age,
+		AboutMe:       user.AboutMe,
+		IsAuthor:      user.IsAuthor,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
 	}
 }
 
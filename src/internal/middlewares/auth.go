@@ -25,7 +25,7 @@ func AuthMiddleware(c *gin.Context) {
 	}
 
 	tokenString := bearerToken[1]
-	claims, err := auth.ValidateToken(tokenString)
+	claims, err := auth.ValidateToken(c.Request.Context(), tokenString)
 	if err != nil {
 		er.SendError(c, er.NewErrorDetails("Not authorized", fmt.Errorf("Invalid Log in attempt"), http.StatusUnauthorized))
 		c.Abort()
@@ -33,7 +33,8 @@ func AuthMiddleware(c *gin.Context) {
 	}
 
 	c.Set("userId", claims.UserId)
-	c.Set("IsAuthor", claims.IsAuthor)
+	c.Set("role", string(claims.Role))
+	c.Set("sessionId", claims.SessionId)
 
 	c.Next()
 
@@ -54,7 +55,7 @@ func AuthPublicMiddleware(c *gin.Context) {
 		return
 	}
 	tokenString := bearerToken[1]
-	claims, err := auth.ValidateToken(tokenString)
+	claims, err := auth.ValidateToken(c.Request.Context(), tokenString)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
 		c.Abort()
@@ -62,7 +63,8 @@ func AuthPublicMiddleware(c *gin.Context) {
 	}
 
 	c.Set("userId", claims.UserId)
-	c.Set("IsAuthor", claims.IsAuthor)
+	c.Set("role", string(claims.Role))
+	c.Set("sessionId", claims.SessionId)
 	c.Next()
 
 }
@@ -1,36 +1,47 @@
 package application
 
 import (
+	"io"
+	"net/http"
+	"runtime/debug"
+
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/logger"
 	"github.com/gin-gonic/gin"
-	"log"
-	"net/http"
 )
 
 func ErrorMiddleware(c *gin.Context) {
-	logger := log.New(log.Writer(), "[Error] ", log.LstdFlags)
 	c.Next() // This is a placeholder for the next middleware
 
 	err := c.Errors.Last()
 	if err != nil {
+		log := logger.FromContext(c.Request.Context())
 		switch e := err.Err.(type) {
 		case *er.ErrorDetails:
 			if e.Status == http.StatusInternalServerError {
-				c.JSON(e.Status, gin.H{"error": "Internal Server Error"})
-				logger.Print(e.Error())
+				er.SendInternalError(c)
+				log.Error("internal server error", "error", e.Error())
 			} else {
 				er.SendError(c, e)
 			}
 		case *er.ErrorDetailsWithParams:
 			if e.Status == http.StatusInternalServerError {
-				c.JSON(e.Status, gin.H{"error": "Internal Server Error"})
-				logger.Print(e.Error())
+				er.SendInternalError(c)
+				log.Error("internal server error", "error", e.Error())
 			} else {
 				er.SendErrorWithParams(c, e)
 			}
 		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-			logger.Print(err.Error())
+			er.SendInternalError(c)
+			log.Error("internal server error", "error", err.Error())
 		}
 	}
 }
+
+// Recovery answers the requests that panic with an internal error and logs the panic with the
+// request id. It goes after RequestLogger and Metrics, so they still see the 500.
+var Recovery = gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+	logger.FromContext(c.Request.Context()).Error("panic while handling the request", "error", recovered, "stack", string(debug.Stack()))
+	er.SendInternalError(c)
+	c.Abort()
+})
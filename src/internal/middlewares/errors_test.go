@@ -0,0 +1,58 @@
+package application
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecovery(t *testing.T) {
+	logs := &bytes.Buffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logs, nil)))
+	defer slog.SetDefault(previous)
+
+	engine := gin.New()
+	engine.Use(RequestID, RequestLogger, Recovery, ErrorMiddleware)
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("test panic")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "test-request")
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	var body struct {
+		Status    int    `json:"status"`
+		RequestId string `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+	}
+	if body.Status != http.StatusInternalServerError || body.RequestId != "test-request" {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the panic and the request logged, got %q", logs.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"request_id":"test-request"`) {
+			t.Errorf("log line without the request id: %s", line)
+		}
+	}
+	if !strings.Contains(lines[0], "test panic") || !strings.Contains(lines[1], `"status":500`) {
+		t.Errorf("unexpected logs %q", logs.String())
+	}
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Methods with their own series, the rest are grouped as other
+var metricsMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Metrics records the count and latency of each request by the template of its route
+func Metrics(c *gin.Context) {
+	start := time.Now()
+
+	c.Next()
+
+	// Unknown paths and methods are grouped so scanners can't create new series
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := c.Request.Method
+	if !metricsMethods[method] {
+		method = "other"
+	}
+	metrics.ObserveRequest(method, route, c.Writer.Status(), time.Since(start))
+}
+
+// RequireToken only lets through the requests with the token as bearer token, it guards /metrics.
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bearerToken, found := strings.CutPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(bearerToken), []byte(token)) != 1 {
+			er.SendError(c, er.NewErrorDetails("Not authorized", fmt.Errorf("invalid metrics token"), http.StatusUnauthorized))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
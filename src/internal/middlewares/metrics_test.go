@@ -0,0 +1,81 @@
+package application
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Metrics)
+	engine.Use(gin.RecoveryWithWriter(io.Discard))
+	engine.GET("/metrics-test/panic", func(c *gin.Context) {
+		panic("test")
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/metrics-test/panic", nil),
+		httptest.NewRequest("BREW", "/nothing", nil),
+	} {
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrapeMetrics(t)
+	expected := []string{
+		`betterreads_http_requests_total{method="GET",route="/metrics-test/panic",status="500"} 1`,
+		`betterreads_http_requests_total{method="other",route="unmatched",status="404"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %s", line)
+		}
+	}
+	if strings.Contains(body, `method="BREW"`) {
+		t.Error("unknown method has its own series")
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/metrics", RequireToken("secret"), func(c *gin.Context) {
+		c.String(http.StatusOK, "metrics")
+	})
+
+	cases := []struct {
+		name          string
+		authorization string
+		expected      int
+	}{
+		{"valid token", "Bearer secret", http.StatusOK},
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer secreT", http.StatusUnauthorized},
+		{"token prefix", "Bearer secre", http.StatusUnauthorized},
+		{"not a bearer token", "Basic secret", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if c.authorization != "" {
+				req.Header.Set("Authorization", c.authorization)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != c.expected {
+				t.Errorf("expected %d, got %d", c.expected, w.Code)
+			}
+		})
+	}
+}
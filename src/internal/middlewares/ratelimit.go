@@ -0,0 +1,61 @@
+package application
+
+import (
+	"net/http"
+	"strconv"
+
+	aux "github.com/betterreads/internal/pkg/controller"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/logger"
+	"github.com/betterreads/internal/pkg/metrics"
+	"github.com/betterreads/internal/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey identifies the client whose bucket a request takes a token from
+type RateLimitKey func(c *gin.Context) string
+
+// ByIP limits each client IP
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser limits each logged user, or the IP when there is no logged user. It goes after the auth middleware.
+func ByUser(c *gin.Context) string {
+	if userId := c.GetString("userId"); userId != "" {
+		return "user:" + userId
+	}
+	return ByIP(c)
+}
+
+// RateLimit answers 429 to the clients that made more requests than the limit. The routes with the
+// same name share the buckets. If the store fails the request is let through.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := store.Take(c.Request.Context(), name+":"+key(c), limit)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Error("rate limiter failed", "limit", name, "error", err)
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			metrics.RateLimited.WithLabelValues(name).Inc()
+			aux.SetRetryAfter(c, res.RetryAfter)
+			errDetails := er.NewErrorDetails("Too many requests", ratelimit.ErrRateLimited, http.StatusTooManyRequests)
+			c.AbortWithError(errDetails.Status, errDetails)
+		}
+	}
+}
+
+// WritesOnly applies a middleware to the requests that change something, GET, HEAD and OPTIONS skip it
+func WritesOnly(middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		middleware(c)
+	}
+}
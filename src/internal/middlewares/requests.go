@@ -1,28 +1,75 @@
 package application
 
 import (
+	"log/slog"
 	"time"
 
+	"github.com/betterreads/internal/pkg/logger"
 	"github.com/gin-gonic/gin"
-	"log"
+	"github.com/google/uuid"
 )
 
+const (
+	RequestIDHeader = "X-Request-ID"
+	// Longest request id taken from the client, longer ones are replaced
+	maxRequestIDLength = 128
+)
+
+// RequestID takes the X-Request-ID of the request or generates one. It is sent back in the response
+// and the logger of the request context logs it with every line.
+func RequestID(c *gin.Context) {
+	requestId := c.GetHeader(RequestIDHeader)
+	if !validRequestID(requestId) {
+		requestId = uuid.NewString()
+	}
+
+	c.Set("requestId", requestId)
+	c.Header(RequestIDHeader, requestId)
+
+	l := slog.Default().With("request_id", requestId)
+	c.Request = c.Request.WithContext(logger.WithLogger(c.Request.Context(), l))
+
+	c.Next()
+}
+
+// Request ids from the client end up in the logs, only short printable ones are accepted
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
 // RequestLogger logs the details of each HTTP request
 func RequestLogger(c *gin.Context) {
-	logger := log.New(log.Writer(), "[GIN] ", log.LstdFlags)
-
 	start := time.Now() // Record the start time
 
 	// Process the request
 	c.Next()
 
 	// After request is processed, log the details
-	latency := time.Since(start)
-	status := c.Writer.Status() // Get the response status code
-	clientIP := c.ClientIP()    // Get the client IP
-	method := c.Request.Method  // Get the HTTP method
-	path := c.Request.URL.Path  // Get the request path
-
-	logger.Printf("%s | %3d | %13v | %15s | %s", method, status, latency, clientIP, path)
+	status := c.Writer.Status()
+	attrs := []any{
+		"method", c.Request.Method,
+		"path", c.Request.URL.Path,
+		"status", status,
+		"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		"client_ip", c.ClientIP(),
+	}
+	if userId := c.GetString("userId"); userId != "" {
+		attrs = append(attrs, "user_id", userId)
+	}
 
+	level := slog.LevelInfo
+	if status >= 500 {
+		level = slog.LevelError
+	} else if status >= 400 {
+		level = slog.LevelWarn
+	}
+	logger.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request", attrs...)
 }
@@ -0,0 +1,23 @@
+package application
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/betterreads/internal/pkg/auth"
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets through the users with one of the roles, it goes after AuthMiddleware.
+func RequireRole(roles ...auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := auth.Role(c.GetString("role"))
+		if !role.HasRole(roles...) {
+			er.SendError(c, er.NewErrorDetails("Forbidden", fmt.Errorf("the user doesn't have the required role"), http.StatusForbidden))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
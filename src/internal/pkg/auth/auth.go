@@ -1,26 +1,46 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"os"
 	"strconv"
 	"time"
 )
 
+const (
+	defaultAccessMinutes = 15
+	defaultRefreshHours  = 30 * 24
+
+	// Audience of the tokens that only allow to send the second factor of a log in
+	challengeAudience = "second-factor"
+	// How long the user has to send the code of the second factor after the password
+	ChallengeTokenDuration = 5 * time.Minute
+)
+
 var (
-	jwtSecret          string
-	jwtExpirationHours int
+	jwtSecret       string
+	accessDuration  time.Duration
+	refreshDuration time.Duration
 )
 
 func init() {
 	jwtSecret = os.Getenv("JWT_SECRET")
-	jwtExpirationHoursStr := os.Getenv("JWT_DURATION_HOURS")
 
-	if jwtExpirationHoursStr != "" {
-		jwtExpirationHours, _ = strconv.Atoi(jwtExpirationHoursStr)
+	accessMinutes, err := strconv.Atoi(os.Getenv("JWT_ACCESS_DURATION_MINUTES"))
+	if err != nil || accessMinutes <= 0 {
+		accessMinutes = defaultAccessMinutes
+	}
+	accessDuration = time.Duration(accessMinutes) * time.Minute
+
+	refreshHours, err := strconv.Atoi(os.Getenv("JWT_REFRESH_DURATION_HOURS"))
+	if err != nil || refreshHours <= 0 {
+		refreshHours = defaultRefreshHours
 	}
+	refreshDuration = time.Duration(refreshHours) * time.Hour
 }
 
 func HashPassword(password string) (string, error) {
@@ -34,24 +54,30 @@ func VerifyPassword(hashedPassword, password string) bool {
 }
 
 type Claims struct {
-	IsAuthor bool   `json:"is_author"`
-	UserId   string `json:"user_id"`
+	Role      Role   `json:"role"`
+	UserId    string `json:"user_id"`
+	SessionId string `json:"session_id"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userId string, isAuthor bool) (string, error) {
+// AccessTokenDuration is how long the access tokens are valid, they are renewed with a refresh token.
+func AccessTokenDuration() time.Duration {
+	return accessDuration
+}
+
+// GenerateToken creates an access token of the session, it stops being valid when the session is revoked.
+func GenerateToken(userId string, role Role, sessionId string) (string, error) {
 	if jwtSecret == "" {
 		return "", fmt.Errorf("JWT_SECRET environment variable is not set")
 	}
-	if jwtExpirationHours == 0 {
-		return "", fmt.Errorf("JWT_DURATION_HOURS environment variable is not set or invalid")
-	}
 
 	claims := Claims{
-		IsAuthor: isAuthor,
-		UserId:   userId,
+		Role:      role,
+		UserId:    userId,
+		SessionId: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(jwtExpirationHours) * time.Hour)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "Beterreads-monke-crack",
 		},
@@ -67,7 +93,7 @@ func GenerateToken(userId string, isAuthor bool) (string, error) {
 	return sign, nil
 }
 
-func ValidateToken(tokenString string) (*Claims, error) {
+func ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	if jwtSecret == "" {
 		return nil, fmt.Errorf("JWT_SECRET environment variable is not set")
 	}
@@ -92,5 +118,57 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, fmt.Errorf("token has expired")
 	}
 
+	// Challenge tokens are signed with the same secret, they aren't access tokens
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if err := checkRevocation(ctx, claims); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
+
+// GenerateChallengeToken is given after the password of a user with two-factor authentication, it
+// is exchanged with a code of the second factor for the access token.
+func GenerateChallengeToken(userId string) (string, error) {
+	if jwtSecret == "" {
+		return "", fmt.Errorf("JWT_SECRET environment variable is not set")
+	}
+
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Subject:   userId,
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeTokenDuration)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    "Beterreads-monke-crack",
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	sign, err := token.SignedString([]byte(jwtSecret))
+	if err != nil {
+		return "", fmt.Errorf("error signing JWT token: %w", err)
+	}
+
+	return sign, nil
+}
+
+// ValidateChallengeToken returns the id of the user of a challenge token.
+func ValidateChallengeToken(tokenString string) (string, error) {
+	if jwtSecret == "" {
+		return "", fmt.Errorf("JWT_SECRET environment variable is not set")
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(challengeAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", fmt.Errorf("error parsing challenge token: %w", err)
+	}
+
+	return claims.Subject, nil
+}
@@ -0,0 +1,39 @@
+package auth
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAuthor    Role = "author"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var ValidRoles = []Role{RoleUser, RoleAuthor, RoleModerator, RoleAdmin}
+
+func (r Role) IsValid() bool {
+	for _, role := range ValidRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports if the role is one of the given roles.
+func (r Role) HasRole(roles ...Role) bool {
+	for _, role := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ModeratorRoles can edit and delete the books, reviews and posts of other users.
+var ModeratorRoles = []Role{RoleModerator, RoleAdmin}
+
+// CanModerate reports if the role can change the content of other users.
+func (r Role) CanModerate() bool {
+	return r.HasRole(ModeratorRoles...)
+}
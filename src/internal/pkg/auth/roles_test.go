@@ -0,0 +1,18 @@
+package auth
+
+import "testing"
+
+func TestCanModerate(t *testing.T) {
+	cases := map[Role]bool{
+		RoleUser:      false,
+		RoleAuthor:    false,
+		RoleModerator: true,
+		RoleAdmin:     true,
+		Role(""):      false,
+	}
+	for role, expected := range cases {
+		if got := role.CanModerate(); got != expected {
+			t.Errorf("CanModerate(%q) = %v, expected %v", role, got, expected)
+		}
+	}
+}
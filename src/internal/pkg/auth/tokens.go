@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const opaqueTokenBytes = 32
+
+// RevocationChecker tells if the session of a token was revoked, by a log out or because
+// one of its refresh tokens was reused.
+type RevocationChecker interface {
+	IsSessionRevoked(ctx context.Context, sessionId uuid.UUID) (bool, error)
+}
+
+var revocationChecker RevocationChecker
+
+// SetRevocationChecker is called when the server starts, without it tokens are only checked
+// by their signature and expiration.
+func SetRevocationChecker(checker RevocationChecker) {
+	revocationChecker = checker
+}
+
+func checkRevocation(ctx context.Context, claims *Claims) error {
+	sessionId, err := uuid.Parse(claims.SessionId)
+	if err != nil {
+		return fmt.Errorf("token without session")
+	}
+	if revocationChecker == nil {
+		return nil
+	}
+
+	revoked, err := revocationChecker.IsSessionRevoked(ctx, sessionId)
+	if err != nil {
+		return fmt.Errorf("error checking session: %w", err)
+	}
+	if revoked {
+		return fmt.Errorf("token has been revoked")
+	}
+	return nil
+}
+
+// RefreshTokenDuration is how long a refresh token can be used, every use gives a new one.
+func RefreshTokenDuration() time.Duration {
+	return refreshDuration
+}
+
+// GenerateOpaqueToken returns a random token and the hash to store it, it's used for refresh
+// tokens and the single use tokens sent by email.
+func GenerateOpaqueToken() (string, string, error) {
+	bytes := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", fmt.Errorf("error generating token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken is the SHA-256 of the token in hex, tokens are random so they don't need a salt.
+func HashOpaqueToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// The secret of the test vectors of RFC 4226 and RFC 6238 for SHA-1
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, appendix D
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range expected {
+		if got := hotp(rfcKey, int64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, expected %s", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcKey)
+
+	// RFC 6238, appendix B, with the last 6 of the 8 digits
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, c := range cases {
+		now := time.Unix(c.unix, 0)
+		counter, ok := ValidateTOTP(secret, c.code, now)
+		if !ok {
+			t.Errorf("%s isn't valid at %d", c.code, c.unix)
+			continue
+		}
+		if counter != c.unix/totpPeriod {
+			t.Errorf("%s at %d: counter %d, expected %d", c.code, c.unix, counter, c.unix/totpPeriod)
+		}
+
+		// The periods next to it are accepted for clocks that drift, but not the ones after them
+		if _, ok := ValidateTOTP(secret, c.code, now.Add(totpPeriod*time.Second)); !ok {
+			t.Errorf("%s isn't valid a period after %d", c.code, c.unix)
+		}
+		if _, ok := ValidateTOTP(secret, c.code, now.Add(3*totpPeriod*time.Second)); ok {
+			t.Errorf("%s is still valid 3 periods after %d", c.code, c.unix)
+		}
+	}
+
+	if _, ok := ValidateTOTP(secret, "287083", time.Unix(59, 0)); ok {
+		t.Error("a wrong code is valid")
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", time.Unix(59, 0)); ok {
+		t.Error("a code of a broken secret is valid")
+	}
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/betterreads/internal/pkg/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Pictures can be reused for an hour without asking, after that they are revalidated with their ETag.
+const pictureCacheControl = "public, max-age=3600"
+
+// Sends the picture with its caching headers, or 304 when the client already has it and 204 when there
+// is no picture. It returns an error when the picture can't be read, nothing is sent then.
+func SendPicture(ctx *gin.Context, picture *storage.Blob) error {
+	if picture == nil {
+		ctx.Status(http.StatusNoContent)
+		return nil
+	}
+
+	etag := quoteETag(picture.ETag)
+	if isNotModified(ctx.Request, etag, picture.ModTime) {
+		setCacheHeaders(ctx, etag, picture.ModTime, pictureCacheControl)
+		ctx.Status(http.StatusNotModified)
+		return nil
+	}
+
+	data, err := picture.Data(ctx.Request.Context())
+	if err != nil {
+		return err
+	}
+	setCacheHeaders(ctx, etag, picture.ModTime, pictureCacheControl)
+	ctx.Data(http.StatusOK, picture.ContentType, data)
+	return nil
+}
+
+// Sends obj as JSON with the hash of the body as ETag, or 304 when the client already has it.
+// modTime is sent as Last-Modified unless it is zero.
+func SendCachedJSON(ctx *gin.Context, obj any, modTime time.Time, cacheControl string) error {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	etag := quoteETag(storage.ContentHash(body))
+	setCacheHeaders(ctx, etag, modTime, cacheControl)
+	if isNotModified(ctx.Request, etag, modTime) {
+		ctx.Status(http.StatusNotModified)
+		return nil
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	return nil
+}
+
+func setCacheHeaders(ctx *gin.Context, etag string, modTime time.Time, cacheControl string) {
+	ctx.Header("Cache-Control", cacheControl)
+	ctx.Header("ETag", etag)
+	if !modTime.IsZero() {
+		ctx.Header("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+}
+
+// isNotModified checks the conditional headers of the request. If-Modified-Since is only used
+// when there is no If-None-Match, like RFC 9110 says.
+func isNotModified(req *http.Request, etag string, modTime time.Time) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+	// Last-Modified only has seconds
+	return !modTime.Truncate(time.Second).After(ifModifiedSince)
+}
+
+// etagMatches compares the etags of If-None-Match with the weak comparison, W/"x" matches "x"
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteETag(etag string) string {
+	return `"` + etag + `"`
+}
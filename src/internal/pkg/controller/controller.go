@@ -1,10 +1,17 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/betterreads/internal/pkg/auth"
 	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/betterreads/internal/pkg/pagination"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -34,3 +41,80 @@ func GetUserIdIfLogged(ctx *gin.Context) uuid.UUID {
 	}
 	return userId
 }
+
+// Returns the session of the token of the logged user, or uuid.Nil if there is none.
+func GetSessionId(ctx *gin.Context) uuid.UUID {
+	sessionId, err := uuid.Parse(ctx.GetString("sessionId"))
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionId
+}
+
+// Returns the role of the logged user, it is empty if there is no logged user.
+func GetLoggedUserRole(ctx *gin.Context) auth.Role {
+	return auth.Role(ctx.GetString("role"))
+}
+
+// Returns the pagination request from the limit and cursor query params. If they are invalid it returns an errorDetails prepared to send.
+func GetPageRequest(ctx *gin.Context) (pagination.Request, *er.ErrorDetailsWithParams) {
+	req, err := pagination.NewRequest(ctx.Query("limit"), ctx.Query("cursor"))
+	if err != nil {
+		return req, er.NewErrorDetailsWithParams("Error when getting page", http.StatusBadRequest, err)
+	}
+	return req, nil
+}
+
+// Returns the picture size from the size query param. If it is invalid it returns an errorDetails prepared to send.
+func GetPictureSize(ctx *gin.Context) (images.Size, *er.ErrorDetailsWithParams) {
+	size, err := images.ParseSize(ctx.Query("size"))
+	if err != nil {
+		return size, er.NewErrorDetailsWithParams("Error when getting picture size", http.StatusBadRequest, err)
+	}
+	return size, nil
+}
+
+// Returns the errorDetails to send when an uploaded picture is rejected, or nil if the error is not about the picture.
+func GetPictureError(title string, err error) *er.ErrorDetailsWithParams {
+	if errors.Is(err, images.ErrImageTooLarge) {
+		return er.NewErrorDetailsWithParams(title, http.StatusRequestEntityTooLarge, err)
+	} else if errors.Is(err, images.ErrUnsupportedImage) {
+		return er.NewErrorDetailsWithParams(title, http.StatusUnsupportedMediaType, err)
+	} else if errors.Is(err, images.ErrInvalidImage) {
+		return er.NewErrorDetailsWithParams(title, http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// Biggest body of a request uploading a picture, the picture plus room for the rest of the form
+const MaxPictureRequestSize = images.MaxUploadSize + 1<<20
+
+var ErrBodyTooLarge = errors.New("request body is too large")
+
+// Parses the multipart form of the request reading at most max bytes of its body, so a bigger upload is
+// rejected while it is read instead of after being stored. It returns ErrBodyTooLarge if the body is bigger.
+func ParseMultipartForm(ctx *gin.Context, max int64) error {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, max)
+	if err := ctx.Request.ParseMultipartForm(max); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return fmt.Errorf("%w: it can't be bigger than %d MB", ErrBodyTooLarge, max>>20)
+		}
+		return err
+	}
+	return nil
+}
+
+// Parses the multipart form of a request uploading a picture. A body too big for the picture is reported as images.ErrImageTooLarge.
+func ParsePictureForm(ctx *gin.Context) error {
+	err := ParseMultipartForm(ctx, MaxPictureRequestSize)
+	if errors.Is(err, ErrBodyTooLarge) {
+		return images.ErrImageTooLarge
+	}
+	return err
+}
+
+// Sets the Retry-After header of a 429 or 503, in whole seconds rounded up.
+func SetRetryAfter(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/betterreads/internal/pkg/images"
+	"github.com/gin-gonic/gin"
+)
+
+func newUploadContext(t *testing.T, fileSize int) *gin.Context {
+	t.Helper()
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("file", "picture.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(bytes.Repeat([]byte{0}, fileSize)); err != nil {
+		t.Fatal(err)
+	}
+	form.Close()
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", body)
+	ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+	return ctx
+}
+
+func TestParseMultipartForm(t *testing.T) {
+	ctx := newUploadContext(t, 1<<10)
+	if err := ParseMultipartForm(ctx, 1<<20); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := ctx.Request.FormFile("file"); err != nil {
+		t.Fatalf("file not parsed: %v", err)
+	}
+}
+
+func TestParseMultipartFormTooLarge(t *testing.T) {
+	ctx := newUploadContext(t, 2<<20)
+	if err := ParseMultipartForm(ctx, 1<<20); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestParsePictureFormTooLarge(t *testing.T) {
+	ctx := newUploadContext(t, MaxPictureRequestSize)
+	err := ParsePictureForm(ctx)
+	if !errors.Is(err, images.ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+	if errDetails := GetPictureError("title", err); errDetails == nil || errDetails.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a 413, got %v", errDetails)
+	}
+}
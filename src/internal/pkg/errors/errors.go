@@ -10,11 +10,12 @@ import (
 
 // Follows RFC 7807: https://datatracker.ietf.org/doc/html/rfc7807
 type ErrorDetails struct {
-	Type     string `json:"type"`
-	Title    string `json:"title"`
-	Detail   string `json:"detail"`
-	Instance string `json:"instance"`
-	Status   int    `json:"status"` // ESTE NO IRIA MAS
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	Status    int    `json:"status"` // ESTE NO IRIA MAS
+	RequestId string `json:"request_id,omitempty"`
 }
 
 func (e ErrorDetails) Error() string {
@@ -32,12 +33,13 @@ func NewErrorDetails(title string, err error, status int) *ErrorDetails {
 }
 
 type ErrorDetailsWithParams struct {
-	Type     string       `json:"type"`
-	Title    string       `json:"title"`
-	Detail   string       `json:"detail"`
-	Instance string       `json:"instance"`
-	Params   []ErrorParam `json:"validation_errors"`
-	Status   int          `json:"status"`
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	Params    []ErrorParam `json:"validation_errors"`
+	Status    int          `json:"status"`
+	RequestId string       `json:"request_id,omitempty"`
 }
 
 func (e ErrorDetailsWithParams) Error() string {
@@ -97,10 +99,23 @@ func AbortWithJsonErorr(c *gin.Context, err error) {
 
 func SendError(c *gin.Context, err *ErrorDetails) {
 	err.Instance = c.Request.RequestURI
+	err.RequestId = c.GetString("requestId")
 	c.JSON(err.Status, err)
 }
 
 func SendErrorWithParams(c *gin.Context, err *ErrorDetailsWithParams) {
 	err.Instance = c.Request.RequestURI
+	err.RequestId = c.GetString("requestId")
 	c.JSON(err.Status, err)
 }
+
+// SendInternalError hides the cause of the error, it is only logged. The request id of the
+// response finds it in the logs.
+func SendInternalError(c *gin.Context) {
+	SendError(c, &ErrorDetails{
+		Type:   "about:blank",
+		Title:  "Internal Server Error",
+		Detail: "Something went wrong",
+		Status: http.StatusInternalServerError,
+	})
+}
@@ -0,0 +1,196 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	er "github.com/betterreads/internal/pkg/errors"
+	"github.com/betterreads/internal/pkg/storage"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// MaxUploadSize is the biggest picture that can be uploaded, in bytes
+const MaxUploadSize = 5 << 20
+
+const (
+	// Longest side and pixel count of the pictures that are decoded, bigger ones are rejected
+	// before decoding them
+	maxDimension = 8000
+	maxPixels    = 40_000_000
+	jpegQuality  = 85
+)
+
+var (
+	ErrImageTooLarge = er.ErrorParam{
+		Name:   "picture",
+		Reason: "picture must be at most 5 MB, 8000 pixels per side and 40 megapixels",
+	}
+
+	ErrUnsupportedImage = er.ErrorParam{
+		Name:   "picture",
+		Reason: "picture must be a JPEG, PNG or WebP image",
+	}
+
+	ErrInvalidImage = er.ErrorParam{
+		Name:   "picture",
+		Reason: "picture is not a valid image",
+	}
+
+	ErrInvalidSize = er.ErrorParam{
+		Name:   "size",
+		Reason: "size must be thumb, medium or large",
+	}
+)
+
+// Size is one of the variants a picture is saved in
+type Size string
+
+const (
+	SizeThumb  Size = "thumb"
+	SizeMedium Size = "medium"
+	SizeLarge  Size = "large"
+)
+
+var Sizes = []Size{SizeThumb, SizeMedium, SizeLarge}
+
+// Longest side of each variant in pixels, smaller pictures are not enlarged
+var sizeDimensions = map[Size]int{
+	SizeThumb:  150,
+	SizeMedium: 480,
+	SizeLarge:  1200,
+}
+
+// ParseSize parses the size query param, large is the default.
+func ParseSize(size string) (Size, error) {
+	if size == "" {
+		return SizeLarge, nil
+	}
+	for _, s := range Sizes {
+		if string(s) == size {
+			return s, nil
+		}
+	}
+	return "", ErrInvalidSize
+}
+
+// Picture is an uploaded picture already resized into every variant. All the variants have the
+// same content type.
+type Picture struct {
+	ContentType string
+	Variants    map[Size][]byte
+	// Hash of every variant, the ETag of each one is VariantKey(ETag, size)
+	ETag string
+}
+
+// ReadUpload reads an uploaded picture, failing as soon as it goes over MaxUploadSize.
+func ReadUpload(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read picture: %w", err)
+	}
+	if len(data) > MaxUploadSize {
+		return nil, ErrImageTooLarge
+	}
+	return data, nil
+}
+
+// Process checks that the data is a JPEG, PNG or WebP image and re-encodes it in every size.
+// Re-encoding drops the EXIF metadata, the orientation in it is applied to the pixels first.
+// Opaque pictures are saved as JPEG and the ones with transparency as PNG.
+func Process(data []byte) (*Picture, error) {
+	if len(data) > MaxUploadSize {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	var decodeConfig func(io.Reader) (image.Config, error)
+	var decode func(io.Reader) (image.Image, error)
+	switch contentType {
+	case "image/jpeg":
+		decodeConfig, decode = jpeg.DecodeConfig, jpeg.Decode
+	case "image/png":
+		decodeConfig, decode = png.DecodeConfig, png.Decode
+	case "image/webp":
+		decodeConfig, decode = webp.DecodeConfig, webp.Decode
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width > maxDimension || config.Height > maxDimension || config.Width*config.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	opaque := isOpaque(img)
+	picture := &Picture{ContentType: "image/png", Variants: map[Size][]byte{}}
+	if opaque {
+		picture.ContentType = "image/jpeg"
+	}
+
+	for _, size := range Sizes {
+		resized := resize(img, sizeDimensions[size], opaque)
+		buf := &bytes.Buffer{}
+		if opaque {
+			err = jpeg.Encode(buf, resized, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(buf, resized)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode picture: %w", err)
+		}
+		picture.Variants[size] = buf.Bytes()
+	}
+
+	variants := [][]byte{}
+	for _, size := range Sizes {
+		variants = append(variants, picture.Variants[size])
+	}
+	picture.ETag = storage.ContentHash(variants...)
+
+	return picture, nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// resize fits the picture in a square of the given side keeping its aspect ratio
+func resize(img image.Image, side int, opaque bool) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > side || height > side {
+		if width >= height {
+			width, height = side, max(1, height*side/width)
+		} else {
+			width, height = max(1, width*side/height), side
+		}
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	var dst draw.Image = image.NewNRGBA(rect)
+	if opaque {
+		dst = image.NewRGBA(rect)
+	}
+	draw.CatmullRom.Scale(dst, rect, img, bounds, draw.Src, nil)
+	return dst
+}
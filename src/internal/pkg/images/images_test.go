@@ -0,0 +1,87 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// pngHeader is a PNG with only its header, enough for DecodeConfig but not for Decode.
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func TestProcessRejectsLargePicturesBeforeDecoding(t *testing.T) {
+	cases := []struct {
+		name          string
+		width, height uint32
+		expected      error
+	}{
+		{"too many pixels", 7000, 7000, ErrImageTooLarge},
+		{"too wide", 9000, 10, ErrImageTooLarge},
+		{"too tall", 10, 9000, ErrImageTooLarge},
+		// Small enough, so it is decoded and the missing pixels make it invalid
+		{"under the limits", 8000, 5000, ErrInvalidImage},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Process(pngHeader(c.width, c.height))
+			if !errors.Is(err, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, err)
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 300; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	picture, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if picture.ContentType != "image/jpeg" {
+		t.Errorf("opaque picture saved as %s", picture.ContentType)
+	}
+
+	expected := map[Size]image.Point{
+		SizeThumb:  {150, 75},
+		SizeMedium: {480, 240},
+		SizeLarge:  {600, 300},
+	}
+	for size, dimensions := range expected {
+		config, _, err := image.DecodeConfig(bytes.NewReader(picture.Variants[size]))
+		if err != nil {
+			t.Fatalf("%s: %v", size, err)
+		}
+		if config.Width != dimensions.X || config.Height != dimensions.Y {
+			t.Errorf("%s is %dx%d, expected %dx%d", size, config.Width, config.Height, dimensions.X, dimensions.Y)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package images
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/betterreads/internal/pkg/storage"
+)
+
+// Save puts every variant of the picture in the store next to the key. The picture that was
+// saved at the key itself before there were variants is removed.
+func (p *Picture) Save(ctx context.Context, store storage.BlobStore, key string) error {
+	for _, size := range Sizes {
+		if err := store.Put(ctx, storage.VariantKey(key, string(size)), p.Variants[size], p.ContentType); err != nil {
+			return fmt.Errorf("failed to save picture: %w", err)
+		}
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to save picture: %w", err)
+	}
+	return nil
+}
+
+// Delete removes every variant of the picture from the store.
+func Delete(ctx context.Context, store storage.BlobStore, key string) error {
+	if err := store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete picture: %w", err)
+	}
+	for _, size := range Sizes {
+		if err := store.Delete(ctx, storage.VariantKey(key, string(size))); err != nil {
+			return fmt.Errorf("failed to delete picture: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Key used with pg_advisory_lock so only one replica migrates at a time.
+const advisoryLockKey = 7_245_901_331
+
+var (
+	ErrInvalidFileName  = errors.New("invalid migration file name")
+	ErrMissingMigration = errors.New("migration is missing its up or down file")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrUnknownVersion   = errors.New("database has a migration version unknown to this build")
+)
+
+// Migration is a numbered schema change with its up and down SQL.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells if a migration has been applied and when.
+type MigrationStatus struct {
+	Version   int        `db:"version"`
+	Name      string     `db:"name"`
+	AppliedAt *time.Time `db:"applied_at"`
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations embedded in the sql directory. Files must be
+// named NNNN_name.up.sql and NNNN_name.down.sql.
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied := []Migration{}
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		current, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := current[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, err
+	}
+	return applied, nil
+}
+
+// Down reverts the last `steps` applied migrations and returns the ones reverted.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	reverted := []Migration{}
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		current, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := current[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(conn, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return reverted, err
+	}
+	return reverted, nil
+}
+
+// Status returns every known migration with its applied date, nil when pending.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	res := []MigrationStatus{}
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		current, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := current[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			res = append(res, status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *Migrator) withLock(fn func(conn *sqlx.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, advisoryLockKey)
+
+	schema := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		);
+	`
+	if _, err := conn.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(conn *sqlx.Conn) (map[int]time.Time, error) {
+	rows := []MigrationStatus{}
+	query := `SELECT version, name, applied_at FROM schema_migrations ORDER BY version;`
+	if err := conn.SelectContext(context.Background(), &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+
+	res := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		if !known[row.Version] {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownVersion, row.Version, row.Name)
+		}
+		res[row.Version] = *row.AppliedAt
+	}
+	return res, nil
+}
+
+// apply runs the up or down SQL of a migration and records it in a single transaction.
+func (m *Migrator) apply(conn *sqlx.Conn, migration Migration, up bool) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		query := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`
+		if _, err := tx.Exec(query, migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+	} else {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		query := `DELETE FROM schema_migrations WHERE version = $1;`
+		if _, err := tx.Exec(query, migration.Version); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		version, name, direction, err := parseFileName(fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingMigration, migration.Version, migration.Name)
+		}
+		res = append(res, *migration)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	return res, nil
+}
+
+// parseFileName splits "0001_baseline.up.sql" into 1, "baseline" and "up".
+func parseFileName(fileName string) (int, string, string, error) {
+	base, found := strings.CutSuffix(fileName, ".sql")
+	if !found {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+
+	dot := strings.LastIndex(base, ".")
+	if dot == -1 {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+	direction := base[dot+1:]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+
+	versionStr, name, found := strings.Cut(base[:dot], "_")
+	if !found || name == "" {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+
+	return version, name, direction, nil
+}
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS communities_posts;
+DROP TABLE IF EXISTS communities_pictures;
+DROP TABLE IF EXISTS communities_users;
+DROP TABLE IF EXISTS communities;
+
+DROP TABLE IF EXISTS friends_requests;
+DROP TABLE IF EXISTS friends;
+
+DROP TABLE IF EXISTS bookshelf;
+
+DROP VIEW IF EXISTS book_view;
+DROP TABLE IF EXISTS pictures;
+DROP TABLE IF EXISTS genres_books;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS books;
+
+DROP TABLE IF EXISTS pictures_users;
+DROP TABLE IF EXISTS registry;
+DROP TABLE IF EXISTS users;
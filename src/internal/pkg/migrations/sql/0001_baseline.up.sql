@@ -0,0 +1,168 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- USERS
+
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username VARCHAR(255) NOT NULL UNIQUE,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    location VARCHAR(255) NULL,
+    age INTEGER,
+    gender VARCHAR(255),
+    about_me TEXT,
+    is_author BOOLEAN DEFAULT FALSE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+CREATE TABLE IF NOT EXISTS registry (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) NOT NULL UNIQUE,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    is_author BOOLEAN DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS pictures_users (
+    user_id UUID,
+    picture BYTEA,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    PRIMARY KEY (user_id)
+);
+
+-- BOOKS
+
+CREATE TABLE IF NOT EXISTS books (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(255) NOT NULL,
+    author UUID NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount_of_pages INTEGER NOT NULL,
+    publication_date VARCHAR(255) NOT NULL,
+    language VARCHAR(255) NOT NULL,
+    FOREIGN KEY (author) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    user_id UUID,
+    book_id UUID,
+    rating INTEGER NOT NULL,
+    review VARCHAR (255) NOT NULL,
+    publication_date VARCHAR(255) NOT NULL DEFAULT CURRENT_DATE,
+    PRIMARY KEY (user_id, book_id),
+    FOREIGN KEY (book_id) REFERENCES books(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS genres_books (
+    book_id UUID NOT NULL,
+    genre_id INT,
+    PRIMARY KEY (book_id, genre_id),
+    FOREIGN KEY (book_id) REFERENCES books(id)
+);
+
+CREATE TABLE IF NOT EXISTS pictures (
+    book_id UUID,
+    picture BYTEA,
+    FOREIGN KEY (book_id) REFERENCES books(id),
+    PRIMARY KEY (book_id)
+);
+
+CREATE OR REPLACE VIEW book_view AS
+WITH ratings AS (
+    SELECT
+        book_id,
+        COUNT(*) AS total_ratings,
+        AVG(COALESCE(rating, 0)) AS avg_ratings
+    FROM
+        reviews
+    GROUP BY
+        book_id
+)
+SELECT
+    bk.title,
+    bk.author,
+    (SELECT username FROM users WHERE id = bk.author) AS author_name,
+    bk.description,
+    bk.amount_of_pages,
+    bk.publication_date,
+    bk.language,
+    bk.id,
+    COALESCE(r.total_ratings, 0) AS total_ratings,
+    COALESCE(r.avg_ratings, 0) AS avg_ratings
+FROM
+    books bk
+LEFT JOIN
+    ratings r ON bk.id = r.book_id;
+
+-- BOOKSHELF
+
+CREATE TABLE IF NOT EXISTS bookshelf (
+    user_id UUID NOT NULL,
+    book_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    date DATE NOT NULL,
+    PRIMARY KEY (user_id, book_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (book_id) REFERENCES books(id)
+);
+
+-- FRIENDS
+
+CREATE TABLE IF NOT EXISTS friends (
+    user_a_id UUID NOT NULL,
+    user_b_id UUID NOT NULL,
+    PRIMARY KEY (user_a_id, user_b_id),
+    FOREIGN KEY (user_a_id) REFERENCES users(id),
+    FOREIGN KEY (user_b_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS friends_requests (
+    recipient_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    PRIMARY KEY (recipient_id, sender_id),
+    FOREIGN KEY (recipient_id) REFERENCES users(id),
+    FOREIGN KEY (sender_id) REFERENCES users(id)
+);
+
+-- COMMUNITIES
+
+CREATE TABLE IF NOT EXISTS communities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    owner_id UUID NOT NULL,
+    FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS communities_users (
+    user_id UUID NOT NULL,
+    community_id UUID NOT NULL,
+    PRIMARY KEY (user_id, community_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (community_id) REFERENCES communities(id)
+);
+
+CREATE TABLE IF NOT EXISTS communities_pictures (
+    community_id UUID NOT NULL,
+    picture BYTEA NOT NULL,
+    PRIMARY KEY (community_id),
+    FOREIGN KEY (community_id) REFERENCES communities(id)
+);
+
+CREATE TABLE IF NOT EXISTS communities_posts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    content TEXT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (community_id) REFERENCES communities(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1 @@
+ALTER TABLE reviews ALTER COLUMN review TYPE VARCHAR(255) USING LEFT(review, 255);
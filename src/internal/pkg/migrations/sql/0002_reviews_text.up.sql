@@ -0,0 +1 @@
+ALTER TABLE reviews ALTER COLUMN review TYPE TEXT;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_users_username_trgm;
+DROP INDEX IF EXISTS idx_books_title_trgm;
+DROP INDEX IF EXISTS idx_books_search_vector;
+
+DROP TRIGGER IF EXISTS genres_books_search_vector_trigger ON genres_books;
+DROP FUNCTION IF EXISTS genres_books_search_vector_update();
+DROP TRIGGER IF EXISTS books_search_vector_trigger ON books;
+DROP FUNCTION IF EXISTS books_search_vector_update();
+
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
+
+DROP TABLE IF EXISTS genres;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,75 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Genres live in the GenresDict of the books repository, they are mirrored here
+-- so their names can be part of the search document.
+CREATE TABLE IF NOT EXISTS genres (
+    id INT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE
+);
+
+INSERT INTO genres (id, name) VALUES
+    (1, 'Fiction'),
+    (2, 'Non-fiction'),
+    (3, 'Fantasy'),
+    (4, 'Science Fiction'),
+    (5, 'Mystery'),
+    (6, 'Horror'),
+    (7, 'Romance'),
+    (8, 'Thriller'),
+    (9, 'Historical'),
+    (10, 'Biography'),
+    (11, 'Autobiography'),
+    (12, 'Self-help'),
+    (13, 'Travel'),
+    (14, 'Guide'),
+    (15, 'Poetry'),
+    (16, 'Drama'),
+    (17, 'Satire'),
+    (18, 'Anthology'),
+    (19, 'Encyclopedia'),
+    (20, 'Dictionary'),
+    (21, 'Comic'),
+    (22, 'Art'),
+    (23, 'Cookbook')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE books ADD COLUMN search_vector TSVECTOR;
+
+-- Weights: title (A), author username (B), description (C) and genres (D).
+CREATE OR REPLACE FUNCTION books_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE((SELECT username FROM users WHERE id = NEW.author), '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'C') ||
+        setweight(to_tsvector('english', COALESCE((
+            SELECT string_agg(g.name, ' ')
+            FROM genres_books gb
+            JOIN genres g ON g.id = gb.genre_id
+            WHERE gb.book_id = NEW.id
+        ), '')), 'D');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF title, author, description ON books
+    FOR EACH ROW EXECUTE FUNCTION books_search_vector_update();
+
+-- Genres are inserted after the book, so touching the title recomputes the vector.
+CREATE OR REPLACE FUNCTION genres_books_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE books SET title = title WHERE id = COALESCE(NEW.book_id, OLD.book_id);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER genres_books_search_vector_trigger
+    AFTER INSERT OR DELETE ON genres_books
+    FOR EACH ROW EXECUTE FUNCTION genres_books_search_vector_update();
+
+UPDATE books SET title = title;
+
+CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_books_title_trgm ON books USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_books_isbn;
+
+ALTER TABLE books DROP COLUMN IF EXISTS isbn;
@@ -0,0 +1,5 @@
+-- ISBNs are stored normalized to ISBN-13, they are optional because older books
+-- were published without one.
+ALTER TABLE books ADD COLUMN isbn VARCHAR(13);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn ON books(isbn) WHERE isbn IS NOT NULL;
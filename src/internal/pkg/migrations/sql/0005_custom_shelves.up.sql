@@ -0,0 +1,22 @@
+-- Shelves created by the users, a book in the bookshelf keeps its reading status
+-- and can also be in any number of these.
+CREATE TABLE IF NOT EXISTS shelves (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shelves_user_name ON shelves(user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS shelves_books (
+    shelf_id UUID NOT NULL,
+    book_id UUID NOT NULL,
+    date DATE NOT NULL DEFAULT CURRENT_DATE,
+    PRIMARY KEY (shelf_id, book_id),
+    FOREIGN KEY (shelf_id) REFERENCES shelves(id) ON DELETE CASCADE,
+    FOREIGN KEY (book_id) REFERENCES books(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelves_books_book ON shelves_books(book_id);
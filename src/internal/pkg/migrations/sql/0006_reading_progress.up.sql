@@ -0,0 +1,14 @@
+-- Every progress update a user posts for a book, the last one is the current progress.
+CREATE TABLE IF NOT EXISTS reading_progress (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    book_id UUID NOT NULL,
+    page INTEGER NOT NULL,
+    percent NUMERIC(5, 2) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (book_id) REFERENCES books(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_progress_user_book ON reading_progress(user_id, book_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_reading_progress_read_through;
+ALTER TABLE reading_progress DROP COLUMN IF EXISTS read_through_id;
+DROP TABLE IF EXISTS read_throughs;
@@ -0,0 +1,35 @@
+-- Every time a user reads a book, a book can be read many times. Reads in progress have
+-- the status 'reading' and no finish date, only one of them can be open per book.
+-- Reads are history, they stay when the book is removed from the bookshelf.
+CREATE TABLE IF NOT EXISTS read_throughs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    book_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    started_at DATE,
+    finished_at DATE,
+    abandoned_page INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_read_throughs_user_book ON read_throughs(user_id, book_id);
+CREATE INDEX IF NOT EXISTS idx_read_throughs_user_finished ON read_throughs(user_id, finished_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_read_throughs_open ON read_throughs(user_id, book_id) WHERE status = 'reading';
+
+-- Until now the date of the bookshelf was the date of the last status change
+INSERT INTO read_throughs (user_id, book_id, status, started_at)
+SELECT user_id, book_id, status, date FROM bookshelf WHERE status = 'reading';
+
+INSERT INTO read_throughs (user_id, book_id, status, finished_at)
+SELECT user_id, book_id, status, date FROM bookshelf WHERE status = 'read';
+
+-- Progress updates belong to a read
+ALTER TABLE reading_progress ADD COLUMN IF NOT EXISTS read_through_id UUID REFERENCES read_throughs(id) ON DELETE CASCADE;
+
+UPDATE reading_progress p SET read_through_id = rt.id
+FROM read_throughs rt
+WHERE rt.user_id = p.user_id AND rt.book_id = p.book_id;
+
+CREATE INDEX IF NOT EXISTS idx_reading_progress_read_through ON reading_progress(read_through_id, created_at DESC);
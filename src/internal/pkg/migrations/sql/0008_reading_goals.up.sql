@@ -0,0 +1,11 @@
+-- A goal of books or pages to read in a year, the progress is counted from the finished reads.
+CREATE TABLE IF NOT EXISTS reading_goals (
+    user_id UUID NOT NULL,
+    year INTEGER NOT NULL,
+    goal_type VARCHAR(10) NOT NULL,
+    target INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, year),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
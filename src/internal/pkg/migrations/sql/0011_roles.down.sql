@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN is_author;
+ALTER TABLE users ADD COLUMN is_author BOOLEAN DEFAULT FALSE;
+UPDATE users SET is_author = (role = 'author');
+
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,11 @@
+-- The role replaces the is_author flag. is_author is kept as a generated column so the
+-- queries that filter authors keep working.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'author', 'moderator', 'admin'));
+
+UPDATE users SET role = 'author' WHERE is_author;
+
+ALTER TABLE users DROP COLUMN is_author;
+ALTER TABLE users ADD COLUMN is_author BOOLEAN GENERATED ALWAYS AS (role = 'author') STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
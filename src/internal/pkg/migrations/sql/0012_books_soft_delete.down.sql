@@ -0,0 +1,32 @@
+-- Deleted books show up again in the catalogue
+CREATE OR REPLACE VIEW book_view AS
+WITH ratings AS (
+    SELECT
+        book_id,
+        COUNT(*) AS total_ratings,
+        AVG(COALESCE(rating, 0)) AS avg_ratings
+    FROM
+        reviews
+    GROUP BY
+        book_id
+)
+SELECT
+    bk.title,
+    bk.author,
+    (SELECT username FROM users WHERE id = bk.author) AS author_name,
+    bk.description,
+    bk.amount_of_pages,
+    bk.publication_date,
+    bk.language,
+    bk.id,
+    COALESCE(r.total_ratings, 0) AS total_ratings,
+    COALESCE(r.avg_ratings, 0) AS avg_ratings
+FROM
+    books bk
+LEFT JOIN
+    ratings r ON bk.id = r.book_id;
+
+DROP INDEX IF EXISTS idx_books_isbn;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn ON books(isbn) WHERE isbn IS NOT NULL;
+
+ALTER TABLE books DROP COLUMN IF EXISTS deleted_at;
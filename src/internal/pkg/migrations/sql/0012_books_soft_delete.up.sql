@@ -0,0 +1,36 @@
+-- Deleted books are kept as tombstones so the shelves, reads and reviews that point to them
+-- stay consistent. They are hidden from the catalogue through book_view.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- The ISBN of a deleted book can be used again
+DROP INDEX IF EXISTS idx_books_isbn;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn ON books(isbn) WHERE isbn IS NOT NULL AND deleted_at IS NULL;
+
+CREATE OR REPLACE VIEW book_view AS
+WITH ratings AS (
+    SELECT
+        book_id,
+        COUNT(*) AS total_ratings,
+        AVG(COALESCE(rating, 0)) AS avg_ratings
+    FROM
+        reviews
+    GROUP BY
+        book_id
+)
+SELECT
+    bk.title,
+    bk.author,
+    (SELECT username FROM users WHERE id = bk.author) AS author_name,
+    bk.description,
+    bk.amount_of_pages,
+    bk.publication_date,
+    bk.language,
+    bk.id,
+    COALESCE(r.total_ratings, 0) AS total_ratings,
+    COALESCE(r.avg_ratings, 0) AS avg_ratings
+FROM
+    books bk
+LEFT JOIN
+    ratings r ON bk.id = r.book_id
+WHERE
+    bk.deleted_at IS NULL;
@@ -0,0 +1,11 @@
+-- The pictures in the blob store are lost for the database, the rows without one are removed
+DELETE FROM communities_pictures WHERE picture IS NULL;
+ALTER TABLE communities_pictures DROP COLUMN IF EXISTS content_type;
+ALTER TABLE communities_pictures DROP COLUMN IF EXISTS blob_key;
+ALTER TABLE communities_pictures ALTER COLUMN picture SET NOT NULL;
+
+ALTER TABLE pictures_users DROP COLUMN IF EXISTS content_type;
+ALTER TABLE pictures_users DROP COLUMN IF EXISTS blob_key;
+
+ALTER TABLE pictures DROP COLUMN IF EXISTS content_type;
+ALTER TABLE pictures DROP COLUMN IF EXISTS blob_key;
@@ -0,0 +1,11 @@
+-- Pictures are saved in the blob store, the rows keep the key and the type to serve them with.
+-- The picture column keeps the pictures that weren't moved yet with `betterreads blobs migrate`.
+ALTER TABLE pictures ADD COLUMN IF NOT EXISTS blob_key VARCHAR(255);
+ALTER TABLE pictures ADD COLUMN IF NOT EXISTS content_type VARCHAR(100);
+
+ALTER TABLE pictures_users ADD COLUMN IF NOT EXISTS blob_key VARCHAR(255);
+ALTER TABLE pictures_users ADD COLUMN IF NOT EXISTS content_type VARCHAR(100);
+
+ALTER TABLE communities_pictures ALTER COLUMN picture DROP NOT NULL;
+ALTER TABLE communities_pictures ADD COLUMN IF NOT EXISTS blob_key VARCHAR(255);
+ALTER TABLE communities_pictures ADD COLUMN IF NOT EXISTS content_type VARCHAR(100);
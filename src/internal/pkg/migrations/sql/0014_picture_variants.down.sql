@@ -0,0 +1,3 @@
+ALTER TABLE communities_pictures DROP COLUMN IF EXISTS variants;
+ALTER TABLE pictures_users DROP COLUMN IF EXISTS variants;
+ALTER TABLE pictures DROP COLUMN IF EXISTS variants;
@@ -0,0 +1,5 @@
+-- Pictures are saved resized in thumb, medium and large variants next to their blob_key.
+-- The ones uploaded before keep a single picture until `betterreads blobs migrate` resizes them.
+ALTER TABLE pictures ADD COLUMN IF NOT EXISTS variants BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE pictures_users ADD COLUMN IF NOT EXISTS variants BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE communities_pictures ADD COLUMN IF NOT EXISTS variants BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,46 @@
+ALTER TABLE communities_pictures DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE communities_pictures DROP COLUMN IF EXISTS etag;
+
+ALTER TABLE pictures_users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE pictures_users DROP COLUMN IF EXISTS etag;
+
+ALTER TABLE pictures DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE pictures DROP COLUMN IF EXISTS etag;
+
+-- A view can't lose a column with CREATE OR REPLACE
+DROP VIEW IF EXISTS book_view;
+CREATE VIEW book_view AS
+WITH ratings AS (
+    SELECT
+        book_id,
+        COUNT(*) AS total_ratings,
+        AVG(COALESCE(rating, 0)) AS avg_ratings
+    FROM
+        reviews
+    GROUP BY
+        book_id
+)
+SELECT
+    bk.title,
+    bk.author,
+    (SELECT username FROM users WHERE id = bk.author) AS author_name,
+    bk.description,
+    bk.amount_of_pages,
+    bk.publication_date,
+    bk.language,
+    bk.id,
+    COALESCE(r.total_ratings, 0) AS total_ratings,
+    COALESCE(r.avg_ratings, 0) AS avg_ratings
+FROM
+    books bk
+LEFT JOIN
+    ratings r ON bk.id = r.book_id
+WHERE
+    bk.deleted_at IS NULL;
+
+DROP TRIGGER IF EXISTS reviews_book_updated_at_trigger ON reviews;
+DROP FUNCTION IF EXISTS reviews_book_updated_at_update();
+DROP TRIGGER IF EXISTS books_updated_at_trigger ON books;
+DROP FUNCTION IF EXISTS books_updated_at_update();
+
+ALTER TABLE books DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,66 @@
+-- updated_at is the Last-Modified of the book info. It changes when the book is edited, its
+-- genres change (through the search vector trigger) or it gets a rating or review.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE OR REPLACE FUNCTION books_updated_at_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_updated_at_trigger
+    BEFORE UPDATE ON books
+    FOR EACH ROW EXECUTE FUNCTION books_updated_at_update();
+
+CREATE OR REPLACE FUNCTION reviews_book_updated_at_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE books SET updated_at = now() WHERE id = COALESCE(NEW.book_id, OLD.book_id);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reviews_book_updated_at_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON reviews
+    FOR EACH ROW EXECUTE FUNCTION reviews_book_updated_at_update();
+
+CREATE OR REPLACE VIEW book_view AS
+WITH ratings AS (
+    SELECT
+        book_id,
+        COUNT(*) AS total_ratings,
+        AVG(COALESCE(rating, 0)) AS avg_ratings
+    FROM
+        reviews
+    GROUP BY
+        book_id
+)
+SELECT
+    bk.title,
+    bk.author,
+    (SELECT username FROM users WHERE id = bk.author) AS author_name,
+    bk.description,
+    bk.amount_of_pages,
+    bk.publication_date,
+    bk.language,
+    bk.id,
+    COALESCE(r.total_ratings, 0) AS total_ratings,
+    COALESCE(r.avg_ratings, 0) AS avg_ratings,
+    bk.updated_at
+FROM
+    books bk
+LEFT JOIN
+    ratings r ON bk.id = r.book_id
+WHERE
+    bk.deleted_at IS NULL;
+
+-- The etag is a hash of the content of the picture, it is saved so the picture doesn't have to be
+-- read from the blob store to answer conditional requests.
+ALTER TABLE pictures ADD COLUMN IF NOT EXISTS etag VARCHAR(64);
+ALTER TABLE pictures ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+ALTER TABLE pictures_users ADD COLUMN IF NOT EXISTS etag VARCHAR(64);
+ALTER TABLE pictures_users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+ALTER TABLE communities_pictures ADD COLUMN IF NOT EXISTS etag VARCHAR(64);
+ALTER TABLE communities_pictures ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT now();
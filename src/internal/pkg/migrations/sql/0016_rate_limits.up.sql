@@ -0,0 +1,19 @@
+-- Token buckets of the rate limiter when RATE_LIMIT_STORE is postgres. expires_at is when the
+-- bucket is full again, after that the row is the same as no row and it is deleted.
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits(expires_at);
+
+-- Failed logins since the last successful one. After too many the account is locked until
+-- locked_until and the count starts again.
+CREATE TABLE IF NOT EXISTS login_failures (
+    user_id UUID PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
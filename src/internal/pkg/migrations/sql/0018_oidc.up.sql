@@ -0,0 +1,33 @@
+-- Accounts of the OIDC provider linked to the users, by the subject (sub) the provider gives them.
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- Identities of the users that started the registration with the provider, they are linked
+-- when the second step of the registration creates the user.
+CREATE TABLE IF NOT EXISTS pending_identities (
+    stage_id UUID PRIMARY KEY,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (provider, subject),
+    FOREIGN KEY (stage_id) REFERENCES registry(id) ON DELETE CASCADE
+);
+
+-- Log ins started with the provider, the state comes back in the callback and is used once.
+-- Only the SHA-256 of the state is stored.
+CREATE TABLE IF NOT EXISTS oidc_states (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_states_expires_at ON oidc_states(expires_at);
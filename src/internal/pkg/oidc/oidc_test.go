@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/betterreads/internal/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "betterreads"
+	testRedirectURL = "http://app.test/oidc/callback"
+)
+
+func newTestProvider(issuer *oidctest.Issuer) *Provider {
+	return NewProvider(Config{
+		Name:        "test",
+		IssuerURL:   issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+		Scopes:      []string{"openid", "email"},
+	})
+}
+
+// startLogIn returns the authorization URL, the verifier and the nonce of a new log in.
+func startLogIn(t *testing.T, p *Provider) (string, string, string) {
+	t.Helper()
+	verifier, err := GenerateVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authURL, verifier, "nonce"
+}
+
+func TestExchange(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, testClientID)
+	p := newTestProvider(issuer)
+	authURL, verifier, nonce := startLogIn(t, p)
+
+	claims := issuer.Claims("subject")
+	claims["email"] = "reader@betterreads.test"
+	// Some providers send it as a string
+	claims["email_verified"] = "true"
+	code, state, err := issuer.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state" {
+		t.Errorf("state = %q, expected state", state)
+	}
+
+	got, err := p.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Subject != "subject" || got.Email != "reader@betterreads.test" || !bool(got.EmailVerified) {
+		t.Errorf("unexpected claims %+v", got)
+	}
+
+	// Codes are used once
+	if _, err := p.Exchange(context.Background(), code, verifier, nonce); !errors.Is(err, ErrExchangeFailed) {
+		t.Errorf("expected ErrExchangeFailed for a used code, got %v", err)
+	}
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, testClientID)
+	p := newTestProvider(issuer)
+	authURL, _, nonce := startLogIn(t, p)
+
+	code, _, err := issuer.Authorize(authURL, issuer.Claims("subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVerifier, _ := GenerateVerifier()
+	if _, err := p.Exchange(context.Background(), code, otherVerifier, nonce); !errors.Is(err, ErrExchangeFailed) {
+		t.Errorf("expected ErrExchangeFailed, got %v", err)
+	}
+}
+
+func TestExchangeInvalidIDToken(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, testClientID)
+	otherKey, err := oidctest.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		idToken func(nonce string) string
+	}{
+		{"bad signature", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			return oidctest.Sign(otherKey, claims)
+		}},
+		{"wrong audience", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			claims["aud"] = "other-client"
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"wrong issuer", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			claims["iss"] = "https://evil.test"
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"expired", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			claims["exp"] = time.Now().Add(-clockLeeway - time.Minute).Unix()
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"no expiration", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			delete(claims, "exp")
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"nonce mismatch", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = "other nonce"
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"no subject", func(nonce string) string {
+			claims := issuer.Claims("")
+			claims["nonce"] = nonce
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"several audiences without authorized party", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			claims["aud"] = []string{testClientID, "other-client"}
+			return oidctest.Sign(issuer.Key, claims)
+		}},
+		{"not signed with RS256", func(nonce string) string {
+			claims := issuer.Claims("subject")
+			claims["nonce"] = nonce
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+			return token
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := newTestProvider(issuer)
+			authURL, verifier, nonce := startLogIn(t, p)
+			code, _, err := issuer.AuthorizeWithToken(authURL, c.idToken(nonce))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := p.Exchange(context.Background(), code, verifier, nonce); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExchangeSeveralAudiences(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, testClientID)
+	p := newTestProvider(issuer)
+	authURL, verifier, nonce := startLogIn(t, p)
+
+	claims := issuer.Claims("subject")
+	claims["aud"] = []string{testClientID, "other-client"}
+	claims["azp"] = testClientID
+	code, _, err := issuer.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(context.Background(), code, verifier, nonce); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDiscoverWrongIssuer(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, testClientID)
+	// The discovery document of the issuer is served by another URL
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, issuer.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	p := NewProvider(Config{IssuerURL: proxy.URL, ClientID: testClientID, RedirectURL: testRedirectURL})
+	if _, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("expected ErrProviderUnavailable, got %v", err)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Example of RFC 7636, appendix B
+	challenge := codeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("codeChallenge = %q", challenge)
+	}
+}
@@ -0,0 +1,180 @@
+// Package oidctest has an OpenID Connect provider to test the log in with the authorization code flow.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const KeyID = "test-key"
+
+// Issuer serves the discovery document, the keys and the token endpoint. Authorize plays the user
+// logging in, the code it returns is exchanged for an id token only with the PKCE verifier.
+type Issuer struct {
+	*httptest.Server
+	ClientID string
+	Key      *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+type grant struct {
+	challenge   string
+	redirectURI string
+	idToken     string
+}
+
+func NewIssuer(t *testing.T, clientID string) *Issuer {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &Issuer{ClientID: clientID, Key: key, grants: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", i.discovery)
+	mux.HandleFunc("GET /jwks", i.jwks)
+	mux.HandleFunc("POST /token", i.token)
+	i.Server = httptest.NewServer(mux)
+	t.Cleanup(i.Close)
+	return i
+}
+
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+// Claims are the claims of a valid id token of the subject, without the nonce.
+func (i *Issuer) Claims(subject string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": i.URL,
+		"aud": i.ClientID,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+// Sign signs the claims with the key as the key of the issuer.
+func Sign(key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// Authorize logs the user in with the authorization URL and returns the code and the state the
+// provider sends back. The claims get the nonce of the URL unless they have one.
+func (i *Issuer) Authorize(authURL string, claims jwt.MapClaims) (code string, state string, err error) {
+	params, err := authParams(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = params.Get("nonce")
+	}
+	return i.AuthorizeWithToken(authURL, Sign(i.Key, claims))
+}
+
+// AuthorizeWithToken is Authorize with the id token the token endpoint answers.
+func (i *Issuer) AuthorizeWithToken(authURL string, idToken string) (code string, state string, err error) {
+	params, err := authParams(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	if params.Get("response_type") != "code" || params.Get("client_id") != i.ClientID || params.Get("code_challenge_method") != "S256" {
+		return "", "", errors.New("invalid authorization request")
+	}
+
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	code = base64.RawURLEncoding.EncodeToString(bytes)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.grants[code] = grant{
+		challenge:   params.Get("code_challenge"),
+		redirectURI: params.Get("redirect_uri"),
+		idToken:     idToken,
+	}
+	return code, params.Get("state"), nil
+}
+
+func authParams(authURL string) (url.Values, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return nil, err
+	}
+	return u.Query(), nil
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.URL,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/jwks",
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	public := i.Key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+// token only answers a code once, for the client, redirect URI and verifier it was issued for
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	i.mu.Lock()
+	g, ok := i.grants[r.PostForm.Get("code")]
+	delete(i.grants, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(hash[:])
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("client_id") != i.ClientID ||
+		r.PostForm.Get("redirect_uri") != g.redirectURI || challenge != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": g.idToken, "token_type": "Bearer"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
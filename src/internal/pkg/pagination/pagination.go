@@ -0,0 +1,124 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	er "github.com/betterreads/internal/pkg/errors"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+
+	// SQL format used with to_char so dates and timestamps can be compared as text keys.
+	// Go keys for those columns must be built with TimeKey.
+	TimeKeyFormat = `'YYYY-MM-DD"T"HH24:MI:SS.US'`
+	timeKeyLayout = "2006-01-02T15:04:05.000000"
+)
+
+var (
+	ErrInvalidLimit = er.ErrorParam{
+		Name:   "limit",
+		Reason: "limit must be a number between 1 and 100",
+	}
+
+	ErrInvalidCursor = er.ErrorParam{
+		Name:   "cursor",
+		Reason: "cursor is invalid",
+	}
+)
+
+// Cursor points to the last item of a page. Key is the value of the ordering key
+// of that item and Id breaks ties between items with the same key. It's sent to
+// the clients as an opaque string.
+type Cursor struct {
+	Key string `json:"k"`
+	Id  string `json:"id"`
+}
+
+// Request asks for the Limit items that come after Cursor, nil Cursor asks for the first page.
+type Request struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// Page is the response envelope of every paginated list. NextCursor is nil on the last page.
+type Page[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// NewRequest parses the limit and cursor query params, both can be empty.
+func NewRequest(limit string, cursor string) (Request, error) {
+	req := Request{Limit: DefaultLimit}
+
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > MaxLimit {
+			return req, ErrInvalidLimit
+		}
+		req.Limit = l
+	}
+
+	if cursor != "" {
+		c, err := Decode(cursor)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = c
+	}
+
+	return req, nil
+}
+
+// Fetch is the amount of rows to query, one more than the limit to know if there is a next page.
+func (r Request) Fetch() int {
+	return r.Limit + 1
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(cursor string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// NewPage builds a page from the rows queried with Request.Fetch. If there are more
+// rows than the limit the extra one is dropped and the cursor points to the last item.
+func NewPage[T any](items []T, req Request, cursorOf func(T) Cursor) Page[T] {
+	page := Page[T]{Data: items}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+
+	if len(items) > req.Limit {
+		page.Data = items[:req.Limit]
+		next := cursorOf(page.Data[req.Limit-1]).Encode()
+		page.NextCursor = &next
+	}
+	return page
+}
+
+// TimeKey turns a date or timestamp scanned as a RFC 3339 string into the same text
+// that to_char(column, TimeKeyFormat) returns.
+func TimeKey(value string) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	return t.Format(timeKeyLayout)
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore keeps the buckets in the rate_limits table, so they are shared by every replica.
+type PostgresStore struct {
+	db        *sqlx.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db, lastSweep: time.Now()}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.sweep(ctx)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO rate_limits (key, tokens, updated_at, expires_at) VALUES ($1, $2, now(), now())
+		ON CONFLICT (key) DO NOTHING;`
+	if _, err := tx.ExecContext(ctx, query, key, limit.Requests); err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	// The elapsed time comes from the database so the clocks of the replicas don't matter
+	var bucket struct {
+		Tokens  float64 `db:"tokens"`
+		Elapsed float64 `db:"elapsed"`
+	}
+	query = `SELECT tokens, EXTRACT(EPOCH FROM now() - updated_at)::DOUBLE PRECISION AS elapsed
+		FROM rate_limits WHERE key = $1 FOR UPDATE;`
+	if err := tx.GetContext(ctx, &bucket, query, key); err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	tokens, res := take(bucket.Tokens, time.Duration(bucket.Elapsed*float64(time.Second)), limit)
+	query = `UPDATE rate_limits SET tokens = $2, updated_at = now(), expires_at = now() + make_interval(secs => $3)
+		WHERE key = $1;`
+	if _, err := tx.ExecContext(ctx, query, key, tokens, limit.Period.Seconds()); err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return res, nil
+}
+
+// sweep deletes the buckets that are full again, at most once every sweepInterval
+func (s *PostgresStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	// A failed sweep only leaves rows behind, the next one deletes them
+	s.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE expires_at < now();`)
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrRateLimited = errors.New("too many requests, try again later")
+
+// Limit is a token bucket: a client can make up to Requests requests at once and gets them back
+// at a rate of Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Result of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// How long until the next request is allowed, zero when this one was
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets of the clients. Keys are opaque, the middleware builds them from the
+// route group and the client.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// perSecond is the rate the tokens are refilled at
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// take refills a bucket that had tokens elapsed ago and takes a token when there is one.
+// It returns the tokens left in the bucket.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	tokens = math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.perSecond())
+	if tokens < 1 {
+		wait := (1 - tokens) / limit.perSecond()
+		return tokens, Result{RetryAfter: time.Duration(wait * float64(time.Second))}
+	}
+
+	tokens--
+	return tokens, Result{Allowed: true, Remaining: int(tokens)}
+}
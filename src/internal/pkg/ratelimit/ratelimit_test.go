@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	// A token every 6 seconds
+	limit := Limit{Requests: 10, Period: time.Minute}
+
+	cases := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		left    float64
+		result  Result
+	}{
+		{"full bucket", 10, 0, 9, Result{Allowed: true, Remaining: 9}},
+		{"last token", 1, 0, 0, Result{Allowed: true, Remaining: 0}},
+		{"fraction of a token left", 2.5, 0, 1.5, Result{Allowed: true, Remaining: 1}},
+		{"empty bucket", 0, 0, 0, Result{RetryAfter: 6 * time.Second}},
+		{"half a token refilled", 0, 3 * time.Second, 0.5, Result{RetryAfter: 3 * time.Second}},
+		{"a token refilled", 0, 6 * time.Second, 0, Result{Allowed: true, Remaining: 0}},
+		{"refill up to the cap", 5, time.Hour, 9, Result{Allowed: true, Remaining: 9}},
+		{"idle empty bucket", 0, time.Hour, 9, Result{Allowed: true, Remaining: 9}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			left, result := take(c.tokens, c.elapsed, limit)
+			if math.Abs(left-c.left) > 1e-9 {
+				t.Errorf("%v tokens left, expected %v", left, c.left)
+			}
+			if result.Allowed != c.result.Allowed || result.Remaining != c.result.Remaining {
+				t.Errorf("got %+v, expected %+v", result, c.result)
+			}
+			if diff := result.RetryAfter - c.result.RetryAfter; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Errorf("retry after %v, expected %v", result.RetryAfter, c.result.RetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PictureRecord is a row of the pictures tables. Pictures saved before the blob store have
+// their bytes in the row and no key, the ones saved before the resizing have no variants and
+// no etag.
+type PictureRecord struct {
+	Picture     []byte    `db:"picture"`
+	BlobKey     *string   `db:"blob_key"`
+	ContentType *string   `db:"content_type"`
+	Variants    bool      `db:"variants"`
+	ETag        *string   `db:"etag"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// LoadPicture gets the variant of the picture of the row, or the only picture there is when it
+// has no variants. The picture is read from the store when its data is needed, unless the row
+// lacks the etag or the type; pictures that weren't moved to the store are taken from the row.
+func LoadPicture(ctx context.Context, store BlobStore, record *PictureRecord, variant string) (*Blob, error) {
+	if record.BlobKey == nil {
+		if len(record.Picture) == 0 {
+			return nil, nil
+		}
+		return newPictureBlob(record, record.Picture), nil
+	}
+
+	key := *record.BlobKey
+	if record.Variants {
+		key = VariantKey(key, variant)
+	}
+
+	if record.ETag == nil || record.ContentType == nil {
+		data, err := store.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load picture: %w", err)
+		}
+		return newPictureBlob(record, data), nil
+	}
+
+	etag := *record.ETag
+	if record.Variants {
+		etag = VariantKey(etag, variant)
+	}
+	return &Blob{
+		ContentType: *record.ContentType,
+		ETag:        etag,
+		ModTime:     record.UpdatedAt,
+		load:        func(ctx context.Context) ([]byte, error) { return store.Get(ctx, key) },
+	}, nil
+}
+
+func newPictureBlob(record *PictureRecord, data []byte) *Blob {
+	contentType := DetectContentType(data)
+	if record.ContentType != nil {
+		contentType = *record.ContentType
+	}
+	return &Blob{
+		ContentType: contentType,
+		ETag:        ContentHash(data),
+		ModTime:     record.UpdatedAt,
+		data:        data,
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrInvalidKey   = errors.New("invalid blob key")
+)
+
+// BlobStore keeps the pictures outside of the database. Keys are paths like books/<id>.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// A stored file with what is needed to serve it. Its data is only read from the store when
+// Data is called, so conditional requests can be answered without reading it.
+type Blob struct {
+	ContentType string
+	// Hash of the content, without quotes
+	ETag    string
+	ModTime time.Time
+	data    []byte
+	load    func(ctx context.Context) ([]byte, error)
+}
+
+func (b *Blob) Data(ctx context.Context) ([]byte, error) {
+	if b.load == nil {
+		return b.data, nil
+	}
+	data, err := b.load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blob: %w", err)
+	}
+	return data, nil
+}
+
+// DetectContentType sniffs the type of the data, like image/png.
+func DetectContentType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// ContentHash is the hex SHA-256 of the data, it is used as ETag.
+func ContentHash(data ...[]byte) string {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VariantKey is the key of a variant of the blob, like books/<id>_thumb
+func VariantKey(key string, variant string) string {
+	return key + "_" + variant
+}
+
+func BookPictureKey(id string) string {
+	return "books/" + id
+}
+
+func UserPictureKey(id string) string {
+	return "users/" + id
+}
+
+func CommunityPictureKey(id string) string {
+	return "communities/" + id
+}
+
+// Keys can't go outside of the store
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}